@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// recencyHalfLife is the inactivity after which the recency score halves.
+const recencyHalfLife = 90 * 24 * time.Hour
+
+// fetchLastActivity returns the time of each user's latest public event,
+// or "" for users with none (GitHub keeps 90 days of events). Users whose
+// lookup failed are absent from the map.
+func (c *apiClient) fetchLastActivity(ctx context.Context, users []User) map[string]string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	activity := map[string]string{}
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, user := range users {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(login string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			body, err := c.getCached(ctx, fmt.Sprintf("%s/users/%s/events/public?per_page=1", c.baseURL, login))
+			if err != nil {
+				return
+			}
+			var events []struct {
+				CreatedAt string `json:"created_at"`
+			}
+			if json.Unmarshal(body, &events) != nil {
+				return
+			}
+			latest := ""
+			if len(events) > 0 {
+				latest = events[0].CreatedAt
+			}
+			mu.Lock()
+			activity[login] = latest
+			mu.Unlock()
+		}(user.Login)
+	}
+	wg.Wait()
+	return activity
+}
+
+// activityColumns returns the users.csv columns for the events enrichment:
+// the last public activity, the days since then, and a recency score that
+// starts at 1 and halves every recencyHalfLife. Users with no events in
+// GitHub's window score 0; users that were not checked are left blank.
+func activityColumns(activity map[string]string, now time.Time) columnSet[User] {
+	return columnSet[User]{
+		columns: []string{"last_active_at", "days_since_active", "recency_score"},
+		record: func(u User) []string {
+			latest, checked := activity[u.Login]
+			if !checked {
+				return []string{"", "", ""}
+			}
+			t, err := time.Parse(time.RFC3339, latest)
+			if err != nil {
+				return []string{"", "", "0.000"}
+			}
+			age := max(now.Sub(t), 0)
+			score := math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+			return []string{latest, strconv.Itoa(int(age.Hours() / 24)), strconv.FormatFloat(score, 'f', 3, 64)}
+		},
+	}
+}
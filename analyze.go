@@ -0,0 +1,459 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	usersPath := fs.String("users", "users.csv", "users CSV to analyze")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV to analyze")
+	outDir := fs.String("out-dir", "analysis", "directory for the metric CSVs")
+	chartsDir := fs.String("charts", "", "also render charts into this directory")
+	chartFormat := fs.String("chart-format", "png", "chart image format: png or svg")
+	depsPath := fs.String("dependencies", "dependencies.csv", "SBOM dependencies CSV from a scrape run with --dependencies (skipped if missing)")
+	edgesPath := fs.String("edges", "edges.csv", "follow edges CSV from a scrape run with --edges (skipped if missing)")
+	historyDir := fs.String("history-dir", "history", "history store to compare against for star spikes (skipped if missing)")
+	timezone := fs.String("timezone", "Asia/Shanghai", "timezone of the searched location, for weekday and hour breakdowns")
+	cohortSpec := fs.String("cohorts", "", "also write per-cohort metrics for follower bands, e.g. \"200-500,500-2000,2000+\"")
+	clusters := fs.Int("clusters", 5, "group users into this many language profiles (0 = skip)")
+	topPerLanguage := fs.Int("top-per-language", 5, "number of repos per language in top_repos_by_language.csv")
+	weightSpec := fs.String("weights", "", "also write language and company aggregates weighted by each of these: repos, stars, users, e.g. \"stars,users\"")
+	languageMapPath := fs.String("language-map", "", "YAML file renaming language labels before they are counted, e.g. \"HTML: HTML+CSS\"")
+	fs.Parse(args)
+
+	if *chartFormat != "png" && *chartFormat != "svg" {
+		fmt.Println("Unknown chart format:", *chartFormat)
+		return 2
+	}
+	cohorts, err := parseCohorts(*cohortSpec)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 2
+	}
+	loc, err := loadTimezone(*timezone)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 2
+	}
+	weights, err := parseWeights(*weightSpec)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 2
+	}
+	languages, err := loadLanguageMap(*languageMapPath)
+	if err != nil {
+		fmt.Println("Error loading language map:", err)
+		return 2
+	}
+	users, err := loadUsersCSV(*usersPath)
+	if err != nil {
+		fmt.Println("Error loading users:", err)
+		return 1
+	}
+	repos, err := loadReposCSV(*reposPath)
+	if err != nil {
+		fmt.Println("Error loading repos:", err)
+		return 1
+	}
+	languages.apply(repos)
+	assignRootRepos(repos)
+
+	cfg := metricsConfig{chartsDir: *chartsDir, chartFormat: *chartFormat, timezone: *timezone, loc: loc, weights: weights, topPerLanguage: *topPerLanguage}
+	if err := writeMetrics(*outDir, users, repos, cfg); err != nil {
+		fmt.Println("Error writing metrics:", err)
+		return 1
+	}
+
+	if len(cohorts) > 0 {
+		userSets, repoSets := splitCohorts(cohorts, users, repos)
+		summaries := make([]cohortSummary, len(cohorts))
+		for i, c := range cohorts {
+			summaries[i] = summarizeCohort(c.Name, userSets[i], repoSets[i])
+			cohortCfg := cfg
+			if cfg.chartsDir != "" {
+				cohortCfg.chartsDir = filepath.Join(cfg.chartsDir, "cohorts", partitionFileName(c.Name))
+			}
+			if err := writeMetrics(filepath.Join(*outDir, "cohorts", partitionFileName(c.Name)), userSets[i], repoSets[i], cohortCfg); err != nil {
+				fmt.Printf("Error writing metrics for cohort %s: %v\n", c.Name, err)
+				return 1
+			}
+		}
+		if err := writeRecordsCSV(filepath.Join(*outDir, "cohorts.csv"), cohortSummaryColumns, summaries, cohortSummaryRecord); err != nil {
+			fmt.Println("Error saving cohort summary:", err)
+			return 1
+		}
+	}
+
+	anomalies := findAnomalies(users, repos, previousStars(*historyDir))
+	if err := writeRecordsCSV(filepath.Join(*outDir, "anomalies.csv"), anomalyColumns, anomalies, anomalyRecord); err != nil {
+		fmt.Println("Error saving anomalies:", err)
+		return 1
+	}
+	if len(anomalies) > 0 {
+		fmt.Printf("Flagged %d anomalies in %s\n", len(anomalies), filepath.Join(*outDir, "anomalies.csv"))
+	}
+
+	if err := writeRecordsCSV(filepath.Join(*outDir, "fork_clusters.csv"), forkClusterColumns, forkClusters(repos), forkClusterRecord); err != nil {
+		fmt.Println("Error saving fork clusters:", err)
+		return 1
+	}
+
+	if deps, err := loadDependenciesCSV(*depsPath); err == nil {
+		if err := saveSharesCSV(filepath.Join(*outDir, "top_dependencies.csv"), "package", topDependencies(deps)); err != nil {
+			fmt.Println("Error saving dependencies:", err)
+			return 1
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("Error loading dependencies:", err)
+		return 1
+	}
+	if edges, err := loadEdgesCSV(*edgesPath); err == nil {
+		scores := computeInfluence(users, edges, 0.85)
+		if err := writeRecordsCSV(filepath.Join(*outDir, "influence.csv"), influenceColumns, scores, influenceRecord); err != nil {
+			fmt.Println("Error saving influence scores:", err)
+			return 1
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("Error loading edges:", err)
+		return 1
+	}
+	if *clusters > 0 {
+		if err := writeClusters(*outDir, users, repos, *clusters); err != nil {
+			fmt.Println("Error saving clusters:", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// metricsConfig controls the output of writeMetrics.
+type metricsConfig struct {
+	chartsDir   string
+	chartFormat string
+	timezone    string
+	loc         *time.Location
+	// weights lists the extra weightings of the language and company
+	// aggregates to write.
+	weights []string
+	// topPerLanguage is how many repos per language
+	// top_repos_by_language.csv lists.
+	topPerLanguage int
+}
+
+// writeMetrics writes the metric CSVs (and charts, if configured) for a
+// set of users and their repos into dir.
+func writeMetrics(dir string, users []User, repos []Repo, cfg metricsConfig) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// Star aggregates count each upstream once, not once per fork.
+	unique := dedupeByRoot(repos)
+	s := summarize(users, repos, 0)
+	weekdays, hours, weekend := creationRhythm(repos, cfg.loc)
+	metrics := []struct {
+		name, column, title string
+		shares              []share
+		chartTop            int
+	}{
+		{"languages", "language", "Repositories by language", s.Languages, 15},
+		{"licenses", "license", "Repositories by license", s.Licenses, 15},
+		{"followers_hist", "followers", "Users by follower count", followerHistogram(users), 0},
+		{"accounts_by_year", "year", "Accounts created per year", creationTimeline(users), 0},
+		{"language_pairs", "languages", "Languages used together", languagePairs(repos), 15},
+		{"repos_by_weekday", "weekday", "Repositories created per weekday", weekdays, 0},
+		{"repos_by_hour", "hour", "Repositories created per hour (" + cfg.timezone + ")", hours, 0},
+		{"repos_weekend", "day_type", "Repositories created on weekdays vs weekends", weekend, 0},
+	}
+	for _, w := range cfg.weights {
+		metrics = append(metrics, struct {
+			name, column, title string
+			shares              []share
+			chartTop            int
+		}{"languages_by_" + w, "language", "Languages by " + weightTitles[w], languageShares(users, repos, unique, w), 15})
+	}
+	for _, m := range metrics {
+		if err := saveSharesCSV(filepath.Join(dir, m.name+".csv"), m.column, m.shares); err != nil {
+			return fmt.Errorf("saving %s: %w", m.name, err)
+		}
+		if cfg.chartsDir == "" {
+			continue
+		}
+		bars := m.shares
+		if m.chartTop > 0 {
+			bars = topShares(bars, m.chartTop)
+		}
+		if err := writeChart(cfg.chartsDir, m.name, cfg.chartFormat, barChart{Title: m.title, Bars: bars}); err != nil {
+			return fmt.Errorf("rendering chart: %w", err)
+		}
+	}
+	if err := writeRecordsCSV(filepath.Join(dir, "distributions.csv"), distributionColumns, distributions(users, unique), distributionRecord); err != nil {
+		return fmt.Errorf("saving distributions: %w", err)
+	}
+
+	if err := writeRecordsCSV(filepath.Join(dir, "correlations.csv"), correlationColumns, correlations(users, unique), correlationRecord); err != nil {
+		return fmt.Errorf("saving correlations: %w", err)
+	}
+
+	if err := writeRecordsCSV(filepath.Join(dir, "license_ranking.csv"), licenseRankColumns, licenseRanking(repos), licenseRankRecord); err != nil {
+		return fmt.Errorf("saving license ranking: %w", err)
+	}
+
+	if err := writeRecordsCSV(filepath.Join(dir, "top_repos_by_language.csv"), topRepoColumns, topReposByLanguage(repos, cfg.topPerLanguage), topRepoRecord); err != nil {
+		return fmt.Errorf("saving top repos by language: %w", err)
+	}
+
+	leaderboards := map[string]string{"company_leaderboard": "users"}
+	for _, w := range cfg.weights {
+		leaderboards["company_leaderboard_by_"+w] = w
+	}
+	for name, w := range leaderboards {
+		rank := 0
+		err := writeRecordsCSV(filepath.Join(dir, name+".csv"), companyColumns, companyLeaderboard(users, unique, w), func(c companyStats) []string {
+			rank++
+			return []string{strconv.Itoa(rank), c.Company, strconv.Itoa(c.Users), strconv.FormatFloat(c.AvgFollowers, 'f', 1, 64), strconv.Itoa(c.TotalStars), strconv.Itoa(c.Repos)}
+		})
+		if err != nil {
+			return fmt.Errorf("saving company leaderboard: %w", err)
+		}
+	}
+
+	accountStamps := make([]string, len(users))
+	for i, u := range users {
+		accountStamps[i] = u.CreatedAt
+	}
+	repoStamps := make([]string, len(repos))
+	for i, r := range repos {
+		repoStamps[i] = r.CreatedAt
+	}
+	series := []struct {
+		name   string
+		points []periodCount
+	}{
+		{"accounts_by_quarter", timeSeries(accountStamps, 3)},
+		{"repos_by_month", timeSeries(repoStamps, 1)},
+	}
+	for _, ts := range series {
+		if err := savePeriodCSV(filepath.Join(dir, ts.name+".csv"), ts.points); err != nil {
+			return fmt.Errorf("saving time series: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func saveSharesCSV(path, column string, shares []share) error {
+	file, err := createAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.discard()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{column, "count", "percent"})
+	for _, s := range shares {
+		writer.Write([]string{s.Name, strconv.Itoa(s.Count), strconv.FormatFloat(s.Percent, 'f', 2, 64)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.commit()
+}
+
+// share is one row of a frequency table.
+type share struct {
+	Name    string
+	Count   int
+	Percent float64
+}
+
+// shareOf counts the values and returns them most common first. Empty
+// values are counted as "(none)".
+func shareOf(values []string) []share {
+	counts := map[string]int{}
+	for _, v := range values {
+		if v == "" {
+			v = "(none)"
+		}
+		counts[v]++
+	}
+	return rankShares(counts, len(values))
+}
+
+// rankShares turns counts into shares of total, most common first.
+func rankShares(counts map[string]int, total int) []share {
+	shares := make([]share, 0, len(counts))
+	for name, n := range counts {
+		s := share{Name: name, Count: n}
+		if total > 0 {
+			s.Percent = 100 * float64(n) / float64(total)
+		}
+		shares = append(shares, s)
+	}
+	slices.SortFunc(shares, func(a, b share) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return shares
+}
+
+// summary holds the headline figures shared by the reports.
+type summary struct {
+	Users       int
+	Repos       int
+	TopUsers    []User
+	TopRepos    []Repo
+	Languages   []share
+	Licenses    []share
+	Hireable    int
+	NotHireable int
+}
+
+func summarize(users []User, repos []Repo, top int) summary {
+	s := summary{Users: len(users), Repos: len(repos)}
+
+	s.TopUsers = slices.Clone(users)
+	slices.SortStableFunc(s.TopUsers, func(a, b User) int { return cmp.Compare(b.Followers, a.Followers) })
+	s.TopUsers = s.TopUsers[:min(top, len(s.TopUsers))]
+
+	s.TopRepos = slices.Clone(repos)
+	slices.SortStableFunc(s.TopRepos, func(a, b Repo) int { return cmp.Compare(b.StargazersCount, a.StargazersCount) })
+	s.TopRepos = s.TopRepos[:min(top, len(s.TopRepos))]
+
+	languages := make([]string, len(repos))
+	licenses := make([]string, len(repos))
+	for i, r := range repos {
+		languages[i] = r.Language
+		licenses[i] = r.LicenseName
+	}
+	s.Languages = shareOf(languages)
+	s.Licenses = shareOf(licenses)
+
+	for _, u := range users {
+		if u.Hireable {
+			s.Hireable++
+		} else {
+			s.NotHireable++
+		}
+	}
+	return s
+}
+
+// followerBuckets are the lower bounds of the follower histogram bins.
+var followerBuckets = []int{0, 100, 200, 500, 1000, 2000, 5000, 10000, 20000, 50000}
+
+// followerHistogram bins users by follower count.
+func followerHistogram(users []User) []share {
+	bins := make([]share, len(followerBuckets))
+	for i, lo := range followerBuckets {
+		if i+1 < len(followerBuckets) {
+			bins[i].Name = fmt.Sprintf("%d–%d", lo, followerBuckets[i+1]-1)
+		} else {
+			bins[i].Name = fmt.Sprintf("%d+", lo)
+		}
+	}
+	for _, u := range users {
+		i, found := slices.BinarySearch(followerBuckets, u.Followers)
+		if !found {
+			i--
+		}
+		bins[max(i, 0)].Count++
+	}
+	for i := range bins {
+		if len(users) > 0 {
+			bins[i].Percent = 100 * float64(bins[i].Count) / float64(len(users))
+		}
+	}
+	// Drop the empty bins below the first populated one.
+	for len(bins) > 0 && bins[0].Count == 0 {
+		bins = bins[1:]
+	}
+	return bins
+}
+
+// topShares keeps the n largest shares and folds the rest into "Other".
+func topShares(shares []share, n int) []share {
+	if len(shares) <= n {
+		return shares
+	}
+	other := share{Name: "Other"}
+	for _, s := range shares[n:] {
+		other.Count += s.Count
+		other.Percent += s.Percent
+	}
+	return append(slices.Clone(shares[:n]), other)
+}
+
+// creationTimeline counts users by the year their account was created.
+func creationTimeline(users []User) []share {
+	years := make([]string, 0, len(users))
+	for _, u := range users {
+		if len(u.CreatedAt) >= 4 {
+			years = append(years, u.CreatedAt[:4])
+		}
+	}
+	timeline := shareOf(years)
+	slices.SortFunc(timeline, func(a, b share) int { return cmp.Compare(a.Name, b.Name) })
+	return timeline
+}
+
+// periodCount is one point of a time series.
+type periodCount struct {
+	Period     string
+	Count      int
+	Cumulative int
+}
+
+// timeSeries buckets RFC 3339 timestamps into periods of months months (1
+// for monthly, 3 for quarterly) and returns every period from the first to
+// the last, including empty ones, with a running total.
+func timeSeries(stamps []string, months int) []periodCount {
+	counts := map[time.Time]int{}
+	var first, last time.Time
+	for _, ts := range stamps {
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			continue
+		}
+		start := time.Date(t.Year(), time.Month((int(t.Month())-1)/months*months+1), 1, 0, 0, 0, 0, time.UTC)
+		counts[start]++
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	var series []periodCount
+	total := 0
+	for p := first; !p.After(last); p = p.AddDate(0, months, 0) {
+		total += counts[p]
+		label := p.Format("2006-01")
+		if months == 3 {
+			label = fmt.Sprintf("%d-Q%d", p.Year(), (int(p.Month())-1)/3+1)
+		}
+		series = append(series, periodCount{label, counts[p], total})
+	}
+	return series
+}
+
+func savePeriodCSV(path string, series []periodCount) error {
+	return writeRecordsCSV(path, []string{"period", "count", "cumulative"}, series, func(p periodCount) []string {
+		return []string{p.Period, strconv.Itoa(p.Count), strconv.Itoa(p.Cumulative)}
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import "fmt"
+
+// anomaly is a user flagged by one of the anomaly rules.
+type anomaly struct {
+	Login  string
+	Rule   string
+	Detail string
+}
+
+var anomalyColumns = []string{"login", "rule", "detail"}
+
+func anomalyRecord(a anomaly) []string { return []string{a.Login, a.Rule, a.Detail} }
+
+// Thresholds of the anomaly rules.
+const (
+	followRatioLimit   = 5    // following per follower
+	followRatioMinimum = 2000 // following needed before the ratio counts
+	massRepoMinimum    = 500  // public repos needed to check for empty repos
+	emptyRepoShare     = 0.9  // share of starless repos that looks like spam
+	starSpikeMinimum   = 500  // stars gained between snapshots
+	starSpikeGrowth    = 1.0  // gain relative to the earlier total
+)
+
+// findAnomalies flags users whose patterns suggest spam or manipulated
+// numbers:
+//   - follow-ratio: follows far more accounts than follow them back
+//   - mass-repos: hundreds of repos, nearly all without a single star
+//   - star-spike: total stars jumped since the previous snapshot
+//
+// previous holds total stars per login from an earlier snapshot; nil skips
+// the star-spike rule.
+func findAnomalies(users []User, repos []Repo, previous map[string]int) []anomaly {
+	stars := map[string]int{}
+	collected := map[string]int{}
+	starless := map[string]int{}
+	for _, r := range repos {
+		stars[r.Login] += r.StargazersCount
+		collected[r.Login]++
+		if r.StargazersCount == 0 {
+			starless[r.Login]++
+		}
+	}
+	var out []anomaly
+	for _, u := range users {
+		if u.Following >= followRatioMinimum && u.Following > followRatioLimit*u.Followers {
+			out = append(out, anomaly{u.Login, "follow-ratio",
+				fmt.Sprintf("follows %d accounts but has %d followers", u.Following, u.Followers)})
+		}
+		if n := collected[u.Login]; u.PublicRepos >= massRepoMinimum && n > 0 && float64(starless[u.Login]) >= emptyRepoShare*float64(n) {
+			out = append(out, anomaly{u.Login, "mass-repos",
+				fmt.Sprintf("%d public repos, %d of %d collected have no stars", u.PublicRepos, starless[u.Login], n)})
+		}
+		if before, ok := previous[u.Login]; ok {
+			gain := stars[u.Login] - before
+			if gain >= starSpikeMinimum && float64(gain) >= starSpikeGrowth*float64(before) {
+				out = append(out, anomaly{u.Login, "star-spike",
+					fmt.Sprintf("stars rose from %d to %d since the previous snapshot", before, stars[u.Login])})
+			}
+		}
+	}
+	return out
+}
+
+// previousStars returns total stars per user from the second-newest
+// snapshot in the history store, or nil if there is none.
+func previousStars(historyDir string) map[string]int {
+	snaps, err := listSnapshots(historyDir)
+	if err != nil || len(snaps) < 2 {
+		return nil
+	}
+	_, repos, err := snaps[len(snaps)-2].load()
+	if err != nil {
+		return nil
+	}
+	stars := map[string]int{}
+	for _, r := range repos {
+		stars[r.Login] += r.StargazersCount
+	}
+	return stars
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is an output file written under a temporary name next to its
+// destination and renamed into place by commit, so a crash or failed write
+// leaves the previous version, or nothing, rather than a truncated file
+// that downstream jobs would ingest silently.
+type atomicFile struct {
+	*os.File
+	path      string
+	committed bool
+}
+
+func createAtomic(path string) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+// commit flushes the file to disk and renames it into place.
+func (f *atomicFile) commit() error {
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return err
+	}
+	f.committed = true
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform can open a directory for syncing, so failures are ignored.
+	if dir, err := os.Open(filepath.Dir(f.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// discard removes the temporary file unless it was committed. Defer it
+// right after createAtomic.
+func (f *atomicFile) discard() {
+	if f.committed {
+		return
+	}
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+// writeFileAtomic is os.WriteFile through an atomicFile.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := createAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.discard()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.commit()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	_ "net/http/pprof"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startPprof serves the net/http/pprof endpoints on addr, e.g. ":6060",
+// for the rest of the process.
+func startPprof(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			fmt.Println("Error serving pprof:", err)
+		}
+	}()
+	fmt.Printf("Serving pprof on http://%s/debug/pprof/\n", addr)
+}
+
+// fakeGitHub serves the search, user, repos, and GraphQL user endpoints
+// with synthetic data: users accounts with reposPerUser repos each,
+// answering every request after latency.
+func fakeGitHub(users, reposPerUser int, latency time.Duration) *httptest.Server {
+	login := func(i int) string { return fmt.Sprintf("user%05d", i) }
+	// page slices n items by the request's page and per_page parameters,
+	// linking to the next page like GitHub does.
+	page := func(w http.ResponseWriter, r *http.Request, n int) (int, int) {
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		p, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage = min(max(perPage, 1), 100)
+		p = max(p, 1)
+		start := min((p-1)*perPage, n)
+		link := func(page int, rel string) string {
+			u := *r.URL
+			q := u.Query()
+			q.Set("page", strconv.Itoa(page))
+			u.RawQuery = q.Encode()
+			return fmt.Sprintf(`<http://%s%s>; rel="%s"`, r.Host, u.String(), rel)
+		}
+		switch {
+		case p*perPage < n:
+			w.Header().Set("Link", link(p+1, "next"))
+		case p > 1:
+			w.Header().Set("Link", link(1, "first"))
+		}
+		return start, min(start+perPage, n)
+	}
+	user := func(name string) User {
+		return User{
+			Login: name, Name: strings.ToUpper(name), Company: "@fake", Location: "Shanghai",
+			Bio: "bench user", PublicRepos: reposPerUser, Followers: 250, Following: 10,
+			CreatedAt: "2015-06-01T00:00:00Z", Type: "User",
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search/users", func(w http.ResponseWriter, r *http.Request) {
+		start, end := page(w, r, users)
+		items := make([]User, 0, end-start)
+		for i := start; i < end; i++ {
+			items = append(items, User{Login: login(i)})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"total_count": users, "items": items})
+	})
+	// Profiles never change, so revalidations get 304 Not Modified.
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mux.HandleFunc("GET /users/{login}", func(w http.ResponseWriter, r *http.Request) {
+		if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		json.NewEncoder(w).Encode(user(r.PathValue("login")))
+	})
+	mux.HandleFunc("GET /users/{login}/repos", func(w http.ResponseWriter, r *http.Request) {
+		start, end := page(w, r, reposPerUser)
+		repos := make([]Repo, 0, end-start)
+		for i := start; i < end; i++ {
+			repos = append(repos, Repo{
+				FullName:  fmt.Sprintf("%s/repo%d", r.PathValue("login"), i),
+				CreatedAt: "2020-01-01T00:00:00Z", StargazersCount: i, WatchersCount: i,
+				Language: "Go", HasWiki: true, LicenseName: "mit",
+			})
+		}
+		json.NewEncoder(w).Encode(repos)
+	})
+	// The GraphQL endpoint answers the aliased user batches of --graphql.
+	alias := regexp.MustCompile(`(u\d+): repositoryOwner\(login: "([^"]+)"\)`)
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		data := map[string]any{}
+		for _, m := range alias.FindAllStringSubmatch(req.Query, -1) {
+			u := user(m[2])
+			g := graphQLUser{Typename: u.Type, Login: u.Login, Name: u.Name, Company: u.Company, Location: u.Location, Bio: u.Bio, CreatedAt: u.CreatedAt}
+			g.Repositories.TotalCount, g.Followers.TotalCount, g.Following.TotalCount = u.PublicRepos, u.Followers, u.Following
+			data[m[1]] = g
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+		w.Header().Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// benchPhase is the cost of one phase of a benchmark run.
+type benchPhase struct {
+	name     string
+	duration time.Duration
+	calls    int64
+	allocMB  float64
+}
+
+// measure runs fn and records its wall time, API calls, and allocations.
+func measure(name string, c *apiClient, fn func()) benchPhase {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	calls, start := c.callCount(), time.Now()
+	fn()
+	p := benchPhase{name: name, duration: time.Since(start), calls: c.callCount() - calls}
+	runtime.ReadMemStats(&after)
+	p.allocMB = float64(after.TotalAlloc-before.TotalAlloc) / (1 << 20)
+	return p
+}
+
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	users := fs.Int("users", 1000, "number of users the fake server returns")
+	repos := fs.Int("repos", 30, "repos per user")
+	latency := fs.Duration("latency", 20*time.Millisecond, "simulated latency of every API response")
+	graphQL := fs.Bool("graphql", false, "fetch user details in GraphQL batches")
+	pprofAddr := fs.String("pprof", "", "serve pprof endpoints on this address during the benchmark, e.g. :6060")
+	fs.Parse(args)
+
+	if *pprofAddr != "" {
+		startPprof(*pprofAddr)
+	}
+	srv := fakeGitHub(*users, *repos, *latency)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "tds-bench")
+	if err != nil {
+		fmt.Println("Error creating output directory:", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	client := newClient(withToken("bench"), withBaseURL(srv.URL))
+	var searched, detailed []User
+	var allRepos []Repo
+	var phases []benchPhase
+	phases = append(phases, measure("search", client, func() {
+		searched, err = client.fetchUsersInShanghai(ctx)
+	}))
+	if err != nil {
+		fmt.Println("Error fetching users:", err)
+		return 1
+	}
+	phases = append(phases, measure("details", client, func() {
+		if *graphQL {
+			detailed = client.fetchUserDetailsGraphQL(ctx, searched, graphQLBatch)
+		} else {
+			detailed = client.fetchUserDetailsConcurrently(ctx, searched)
+		}
+	}))
+	phases = append(phases, measure("repos", client, func() {
+		allRepos, _ = client.fetchUserReposConcurrently(ctx, detailed)
+	}))
+	phases = append(phases, measure("export", client, func() {
+		if err = writeRecordsCSV(filepath.Join(dir, "users.csv"), userColumns, detailed, userRecord); err == nil {
+			err = writeRecordsCSV(filepath.Join(dir, "repositories.csv"), repoColumns, allRepos, repoRecord)
+		}
+	}))
+	if err != nil {
+		fmt.Println("Error exporting:", err)
+		return 1
+	}
+
+	fmt.Printf("Benchmark: %d users, %d repos, %s latency\n", len(detailed), len(allRepos), *latency)
+	fmt.Printf("  %-8s %12s %8s %10s %10s\n", "phase", "time", "calls", "calls/s", "alloc MB")
+	var total time.Duration
+	for _, p := range phases {
+		total += p.duration
+		rate := float64(p.calls) / p.duration.Seconds()
+		fmt.Printf("  %-8s %12s %8d %10.0f %10.1f\n", p.name, p.duration.Round(time.Millisecond), p.calls, rate, p.allocMB)
+	}
+	fmt.Printf("  %-8s %12s %8d\n", "total", total.Round(time.Millisecond), client.callCount())
+	return 0
+}
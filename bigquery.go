@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const bigQueryScope = "https://www.googleapis.com/auth/bigquery"
+
+// bigQueryExporter batch-loads users and repos into BigQuery tables,
+// replacing their contents. Tables are created with a schema derived from
+// the User and Repo structs.
+type bigQueryExporter struct {
+	account    *googleServiceAccount
+	project    string
+	dataset    string
+	usersTable string
+	reposTable string
+}
+
+func (e *bigQueryExporter) name() string { return "BigQuery" }
+
+func (e *bigQueryExporter) export(ctx context.Context, users []User, repos []Repo) error {
+	token, err := e.account.accessToken(ctx, bigQueryScope)
+	if err != nil {
+		return err
+	}
+	if err := loadBigQueryTable(ctx, e, token, e.usersTable, users); err != nil {
+		return err
+	}
+	return loadBigQueryTable(ctx, e, token, e.reposTable, repos)
+}
+
+type bigQueryField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode"`
+}
+
+// bigQuerySchema maps the JSON-tagged fields of a struct type to columns.
+func bigQuerySchema(t reflect.Type) []bigQueryField {
+	var fields []bigQueryField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		typ := "STRING"
+		switch f.Type.Kind() {
+		case reflect.Bool:
+			typ = "BOOLEAN"
+		case reflect.Int, reflect.Int64, reflect.Int32:
+			typ = "INTEGER"
+		case reflect.Float64, reflect.Float32:
+			typ = "FLOAT"
+		}
+		fields = append(fields, bigQueryField{Name: name, Type: typ, Mode: "NULLABLE"})
+	}
+	return fields
+}
+
+// loadBigQueryTable runs a load job from newline-delimited JSON and waits
+// for it to finish.
+func loadBigQueryTable[T any](ctx context.Context, e *bigQueryExporter, token, table string, rows []T) error {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{
+		"configuration": map[string]any{
+			"load": map[string]any{
+				"destinationTable": map[string]string{
+					"projectId": e.project,
+					"datasetId": e.dataset,
+					"tableId":   table,
+				},
+				"schema":            map[string]any{"fields": bigQuerySchema(reflect.TypeFor[T]())},
+				"sourceFormat":      "NEWLINE_DELIMITED_JSON",
+				"writeDisposition":  "WRITE_TRUNCATE",
+				"createDisposition": "CREATE_IF_NEEDED",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	part.Write(config)
+	part, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+	part.Write(data.Bytes())
+	mw.Close()
+
+	endpoint := fmt.Sprintf("https://bigquery.googleapis.com/upload/bigquery/v2/projects/%s/jobs?uploadType=multipart", e.project)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+
+	var job bigQueryJob
+	if err := doJSON(req, &job); err != nil {
+		return err
+	}
+	for job.Status.State != "DONE" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+		endpoint := fmt.Sprintf("https://bigquery.googleapis.com/bigquery/v2/projects/%s/jobs/%s?location=%s",
+			e.project, job.JobReference.JobID, job.JobReference.Location)
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if err := doJSON(req, &job); err != nil {
+			return err
+		}
+	}
+	if job.Status.ErrorResult != nil {
+		return fmt.Errorf("loading %s.%s: %s", e.dataset, table, job.Status.ErrorResult.Message)
+	}
+	return nil
+}
+
+type bigQueryJob struct {
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status"`
+}
+
+// parseBigQueryDataset splits "project.dataset", defaulting the project to
+// the service account's own.
+func parseBigQueryDataset(s string, account *googleServiceAccount) (project, dataset string, err error) {
+	project, dataset, ok := strings.Cut(s, ".")
+	if !ok {
+		project, dataset = account.ProjectID, s
+	}
+	if project == "" || dataset == "" {
+		return "", "", errors.New("--bigquery-dataset must be project.dataset")
+	}
+	return project, dataset, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages are the languages told apart by their script alone.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+}
+
+// latinStopwords are common short words of the languages written in the
+// Latin script, which tell them apart.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "at", "for", "with", "i", "a", "an", "my", "on", "love", "developer", "engineer"},
+	"de": {"und", "der", "die", "das", "ich", "bei", "mit", "für", "ist", "nicht", "ein", "eine"},
+	"fr": {"le", "la", "les", "et", "de", "du", "des", "je", "chez", "pour", "est", "un", "une"},
+	"es": {"el", "la", "los", "las", "y", "de", "en", "con", "soy", "para", "es", "un", "una"},
+	"pt": {"o", "os", "as", "e", "de", "do", "da", "em", "com", "sou", "para", "um", "uma"},
+	"it": {"il", "lo", "gli", "e", "di", "del", "della", "in", "con", "sono", "per", "un", "una"},
+}
+
+// detectLanguage guesses the language of a bio as an ISO 639-1 code, or
+// returns "" when there is too little to go on. Non-Latin scripts decide
+// it by their letters, with each Han character counting as much as a word
+// of Latin letters, so "全栈工程师 @ Alibaba" is zh; Japanese kana win
+// over the Han characters mixed with them. Latin text is told apart by
+// its common words.
+func detectLanguage(bio string) string {
+	counts := map[string]int{}
+	latin := 0
+	for _, r := range bio {
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				weight := 1
+				if s.lang == "zh" || s.lang == "ja" {
+					weight = 4
+				}
+				counts[s.lang] += weight
+				break
+			}
+		}
+	}
+	if counts["ja"] > 0 && counts["zh"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	best, bestCount := "", 0
+	for _, s := range scriptLanguages {
+		if n := counts[s.lang]; n > bestCount {
+			best, bestCount = s.lang, n
+		}
+	}
+	if bestCount >= latin {
+		return best
+	}
+	return latinLanguage(bio)
+}
+
+// latinLanguage picks the Latin-script language whose common words occur
+// most in text, preferring English on a tie.
+func latinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	seen := map[string]bool{}
+	for _, w := range words {
+		seen[w] = true
+	}
+	best, bestHits := "", 0
+	for _, lang := range []string{"en", "de", "fr", "es", "pt", "it"} {
+		hits := 0
+		for _, w := range latinStopwords[lang] {
+			if seen[w] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = lang, hits
+		}
+	}
+	return best
+}
+
+// bioLanguageColumns returns the users.csv column with the detected
+// language of each user's bio.
+func bioLanguageColumns() columnSet[User] {
+	return columnSet[User]{[]string{"bio_language"}, func(u User) []string {
+		return []string{detectLanguage(u.Bio)}
+	}}
+}
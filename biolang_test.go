@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		bio, want string
+	}{
+		{"", ""},
+		{"Just code", ""},
+		{"全栈工程师 @ Alibaba", "zh"},
+		{"東京のエンジニア", "ja"},
+		{"서울 개발자", "ko"},
+		{"Программист из Москвы", "ru"},
+		{"Go developer, 北京", "en"},
+		{"Software engineer and open source lover", "en"},
+		{"Entwickler bei SAP und Open Source", "de"},
+		{"Développeur chez Google et passionné", "fr"},
+		{"Desarrollador de software en Madrid y Python", "es"},
+		{"Sou desenvolvedor em São Paulo com Go", "pt"},
+		{"Sviluppatore di software, sono a Roma con Go", "it"},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.bio); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.bio, got, tt.want)
+		}
+	}
+}
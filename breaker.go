@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	maxBreakerCooldown      = 5 * time.Minute
+)
+
+// breaker is a circuit breaker around the API. After threshold consecutive
+// failures (network errors or 5xx responses) it opens and holds every
+// request for the cooldown; then one probe request is let through. A
+// successful probe closes the circuit, a failed one reopens it with the
+// cooldown doubled, up to maxBreakerCooldown. A nil breaker never opens.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	wait      time.Duration
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, wait: cooldown}
+}
+
+// acquire blocks while the circuit is open, returning early with the
+// context's error. When the cooldown has passed, a single caller is let
+// through as the probe, reported by probe, and the rest keep waiting for
+// its outcome. A probe must end in record or abandon.
+func (b *breaker) acquire(ctx context.Context) (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+	for {
+		b.mu.Lock()
+		if b.failures < b.threshold {
+			b.mu.Unlock()
+			return false, nil
+		}
+		delay := time.Until(b.openUntil)
+		if !b.probing && delay <= 0 {
+			b.probing = true
+			b.mu.Unlock()
+			return true, nil
+		}
+		if b.probing {
+			// Check back shortly for the probe's outcome.
+			delay = max(delay, time.Second)
+		}
+		b.mu.Unlock()
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// abandon gives up a probe that was never sent or whose outcome is
+// unknown, such as one cancelled with its context, so another request can
+// probe in its place.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record reports the outcome of a request.
+func (b *breaker) record(ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		if b.failures >= b.threshold {
+			fmt.Println("Circuit closed: GitHub API is responding again")
+		}
+		b.failures, b.wait, b.probing = 0, b.cooldown, false
+		return
+	}
+	b.failures++
+	switch {
+	case b.probing:
+		b.probing = false
+		b.wait = min(2*b.wait, maxBreakerCooldown)
+		b.openUntil = time.Now().Add(b.wait)
+		fmt.Printf("Circuit still open: probe failed; next probe in %s\n", b.wait)
+	case b.failures == b.threshold:
+		b.openUntil = time.Now().Add(b.wait)
+		fmt.Printf("Circuit open after %d consecutive failures; pausing requests for %s\n", b.failures, b.wait)
+	}
+}
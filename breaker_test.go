@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	b := newBreaker(2, 20*time.Millisecond)
+	ctx := context.Background()
+
+	// Closed: requests pass and are not probes.
+	if probe, err := b.acquire(ctx); probe || err != nil {
+		t.Fatalf("closed acquire = %v, %v; want false, nil", probe, err)
+	}
+	b.record(false)
+	if probe, _ := b.acquire(ctx); probe {
+		t.Fatal("one failure below the threshold opened the circuit")
+	}
+	b.record(false)
+
+	// Open: requests wait for the cooldown.
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := b.acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("open acquire err = %v; want a deadline error", err)
+	}
+
+	// Half open: one probe; a failed probe doubles the cooldown.
+	if probe, err := b.acquire(ctx); !probe || err != nil {
+		t.Fatalf("acquire after cooldown = %v, %v; want a probe", probe, err)
+	}
+	b.record(false)
+	if b.wait != 40*time.Millisecond {
+		t.Fatalf("cooldown after a failed probe = %s; want 40ms", b.wait)
+	}
+
+	// An abandoned probe lets the next request probe instead of hanging.
+	if probe, _ := b.acquire(ctx); !probe {
+		t.Fatal("want a probe after the doubled cooldown")
+	}
+	b.abandon()
+	wait, cancel2 := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel2()
+	if probe, err := b.acquire(wait); !probe || err != nil {
+		t.Fatalf("acquire after an abandoned probe = %v, %v; want a probe", probe, err)
+	}
+
+	// A successful probe closes the circuit and resets the cooldown.
+	b.record(true)
+	if b.failures != 0 || b.wait != b.cooldown || b.probing {
+		t.Fatalf("after a successful probe: failures %d, wait %s, probing %v", b.failures, b.wait, b.probing)
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *breaker
+	if newBreaker(0, time.Second) != nil {
+		t.Fatal("threshold 0 should disable the breaker")
+	}
+	if probe, err := b.acquire(context.Background()); probe || err != nil {
+		t.Fatalf("nil acquire = %v, %v", probe, err)
+	}
+	b.record(false)
+}
+
+// A request that never reaches the server, here one over the budget, must
+// not leave the breaker's probe taken.
+func TestBreakerProbeReleasedOnBudget(t *testing.T) {
+	c := newClient(withToken("x"), withBaseURL("http://127.0.0.1:1"), withMaxCalls(1))
+	c.breaker = newBreaker(1, time.Millisecond)
+	c.breaker.record(false)
+	time.Sleep(2 * time.Millisecond)
+	c.calls.Store(1)
+	if _, err := c.get(context.Background(), c.baseURL+"/users/a"); !errors.Is(err, errBudgetExhausted) {
+		t.Fatalf("err = %v; want errBudgetExhausted", err)
+	}
+	if c.breaker.probing {
+		t.Fatal("the probe is still taken after a request refused by the budget")
+	}
+}
+
+// The client stops sending while the breaker is open and resumes once a
+// probe succeeds.
+func TestClientBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	c.breaker = newBreaker(2, 50*time.Millisecond)
+	ctx := context.Background()
+
+	for range 2 {
+		resp, err := c.get(ctx, srv.URL)
+		if err != nil || resp.StatusCode != http.StatusBadGateway {
+			t.Fatalf("get = %v, %v", resp, err)
+		}
+		resp.Body.Close()
+	}
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.get(short, srv.URL); !errors.Is(err, context.DeadlineExceeded) || calls.Load() != 2 {
+		t.Fatalf("open breaker: err %v after %d requests; want a deadline error after 2", err, calls.Load())
+	}
+
+	healthy.Store(true)
+	for range 2 {
+		resp, err := c.get(ctx, srv.URL)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("after the cooldown: %v, %v", resp, err)
+		}
+		resp.Body.Close()
+	}
+	if calls.Load() != 4 {
+		t.Errorf("%d requests, want 4", calls.Load())
+	}
+}
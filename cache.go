@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// responseCache stores API response bodies on disk, keyed by URL, so that
+// re-running the export does not hit the API again. A nil cache is a no-op.
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+type cacheEntry struct {
+	URL          string          `json:"url"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Link         string          `json:"link,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// header returns the response headers kept with the entry.
+func (e *cacheEntry) header() http.Header {
+	h := http.Header{}
+	if e.LastModified != "" {
+		h.Set("Last-Modified", e.LastModified)
+	}
+	if e.Link != "" {
+		h.Set("Link", e.Link)
+	}
+	return h
+}
+
+func newResponseCache(dir string, ttl time.Duration) (*responseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &responseCache{dir: dir, ttl: ttl}, nil
+}
+
+func (rc *responseCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// lookup returns the cached entry for url, if any, and whether it is
+// younger than the TTL. Stale entries are returned for revalidation.
+func (rc *responseCache) lookup(url string) (*cacheEntry, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(rc.path(url))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil, false
+	}
+	return &entry, rc.ttl == 0 || time.Since(entry.FetchedAt) <= rc.ttl
+}
+
+// store caches body with the response's Last-Modified and Link headers.
+func (rc *responseCache) store(url string, body []byte, header http.Header) error {
+	if rc == nil || !json.Valid(body) {
+		return nil
+	}
+	entry := cacheEntry{URL: url, FetchedAt: time.Now().UTC(), LastModified: header.Get("Last-Modified"), Link: header.Get("Link"), Body: body}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp := rc.path(url) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, rc.path(url))
+}
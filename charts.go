@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// barChart is a horizontal bar chart that can be rendered as SVG or PNG.
+// It is drawn with the standard library alone, as the tool has no
+// third-party dependencies to pull a charting library in with.
+type barChart struct {
+	Title string
+	Bars  []share
+}
+
+const (
+	chartWidth  = 800
+	chartLabelW = 200
+	chartBarH   = 24
+	chartTop    = 40
+)
+
+var (
+	chartBarColor  = color.RGBA{0x4e, 0x79, 0xa7, 0xff}
+	chartTextColor = color.RGBA{0x22, 0x22, 0x22, 0xff}
+)
+
+func (c barChart) height() int { return chartTop + chartBarH*len(c.Bars) + 20 }
+
+func (c barChart) maxCount() int {
+	top := 1
+	for _, b := range c.Bars {
+		top = max(top, b.Count)
+	}
+	return top
+}
+
+// barWidth is the length in pixels of a bar, leaving room for its value.
+func (c barChart) barWidth(count int) int {
+	return (chartWidth - chartLabelW - 80) * count / c.maxCount()
+}
+
+func (c barChart) writeSVG(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="13">`+"\n", chartWidth, c.height())
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="10" y="24" font-size="16" font-weight="bold">%s</text>`+"\n", html.EscapeString(c.Title))
+	for i, bar := range c.Bars {
+		y := chartTop + i*chartBarH
+		bw := c.barWidth(bar.Count)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", chartLabelW-8, y+16, html.EscapeString(bar.Name))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#4e79a7"><title>%s: %d</title></rect>`+"\n", chartLabelW, y+3, bw, chartBarH-6, html.EscapeString(bar.Name), bar.Count)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%d</text>`+"\n", chartLabelW+bw+6, y+16, bar.Count)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c barChart) writePNG(w io.Writer) error {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, c.height()))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	drawText(img, 10, 12, c.Title, 3)
+	for i, bar := range c.Bars {
+		y := chartTop + i*chartBarH
+		bw := c.barWidth(bar.Count)
+		label := bar.Name
+		if n := (chartLabelW - 16) / (4 * 2); len([]rune(label)) > n {
+			label = string([]rune(label)[:n])
+		}
+		drawText(img, chartLabelW-8-textWidth(label, 2), y+7, label, 2)
+		draw.Draw(img, image.Rect(chartLabelW, y+3, chartLabelW+bw, y+chartBarH-3), image.NewUniform(chartBarColor), image.Point{}, draw.Src)
+		drawText(img, chartLabelW+bw+6, y+7, fmt.Sprint(bar.Count), 2)
+	}
+	return png.Encode(w, img)
+}
+
+// glyphs is a 3x5 pixel font, one string of rows per character. Characters
+// without a glyph are drawn as '?'.
+var glyphs = map[rune]string{
+	'0': "111 101 101 101 111", '1': "010 110 010 010 111", '2': "111 001 111 100 111",
+	'3': "111 001 111 001 111", '4': "101 101 111 001 001", '5': "111 100 111 001 111",
+	'6': "111 100 111 101 111", '7': "111 001 001 001 001", '8': "111 101 111 101 111",
+	'9': "111 101 111 001 111", 'A': "010 101 111 101 101", 'B': "110 101 110 101 110",
+	'C': "011 100 100 100 011", 'D': "110 101 101 101 110", 'E': "111 100 110 100 111",
+	'F': "111 100 110 100 100", 'G': "011 100 101 101 011", 'H': "101 101 111 101 101",
+	'I': "111 010 010 010 111", 'J': "001 001 001 101 010", 'K': "101 101 110 101 101",
+	'L': "100 100 100 100 111", 'M': "101 111 111 101 101", 'N': "110 101 101 101 101",
+	'O': "010 101 101 101 010", 'P': "110 101 110 100 100", 'Q': "010 101 101 110 011",
+	'R': "110 101 110 101 101", 'S': "011 100 010 001 110", 'T': "111 010 010 010 010",
+	'U': "101 101 101 101 111", 'V': "101 101 101 101 010", 'W': "101 101 111 111 101",
+	'X': "101 101 010 101 101", 'Y': "101 101 010 010 010", 'Z': "111 001 010 100 111",
+	'+': "000 010 111 010 000", '-': "000 000 111 000 000", '–': "000 000 111 000 000",
+	'.': "000 000 000 000 010", '%': "101 001 010 100 101", '(': "001 010 010 010 001",
+	')': "100 010 010 010 100", '#': "101 111 101 111 101", '/': "001 001 010 100 100",
+	'_': "000 000 000 000 111", ' ': "000 000 000 000 000", '?': "111 001 011 000 010",
+}
+
+func textWidth(s string, scale int) int {
+	return len([]rune(s)) * 4 * scale
+}
+
+// drawText renders s with the pixel font, top-left at (x, y).
+func drawText(img draw.Image, x, y int, s string, scale int) {
+	ink := image.NewUniform(chartTextColor)
+	for _, r := range s {
+		g, ok := glyphs[unicode.ToUpper(r)]
+		if !ok {
+			g = glyphs['?']
+		}
+		for row, bits := range strings.Fields(g) {
+			for col, bit := range bits {
+				if bit == '1' {
+					px := x + col*scale
+					py := y + row*scale
+					draw.Draw(img, image.Rect(px, py, px+scale, py+scale), ink, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
+
+// writeChart renders the chart into dir as name.png or name.svg.
+func writeChart(dir, name, format string, c barChart) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := createAtomic(filepath.Join(dir, name+"."+format))
+	if err != nil {
+		return err
+	}
+	defer file.discard()
+
+	switch format {
+	case "png":
+		err = c.writePNG(file)
+	case "svg":
+		err = c.writeSVG(file)
+	default:
+		return fmt.Errorf("unknown chart format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return file.commit()
+}
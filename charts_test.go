@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var testChart = barChart{
+	Title: `Top companies <by "users">`,
+	Bars:  []share{{Name: "ALIBABA", Count: 40}, {Name: "字节跳动 & co", Count: 20}, {Name: "NONE", Count: 0}},
+}
+
+func TestBarWidth(t *testing.T) {
+	full := chartWidth - chartLabelW - 80
+	for _, tt := range []struct{ count, want int }{{40, full}, {20, full / 2}, {0, 0}} {
+		if got := testChart.barWidth(tt.count); got != tt.want {
+			t.Errorf("barWidth(%d) = %d, want %d", tt.count, got, tt.want)
+		}
+	}
+	if got := (barChart{Bars: []share{{Count: 0}}}).barWidth(0); got != 0 {
+		t.Errorf("barWidth of an all-zero chart = %d", got)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	var b bytes.Buffer
+	if err := testChart.writeSVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	// The output must be well-formed XML despite the markup in the names.
+	dec := xml.NewDecoder(bytes.NewReader(b.Bytes()))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("SVG is not well-formed: %v\n%s", err, b.String())
+			}
+			break
+		}
+	}
+	widths := regexp.MustCompile(`<rect x="\d+" y="\d+" width="(\d+)"`).FindAllStringSubmatch(b.String(), -1)
+	if len(widths) != len(testChart.Bars) {
+		t.Fatalf("%d bars drawn, want %d", len(widths), len(testChart.Bars))
+	}
+	for i, m := range widths {
+		if w, _ := strconv.Atoi(m[1]); w != testChart.barWidth(testChart.Bars[i].Count) {
+			t.Errorf("bar %d is %d wide, want %d", i, w, testChart.barWidth(testChart.Bars[i].Count))
+		}
+	}
+	if !bytes.Contains(b.Bytes(), []byte("字节跳动 &amp; co")) {
+		t.Error("names are not escaped")
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var b bytes.Buffer
+	if err := testChart.writePNG(&b); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Size(); got.X != chartWidth || got.Y != testChart.height() {
+		t.Fatalf("PNG is %v, want %dx%d", got, chartWidth, testChart.height())
+	}
+	// The top bar is drawn in the bar color across its width.
+	y := chartTop + chartBarH/2
+	for _, x := range []int{chartLabelW + 1, chartLabelW + testChart.barWidth(40) - 1} {
+		if got := img.At(x, y); got != chartBarColor {
+			t.Errorf("pixel (%d, %d) = %v, want the bar color", x, y, got)
+		}
+	}
+}
+
+func TestWriteChart(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{"png", "svg"} {
+		if err := writeChart(dir, "companies", format, testChart); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "companies."+format)); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+	if err := writeChart(dir, "companies", "gif", testChart); err == nil {
+		t.Error("an unknown format was accepted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "companies.gif")); err == nil {
+		t.Error("a file was left for an unknown format")
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// classifierRoles and seniorityHints are the labels the classifier may
+// give; anything else an endpoint answers becomes "other" or "".
+var (
+	classifierRoles = []string{"backend", "frontend", "fullstack", "mobile", "ml", "data", "devops", "security", "devrel", "other"}
+	seniorityHints  = []string{"student", "junior", "mid", "senior", "lead", "founder"}
+)
+
+// classification is a user's role and seniority hint as the classifier
+// read them from their bio and company.
+type classification struct {
+	Role      string `json:"role"`
+	Seniority string `json:"seniority"`
+}
+
+// classifier labels users by sending their bios, in batches, to an
+// OpenAI-compatible chat completions endpoint, such as OpenAI's or a
+// local Ollama or vLLM server. Answers are cached in a JSON file keyed by
+// the model and text, so reruns only send new or changed bios.
+type classifier struct {
+	url, model, key string
+	batch           int
+	cachePath       string
+	cache           map[string]classification
+}
+
+func newClassifier(url, model, key, cachePath string, batch int) (*classifier, error) {
+	c := &classifier{url: url, model: model, key: key, batch: batch, cachePath: cachePath, cache: map[string]classification{}}
+	data, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.cache); err != nil {
+		return nil, fmt.Errorf("%s: %w", cachePath, err)
+	}
+	return c, nil
+}
+
+// cacheKey identifies what the classifier was asked about a user.
+func (c *classifier) cacheKey(u User) string {
+	sum := sha256.Sum256([]byte(c.model + "\x00" + u.Bio + "\x00" + u.Company))
+	return hex.EncodeToString(sum[:])
+}
+
+const classifierPrompt = `You classify software developers from their GitHub bio and company.
+For each user in the JSON array you are given, answer with their role, one of: %s,
+and a seniority hint, one of: %s, or "" when the bio gives no hint.
+Reply with only a JSON object of the form {"users": [{"id": 0, "role": "backend", "seniority": "senior"}]}.`
+
+// classify returns the classification of every user with a bio or
+// company, by login. Users in a failed batch are absent from the map, and
+// the first error is returned alongside it.
+func (c *classifier) classify(ctx context.Context, users []User) (map[string]classification, error) {
+	out := map[string]classification{}
+	var pending []User
+	for _, u := range users {
+		if u.Bio == "" && u.Company == "" {
+			continue
+		}
+		if cl, ok := c.cache[c.cacheKey(u)]; ok {
+			out[u.Login] = cl
+		} else {
+			pending = append(pending, u)
+		}
+	}
+	var firstErr error
+	for batch := range slices.Chunk(pending, c.batch) {
+		if ctx.Err() != nil {
+			return out, cmp.Or(firstErr, ctx.Err())
+		}
+		labels, err := c.send(ctx, batch)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for i, u := range batch {
+			if cl, ok := labels[i]; ok {
+				out[u.Login] = cl
+				c.cache[c.cacheKey(u)] = cl
+			}
+		}
+		if err := c.save(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return out, firstErr
+}
+
+// send asks the endpoint about one batch and returns the answers by the
+// users' index in it.
+func (c *classifier) send(ctx context.Context, batch []User) (map[int]classification, error) {
+	type item struct {
+		ID      int    `json:"id"`
+		Bio     string `json:"bio"`
+		Company string `json:"company"`
+	}
+	items := make([]item, len(batch))
+	for i, u := range batch {
+		items[i] = item{i, u.Bio, u.Company}
+	}
+	question, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":       c.model,
+		"temperature": 0,
+		"messages": []map[string]string{
+			{"role": "system", "content": fmt.Sprintf(classifierPrompt, strings.Join(classifierRoles, ", "), strings.Join(seniorityHints, ", "))},
+			{"role": "user", "content": string(question)},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := doJSON(req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("classifier: empty response")
+	}
+	var answer struct {
+		Users []struct {
+			ID        int    `json:"id"`
+			Role      string `json:"role"`
+			Seniority string `json:"seniority"`
+		} `json:"users"`
+	}
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	// Some models wrap JSON in a Markdown code fence despite being asked
+	// not to.
+	content = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(content, "```json"), "```"), "```")
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return nil, fmt.Errorf("classifier: unreadable answer: %w", err)
+	}
+	labels := map[int]classification{}
+	for _, a := range answer.Users {
+		if a.ID < 0 || a.ID >= len(batch) {
+			continue
+		}
+		cl := classification{Role: "other", Seniority: ""}
+		if role := strings.ToLower(strings.TrimSpace(a.Role)); slices.Contains(classifierRoles, role) {
+			cl.Role = role
+		}
+		if hint := strings.ToLower(strings.TrimSpace(a.Seniority)); slices.Contains(seniorityHints, hint) {
+			cl.Seniority = hint
+		}
+		labels[a.ID] = cl
+	}
+	return labels, nil
+}
+
+func (c *classifier) save() error {
+	data, err := json.MarshalIndent(c.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.cachePath, data)
+}
+
+// classificationColumns returns the users.csv columns for the classifier
+// enrichment. Users that were not classified are left blank.
+func classificationColumns(labels map[string]classification) columnSet[User] {
+	return columnSet[User]{
+		columns: []string{"role", "seniority_hint"},
+		record: func(u User) []string {
+			cl := labels[u.Login]
+			return []string{cl.Role, cl.Seniority}
+		},
+	}
+}
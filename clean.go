@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Hangul syllable composition constants, from the Unicode standard.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// composeNFC composes each character with the combining characters that
+// follow it wherever Unicode has a precomposed form, as NFC does, so that
+// a name typed as "e" and a combining acute accent matches one typed as
+// "é". The marks must already be in canonical order, as typed text
+// almost always is.
+func composeNFC(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if n := len(out); n > 0 {
+			last := out[n-1]
+			if c, ok := compositions[[2]rune{last, r}]; ok {
+				out[n-1] = c
+				continue
+			}
+			if l := last - hangulLBase; l >= 0 && l < hangulLCount {
+				if v := r - hangulVBase; v >= 0 && v < hangulVCount {
+					out[n-1] = hangulSBase + (l*hangulVCount+v)*hangulTCount
+					continue
+				}
+			}
+			if sIndex := last - hangulSBase; sIndex >= 0 && sIndex < hangulSCount && sIndex%hangulTCount == 0 {
+				if t := r - hangulTBase; t > 0 && t < hangulTCount {
+					out[n-1] = last + t
+					continue
+				}
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// cleanText drops invalid UTF-8 and control characters, composes the
+// text to NFC, and collapses each run of whitespace, line breaks
+// included, to one space.
+func cleanText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(composeNFC(s)), " ")
+}
+
+// cleanUsers cleans the names, bios, companies, and locations of users in
+// place and returns how many users changed.
+func cleanUsers(users []User) int {
+	changed := 0
+	for i := range users {
+		u := &users[i]
+		before := *u
+		u.Name, u.Bio = cleanText(u.Name), cleanText(u.Bio)
+		u.Company, u.Location = cleanText(u.Company), cleanText(u.Location)
+		if *u != before {
+			changed++
+		}
+	}
+	return changed
+}
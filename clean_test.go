@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestComposeNFC(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"Jose\u0301", "Jos\u00e9"},
+		{"Jos\u00e9", "Jos\u00e9"},
+		{"Zoe\u0308 and A\u030a", "Zo\u00eb and \u00c5"},
+		{"\u1100\u1161", "\uac00"},
+		{"\u1100\u1161\u11a8", "\uac01"},
+		{"\u0301leading mark", "\u0301leading mark"},
+	}
+	for _, tt := range tests {
+		if got := composeNFC(tt.in); got != tt.want {
+			t.Errorf("composeNFC(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"  Jane\t\n  Doe \r\n", "Jane Doe"},
+		{"bad\xffbyte", "badbyte"},
+		{"nul\x00 and bell\a", "nul and bell"},
+		{"Rene\u0301e", "Ren\u00e9e"},
+	}
+	for _, tt := range tests {
+		if got := cleanText(tt.in); got != tt.want {
+			t.Errorf("cleanText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanUsers(t *testing.T) {
+	users := []User{
+		{Login: "a", Name: "Ann", Bio: "clean"},
+		{Login: "b", Name: " Bob ", Company: "Acme\nInc"},
+	}
+	if n := cleanUsers(users); n != 1 {
+		t.Errorf("cleanUsers changed %d users, want 1", n)
+	}
+	if users[1].Name != "Bob" || users[1].Company != "Acme Inc" {
+		t.Errorf("cleaned user = %+v", users[1])
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errBudgetExhausted is returned once the run has spent its --max-api-calls.
+var errBudgetExhausted = errors.New("api call budget exhausted")
+
+// apiClient sends requests to the GitHub REST API and enforces the run's
+// API call budget.
+type apiClient struct {
+	http     *http.Client
+	baseURL  string
+	token    string
+	maxCalls int64
+	calls    atomic.Int64
+	cache    *responseCache
+	// retries is how many times a request that failed with a network
+	// error or a 5xx status is repeated.
+	retries int
+	// userTimeout, when set, bounds the requests for any one user.
+	userTimeout time.Duration
+	// hedgeAfter, when set, is how long a GET may go unanswered before a
+	// second copy is sent.
+	hedgeAfter time.Duration
+	// pace, when set, spaces out every request; it is used without a
+	// token, when GitHub allows only 60 requests an hour.
+	pace *pacer
+	// throttle, when set, caps the requests a second to each host.
+	throttle *throttle
+	// breaker pauses requests while the API keeps failing.
+	breaker *breaker
+	// header is sent with every request; it carries the User-Agent and any
+	// --header values.
+	header http.Header
+	// requestHooks and responseHooks run around every request sent.
+	requestHooks  []requestHook
+	responseHooks []responseHook
+	// progress, when set, reports how far each phase has got.
+	progress *progressReporter
+
+	// onUser and onRepos, when set, are called as each user's details or
+	// repos arrive. They are never called concurrently.
+	onUser  func(User)
+	onRepos func(login string, repos []Repo)
+}
+
+// requestHook runs before a request is sent, after its headers and token
+// are set. It may change the request, e.g. to add headers, or return an
+// error to abort it without spending an API call.
+type requestHook func(req *http.Request) error
+
+// responseHook runs after a request that started at start ended with resp
+// or err, e.g. to log it or collect metrics. It must not read resp.Body.
+type responseHook func(req *http.Request, start time.Time, resp *http.Response, err error)
+
+// addRequestHook registers fn to run before every request.
+func (c *apiClient) addRequestHook(fn requestHook) { c.requestHooks = append(c.requestHooks, fn) }
+
+// addResponseHook registers fn to run after every request.
+func (c *apiClient) addResponseHook(fn responseHook) { c.responseHooks = append(c.responseHooks, fn) }
+
+// defaultUserAgent identifies the tool to GitHub, which rejects requests
+// without a User-Agent.
+const defaultUserAgent = "tds-scraper"
+
+// Unauthenticated requests are limited to 60 an hour, so without a token
+// the client paces itself and caps the run.
+const (
+	unauthenticatedInterval = time.Minute
+	unauthenticatedMaxCalls = 60
+)
+
+// clientOption configures a client built by newClient.
+type clientOption func(*apiClient)
+
+// withToken authenticates requests with token.
+func withToken(token string) clientOption {
+	return func(c *apiClient) { c.token = token }
+}
+
+// withBaseURL sends requests to the API at u, e.g. a GitHub Enterprise
+// server's https://host/api/v3, instead of api.github.com.
+func withBaseURL(u string) clientOption {
+	return func(c *apiClient) { c.baseURL = strings.TrimSuffix(u, "/") }
+}
+
+// withTimeout bounds each request, including reading its body.
+func withTimeout(d time.Duration) clientOption {
+	return func(c *apiClient) { c.http.Timeout = d }
+}
+
+// withRetry repeats requests that fail with a network error or a 5xx
+// status up to n times, backing off exponentially from one second.
+func withRetry(n int) clientOption {
+	return func(c *apiClient) { c.retries = n }
+}
+
+// withUserTimeout bounds the requests for each user, such as every page
+// of their repos, so a few slow users cannot drag out the run.
+func withUserTimeout(d time.Duration) clientOption {
+	return func(c *apiClient) { c.userTimeout = d }
+}
+
+// withHedge sends a second copy of a GET that has gone unanswered for d
+// and uses whichever answer comes first.
+func withHedge(d time.Duration) clientOption {
+	return func(c *apiClient) { c.hedgeAfter = d }
+}
+
+// withRPS limits requests to rps a second per host; 0 means no limit.
+func withRPS(rps float64) clientOption {
+	return func(c *apiClient) {
+		if rps > 0 {
+			c.throttle = newThrottle(rps)
+		}
+	}
+}
+
+// withMaxCalls caps the API calls the client makes; 0 means no cap.
+func withMaxCalls(n int) clientOption {
+	return func(c *apiClient) { c.maxCalls = int64(n) }
+}
+
+// newClient returns a client configured by opts. Without a token, or with
+// the placeholder "_", it runs unauthenticated: no Authorization header,
+// one request a minute, and at most unauthenticatedMaxCalls calls.
+func newClient(opts ...clientOption) *apiClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	c := &apiClient{
+		http:    &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		baseURL: defaultBaseURL,
+		header:  http.Header{"User-Agent": {defaultUserAgent}},
+		breaker: newBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.token == "" || c.token == "_" {
+		c.token = ""
+		c.pace = &pacer{interval: unauthenticatedInterval}
+		if c.maxCalls == 0 || c.maxCalls > unauthenticatedMaxCalls {
+			c.maxCalls = unauthenticatedMaxCalls
+		}
+	}
+	return c
+}
+
+// setProxy routes every request through the proxy at raw, overriding
+// HTTPS_PROXY and HTTP_PROXY. http, https, and socks5 proxies are supported.
+func (c *apiClient) setProxy(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy %q: scheme must be http, https, or socks5", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+	c.http.Transport.(*http.Transport).Proxy = http.ProxyURL(u)
+	return nil
+}
+
+// setTLS trusts the PEM certificates in caFile in addition to the system
+// roots, for TLS-intercepting proxies and GitHub Enterprise servers with a
+// private CA. insecure disables certificate verification altogether.
+func (c *apiClient) setTLS(caFile string, insecure bool) error {
+	cfg := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no PEM certificates found", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	c.http.Transport.(*http.Transport).TLSClientConfig = cfg
+	return nil
+}
+
+// authenticated reports whether requests carry a token.
+func (c *apiClient) authenticated() bool { return c.token != "" }
+
+// get issues an authenticated GET request. Every call counts against the
+// budget, whether or not it succeeds.
+func (c *apiClient) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, "GET", url, nil, "")
+}
+
+// do issues an authenticated request with an optional body.
+func (c *apiClient) do(ctx context.Context, method, url string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return c.send(req)
+}
+
+// send issues req with the client's headers and token, subject to the
+// breaker, the budget, and pacing, retrying it as configured. Every
+// attempt counts against the budget.
+func (c *apiClient) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+	for _, hook := range c.requestHooks {
+		if err := hook(req); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(req)
+		failed := err != nil || resp.StatusCode >= 500
+		if !failed || attempt >= c.retries || ctx.Err() != nil || errors.Is(err, errBudgetExhausted) {
+			return resp, err
+		}
+		// A request body can only be sent again if it can be recreated.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second << attempt):
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// attempt sends req once.
+func (c *apiClient) attempt(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	probe, err := c.breaker.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recorded := false
+	defer func() {
+		if probe && !recorded {
+			c.breaker.abandon()
+		}
+	}()
+	if n := c.calls.Add(1); c.maxCalls > 0 && n > c.maxCalls {
+		return nil, errBudgetExhausted
+	}
+	if c.pace != nil {
+		if err := c.pace.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.throttle.wait(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.hedgedDo(req)
+	for _, hook := range c.responseHooks {
+		hook(req, start, resp, err)
+	}
+	if ctx.Err() == nil {
+		c.breaker.record(err == nil && resp.StatusCode < 500)
+		recorded = true
+	}
+	return resp, err
+}
+
+// parseHeader parses a "Name: value" header option.
+func parseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (want Name: value)", s)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// sendJSON sends in as a JSON body and decodes a successful response into
+// out. Non-2xx responses are returned as errors.
+func (c *apiClient) sendJSON(ctx context.Context, method, url string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, method, url, bytes.NewReader(body), "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, data)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// getCached returns the body of a successful GET, serving it from the
+// response cache when a fresh entry exists. Cache hits cost no API calls.
+// Rate-limited requests are retried once the limit resets; any other
+// response but 200 OK is a *statusError.
+func (c *apiClient) getCached(ctx context.Context, url string) ([]byte, error) {
+	body, _, err := c.getChanged(ctx, url)
+	return body, err
+}
+
+// getChanged is getCached that also reports whether the body changed since
+// it was last fetched.
+func (c *apiClient) getChanged(ctx context.Context, url string) ([]byte, bool, error) {
+	page, changed, err := c.getOK(ctx, url)
+	if err != nil {
+		return nil, false, err
+	}
+	return page.body, changed, nil
+}
+
+// cachedResponse is a GET response read in full, possibly from the cache.
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// getPage GETs url through the response cache and reports whether the
+// body changed since it was last fetched. A stale cache entry is
+// revalidated with If-Modified-Since; a 304 reply, which GitHub does not
+// count against the rate limit, serves the cached body and renews the
+// entry.
+func (c *apiClient) getPage(ctx context.Context, url string) (cachedResponse, bool, error) {
+	return c.fetchPage(ctx, url, false)
+}
+
+// fetchPage is getPage that, with revalidate, asks the server about a
+// cached entry even while it is fresh.
+func (c *apiClient) fetchPage(ctx context.Context, url string, revalidate bool) (cachedResponse, bool, error) {
+	entry, fresh := c.cache.lookup(url)
+	if fresh && !revalidate {
+		return cachedResponse{http.StatusOK, entry.header(), entry.Body}, false, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return cachedResponse{}, false, err
+	}
+	if entry != nil {
+		req.Header.Set("If-Modified-Since", cmp.Or(entry.LastModified, entry.FetchedAt.Format(http.TimeFormat)))
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return cachedResponse{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		c.cache.store(url, entry.Body, entry.header())
+		return cachedResponse{http.StatusOK, entry.header(), entry.Body}, false, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cachedResponse{}, false, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		c.cache.store(url, body, resp.Header)
+	}
+	return cachedResponse{resp.StatusCode, resp.Header, body}, true, nil
+}
+
+// exhausted reports whether a request has been refused because the API call
+// budget was used up.
+func (c *apiClient) exhausted() bool {
+	return c.maxCalls > 0 && c.calls.Load() > c.maxCalls
+}
+
+// callCount returns the number of API calls made so far.
+func (c *apiClient) callCount() int64 {
+	if c.maxCalls > 0 && c.calls.Load() > c.maxCalls {
+		return c.maxCalls
+	}
+	return c.calls.Load()
+}
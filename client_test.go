@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetCachedStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"subscribers_count":7}`))
+		case "/missing":
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		case "/broken":
+			http.Error(w, `{"message":"Server Error"}`, http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	c.cache = &responseCache{dir: t.TempDir(), ttl: time.Hour}
+	ctx := context.Background()
+
+	body, err := c.getCached(ctx, srv.URL+"/ok")
+	if err != nil || string(body) != `{"subscribers_count":7}` {
+		t.Fatalf("getCached(/ok) = %q, %v", body, err)
+	}
+	if _, err := c.getCached(ctx, srv.URL+"/ok"); err != nil || calls.Load() != 1 {
+		t.Fatalf("cached GET made %d calls, err %v; want 1 call", calls.Load(), err)
+	}
+	for path, want := range map[string]int{"/missing": 404, "/broken": 502} {
+		body, err := c.getCached(ctx, srv.URL+path)
+		var status *statusError
+		if !errors.As(err, &status) || status.status != want || body != nil {
+			t.Errorf("getCached(%s) = %q, %v; want a %d statusError", path, body, err, want)
+		}
+	}
+	if _, _, err := c.fetchUserDetails(ctx, "missing"); err == nil {
+		t.Error("fetchUserDetails of a missing user succeeded")
+	}
+}
+
+// A stale cache entry is revalidated with If-Modified-Since, and a 304
+// serves the cached body.
+func TestGetPageRevalidates(t *testing.T) {
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+	var calls, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(`[1,2]`))
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	c.cache = &responseCache{dir: t.TempDir(), ttl: time.Nanosecond}
+	ctx := context.Background()
+
+	page, changed, err := c.getPage(ctx, srv.URL+"/list")
+	if err != nil || !changed || string(page.body) != "[1,2]" {
+		t.Fatalf("first getPage = %q, %v, %v", page.body, changed, err)
+	}
+	time.Sleep(time.Millisecond)
+	page, changed, err = c.getPage(ctx, srv.URL+"/list")
+	if err != nil || changed || page.status != http.StatusOK || string(page.body) != "[1,2]" {
+		t.Fatalf("revalidated getPage = %d %q, %v, %v", page.status, page.body, changed, err)
+	}
+	if calls.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("%d calls, %d not modified; want 2 and 1", calls.Load(), notModified.Load())
+	}
+
+	// A fresh entry is served without a request.
+	c.cache.ttl = time.Hour
+	if _, _, err := c.getPage(ctx, srv.URL+"/list"); err != nil || calls.Load() != 2 {
+		t.Errorf("fresh getPage made %d calls, err %v", calls.Load(), err)
+	}
+}
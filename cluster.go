@@ -0,0 +1,270 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// languageFamilies names the profile a cluster is labelled with when its
+// centroid leans toward these languages.
+var languageFamilies = map[string][]string{
+	"frontend": {"JavaScript", "TypeScript", "HTML", "CSS", "SCSS", "Less", "Vue", "Svelte", "Astro"},
+	"systems":  {"C", "C++", "Rust", "Go", "Assembly", "Zig", "Makefile", "Shell", "CMake"},
+	"data":     {"Python", "Jupyter Notebook", "R", "Julia", "MATLAB", "Scala", "Cuda"},
+	"mobile":   {"Swift", "Objective-C", "Kotlin", "Dart", "Objective-C++"},
+	"backend":  {"Java", "PHP", "Ruby", "C#", "Elixir", "Erlang", "Clojure", "Groovy"},
+}
+
+// userLanguageVectors builds one vector per user with repos that have a
+// language: the fraction of their repos in each of the vocab languages.
+// Languages outside the vocabulary count towards the user's total only.
+func userLanguageVectors(users []User, repos []Repo, vocab []string) ([]string, [][]float64) {
+	index := map[string]int{}
+	for i, l := range vocab {
+		index[l] = i
+	}
+	counts := map[string][]float64{}
+	totals := map[string]float64{}
+	for _, r := range repos {
+		if r.Language == "" {
+			continue
+		}
+		if counts[r.Login] == nil {
+			counts[r.Login] = make([]float64, len(vocab))
+		}
+		if i, ok := index[r.Language]; ok {
+			counts[r.Login][i]++
+		}
+		totals[r.Login]++
+	}
+	var logins []string
+	var vectors [][]float64
+	for _, u := range users {
+		v := counts[u.Login]
+		if v == nil {
+			continue
+		}
+		for i := range v {
+			v[i] /= totals[u.Login]
+		}
+		logins = append(logins, u.Login)
+		vectors = append(vectors, v)
+	}
+	return logins, vectors
+}
+
+// kmeans clusters the vectors into k groups with k-means++ seeding and
+// Lloyd iterations. The seed makes runs reproducible. It returns each
+// vector's cluster and the centroids.
+func kmeans(vectors [][]float64, k int, seed uint64) ([]int, [][]float64) {
+	k = min(k, len(vectors))
+	if k == 0 {
+		return nil, nil
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	dist := func(a, b []float64) float64 {
+		d := 0.0
+		for i := range a {
+			d += (a[i] - b[i]) * (a[i] - b[i])
+		}
+		return d
+	}
+
+	centroids := [][]float64{slices.Clone(vectors[rng.IntN(len(vectors))])}
+	nearest := make([]float64, len(vectors))
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range vectors {
+			nearest[i] = math.Inf(1)
+			for _, c := range centroids {
+				nearest[i] = math.Min(nearest[i], dist(v, c))
+			}
+			total += nearest[i]
+		}
+		pick := len(vectors) - 1
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, d := range nearest {
+				if target -= d; target <= 0 {
+					pick = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, slices.Clone(vectors[pick]))
+	}
+
+	assign := make([]int, len(vectors))
+	for iter := 0; iter < 100; iter++ {
+		changed := iter == 0
+		for i, v := range vectors {
+			best := 0
+			for c := range centroids {
+				if dist(v, centroids[c]) < dist(v, centroids[best]) {
+					best = c
+				}
+			}
+			if assign[i] != best {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sizes := make([]int, k)
+		for c := range centroids {
+			clear(centroids[c])
+		}
+		for i, v := range vectors {
+			sizes[assign[i]]++
+			for j, x := range v {
+				centroids[assign[i]][j] += x
+			}
+		}
+		for c := range centroids {
+			for j := range centroids[c] {
+				if sizes[c] > 0 {
+					centroids[c][j] /= float64(sizes[c])
+				}
+			}
+		}
+	}
+	return assign, centroids
+}
+
+// clusterLabel names a centroid after the language family holding most of
+// its weight, or "mixed" when no family has any, followed by its leading
+// languages.
+func clusterLabel(centroid []float64, vocab []string) string {
+	family, best := "mixed", 0.0
+	for name, langs := range languageFamilies {
+		w := 0.0
+		for i, l := range vocab {
+			if slices.Contains(langs, l) {
+				w += centroid[i]
+			}
+		}
+		if w > best || (w == best && w > 0 && name < family) {
+			family, best = name, w
+		}
+	}
+	return family + " (" + strings.Join(centroidLanguages(centroid, vocab, 3), ", ") + ")"
+}
+
+// centroidLanguages returns up to n languages with the most centroid
+// weight.
+func centroidLanguages(centroid []float64, vocab []string, n int) []string {
+	order := make([]int, len(vocab))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(centroid[b], centroid[a]) })
+	var out []string
+	for _, i := range order[:min(n, len(order))] {
+		if centroid[i] > 0 {
+			out = append(out, vocab[i])
+		}
+	}
+	return out
+}
+
+// languagePairs counts, for every pair of languages, the users who have
+// repos in both.
+func languagePairs(repos []Repo) []share {
+	byUser := map[string]map[string]bool{}
+	for _, r := range repos {
+		if r.Language == "" {
+			continue
+		}
+		if byUser[r.Login] == nil {
+			byUser[r.Login] = map[string]bool{}
+		}
+		byUser[r.Login][r.Language] = true
+	}
+	counts := map[string]int{}
+	for _, langs := range byUser {
+		names := make([]string, 0, len(langs))
+		for l := range langs {
+			names = append(names, l)
+		}
+		slices.Sort(names)
+		for i := range names {
+			for j := i + 1; j < len(names); j++ {
+				counts[names[i]+" + "+names[j]]++
+			}
+		}
+	}
+	pairs := make([]share, 0, len(counts))
+	for name, n := range counts {
+		pairs = append(pairs, share{Name: name, Count: n, Percent: 100 * float64(n) / float64(max(len(byUser), 1))})
+	}
+	slices.SortFunc(pairs, func(a, b share) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return pairs
+}
+
+// writeClusters clusters users by language and writes clusters.csv (one row
+// per user) and cluster_summary.csv (one row per cluster) into dir.
+func writeClusters(dir string, users []User, repos []Repo, k int) error {
+	languages := make([]string, 0, len(repos))
+	for _, r := range repos {
+		if r.Language != "" {
+			languages = append(languages, r.Language)
+		}
+	}
+	var vocab []string
+	for _, s := range topShares(shareOf(languages), 25) {
+		if s.Name != "Other" {
+			vocab = append(vocab, s.Name)
+		}
+	}
+	logins, vectors := userLanguageVectors(users, repos, vocab)
+	assign, centroids := kmeans(vectors, k, 1)
+
+	labels := make([]string, len(centroids))
+	sizes := make([]int, len(centroids))
+	for c := range centroids {
+		labels[c] = clusterLabel(centroids[c], vocab)
+	}
+	for _, c := range assign {
+		sizes[c]++
+	}
+
+	type member struct {
+		login   string
+		cluster int
+	}
+	members := make([]member, len(logins))
+	for i, l := range logins {
+		members[i] = member{l, assign[i]}
+	}
+	err := writeRecordsCSV(filepath.Join(dir, "clusters.csv"), []string{"login", "cluster", "label"}, members, func(m member) []string {
+		return []string{m.login, strconv.Itoa(m.cluster), labels[m.cluster]}
+	})
+	if err != nil {
+		return err
+	}
+	clusters := make([]int, len(centroids))
+	for c := range clusters {
+		clusters[c] = c
+	}
+	err = writeRecordsCSV(filepath.Join(dir, "cluster_summary.csv"), []string{"cluster", "label", "users", "top_languages"}, clusters, func(c int) []string {
+		return []string{strconv.Itoa(c), labels[c], strconv.Itoa(sizes[c]), strings.Join(centroidLanguages(centroids[c], vocab, 5), ";")}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Clustered %d users into %d language profiles\n", len(logins), len(centroids))
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestKmeans(t *testing.T) {
+	vectors := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {5, 5}, {5.1, 5}, {5, 5.1}}
+	for seed := range uint64(10) {
+		assign, centroids := kmeans(vectors, 2, seed)
+		if len(centroids) != 2 {
+			t.Fatalf("seed %d: %d centroids", seed, len(centroids))
+		}
+		if assign[0] != assign[1] || assign[0] != assign[2] || assign[3] != assign[4] || assign[3] != assign[5] || assign[0] == assign[3] {
+			t.Errorf("seed %d: assignments %v split the groups", seed, assign)
+		}
+		if c := centroids[assign[3]]; c[0] < 5 || c[0] > 5.1 {
+			t.Errorf("seed %d: centroid %v is not the far group's mean", seed, c)
+		}
+		again, _ := kmeans(vectors, 2, seed)
+		if !slices.Equal(assign, again) {
+			t.Errorf("seed %d: %v then %v", seed, assign, again)
+		}
+	}
+	if assign, centroids := kmeans(vectors[:2], 5, 1); len(centroids) != 2 || len(assign) != 2 {
+		t.Errorf("k above the vector count: %v, %v", assign, centroids)
+	}
+	if assign, centroids := kmeans(nil, 3, 1); assign != nil || centroids != nil {
+		t.Errorf("no vectors: %v, %v", assign, centroids)
+	}
+}
+
+func TestUserLanguageVectors(t *testing.T) {
+	users := []User{{Login: "a"}, {Login: "b"}, {Login: "none"}}
+	repos := []Repo{
+		{Login: "a", Language: "Go"}, {Login: "a", Language: "Go"}, {Login: "a", Language: "Rust"}, {Login: "a", Language: "COBOL"},
+		{Login: "b", Language: "Rust"}, {Login: "b"}, {Login: "none"},
+	}
+	logins, vectors := userLanguageVectors(users, repos, []string{"Go", "Rust"})
+	if !slices.Equal(logins, []string{"a", "b"}) || !reflect.DeepEqual(vectors, [][]float64{{0.5, 0.25}, {0, 1}}) {
+		t.Errorf("got %v %v", logins, vectors)
+	}
+}
+
+func TestClusterLabel(t *testing.T) {
+	vocab := []string{"Go", "Rust", "Python", "JavaScript"}
+	tests := []struct {
+		centroid []float64
+		want     string
+	}{
+		{[]float64{0.5, 0.3, 0.2, 0}, "systems (Go, Rust, Python)"},
+		{[]float64{0, 0, 0.1, 0.9}, "frontend (JavaScript, Python)"},
+		{[]float64{0, 0, 0, 0}, "mixed ()"},
+	}
+	for _, tt := range tests {
+		if got := clusterLabel(tt.centroid, vocab); got != tt.want {
+			t.Errorf("clusterLabel(%v) = %q, want %q", tt.centroid, got, tt.want)
+		}
+	}
+}
+
+func TestLanguagePairs(t *testing.T) {
+	repos := []Repo{
+		{Login: "a", Language: "Go"}, {Login: "a", Language: "Rust"}, {Login: "a", Language: "Go"},
+		{Login: "b", Language: "Rust"}, {Login: "b", Language: "Go"}, {Login: "b", Language: "C"},
+		{Login: "c", Language: "Go"}, {Login: "c"},
+	}
+	want := []share{
+		{Name: "Go + Rust", Count: 2, Percent: 200.0 / 3},
+		{Name: "C + Go", Count: 1, Percent: 100.0 / 3},
+		{Name: "C + Rust", Count: 1, Percent: 100.0 / 3},
+	}
+	if got := languagePairs(repos); !reflect.DeepEqual(got, want) {
+		t.Errorf("languagePairs = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// codeSearchInterval paces code search requests: GitHub allows 10 per
+// minute.
+const codeSearchInterval = 6 * time.Second
+
+// pacer spaces out calls to a rate-limited endpoint.
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next call is allowed or ctx is done.
+func (p *pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	at := p.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// codeSearchPacer is shared by every code search in the process.
+var codeSearchPacer = &pacer{interval: codeSearchInterval}
+
+// fetchUsersFromCodeSearch seeds the user set from a code search: the
+// owners of repos with matching files, deduplicated in order of first
+// match. Organisation-owned repos are skipped. GitHub returns at most
+// 1000 results per query. On error it returns the users found so far.
+func (c *apiClient) fetchUsersFromCodeSearch(ctx context.Context, query string) ([]User, error) {
+	var users []User
+	seen := map[string]bool{}
+	const perPage = 100
+	for page := 1; page <= 1000/perPage; page++ {
+		if err := codeSearchPacer.wait(ctx); err != nil {
+			return users, err
+		}
+		u := fmt.Sprintf("%s/search/code?q=%s&per_page=%d&page=%d", c.baseURL, url.QueryEscape(query), perPage, page)
+		resp, err := c.get(ctx, u)
+		if err != nil {
+			return users, err
+		}
+		var result struct {
+			Items []struct {
+				Repository struct {
+					Owner struct {
+						Login string `json:"login"`
+						Type  string `json:"type"`
+					} `json:"owner"`
+				} `json:"repository"`
+			} `json:"items"`
+			Message string `json:"message"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return users, err
+		}
+		if resp.StatusCode != 200 {
+			return users, fmt.Errorf("code search: %s: %s", resp.Status, result.Message)
+		}
+		for _, item := range result.Items {
+			owner := item.Repository.Owner
+			if owner.Type == "User" && !seen[owner.Login] {
+				seen[owner.Login] = true
+				users = append(users, User{Login: owner.Login})
+			}
+		}
+		if len(result.Items) < perPage {
+			break
+		}
+	}
+	return users, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cohort is a band of follower counts, [Min, Max).
+type cohort struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// parseCohorts parses follower bands such as "200-500,500-2000,2000+".
+func parseCohorts(spec string) ([]cohort, error) {
+	var out []cohort
+	for _, band := range parseKeywords(spec) {
+		c := cohort{Name: band, Max: math.MaxInt}
+		var err error
+		if lo, ok := strings.CutSuffix(band, "+"); ok {
+			c.Min, err = strconv.Atoi(lo)
+		} else if lo, hi, ok := strings.Cut(band, "-"); ok {
+			if c.Min, err = strconv.Atoi(lo); err == nil {
+				c.Max, err = strconv.Atoi(hi)
+			}
+		} else {
+			err = fmt.Errorf("want lo-hi or lo+")
+		}
+		if err != nil || c.Min < 0 || c.Max <= c.Min {
+			return nil, fmt.Errorf("invalid cohort %q (want bands like 200-500 or 2000+)", band)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// cohortOf returns the name of the first band holding followers, or "".
+func cohortOf(cohorts []cohort, followers int) string {
+	for _, c := range cohorts {
+		if followers >= c.Min && followers < c.Max {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// cohortColumns returns the users.csv column naming each user's cohort.
+func cohortColumns(cohorts []cohort) columnSet[User] {
+	return columnSet[User]{[]string{"cohort"}, func(u User) []string {
+		return []string{cohortOf(cohorts, u.Followers)}
+	}}
+}
+
+// cohortSummary compares the cohorts side by side.
+type cohortSummary struct {
+	Cohort          string
+	Users           int
+	MedianFollowers float64
+	MeanRepos       float64
+	HireablePercent float64
+	TotalStars      int
+	TopLanguage     string
+}
+
+var cohortSummaryColumns = []string{"cohort", "users", "median_followers", "mean_public_repos", "hireable_percent", "total_stars", "top_language"}
+
+func cohortSummaryRecord(c cohortSummary) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return []string{c.Cohort, strconv.Itoa(c.Users), f(c.MedianFollowers), f(c.MeanRepos), f(c.HireablePercent), strconv.Itoa(c.TotalStars), c.TopLanguage}
+}
+
+// splitCohorts partitions users and their repos by cohort, in band order.
+// Users outside every band are left out.
+func splitCohorts(cohorts []cohort, users []User, repos []Repo) ([][]User, [][]Repo) {
+	userSets := make([][]User, len(cohorts))
+	repoSets := make([][]Repo, len(cohorts))
+	index := map[string]int{}
+	for _, u := range users {
+		name := cohortOf(cohorts, u.Followers)
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(cohorts, func(c cohort) bool { return c.Name == name })
+		userSets[i] = append(userSets[i], u)
+		index[u.Login] = i
+	}
+	for _, r := range repos {
+		if i, ok := index[r.Login]; ok {
+			repoSets[i] = append(repoSets[i], r)
+		}
+	}
+	return userSets, repoSets
+}
+
+func summarizeCohort(name string, users []User, repos []Repo) cohortSummary {
+	c := cohortSummary{Cohort: name, Users: len(users)}
+	followers := make([]int, len(users))
+	repoCount, hireable := 0, 0
+	for i, u := range users {
+		followers[i] = u.Followers
+		repoCount += u.PublicRepos
+		if u.Hireable {
+			hireable++
+		}
+	}
+	if len(users) > 0 {
+		slices.Sort(followers)
+		c.MedianFollowers = percentile(followers, 50)
+		c.MeanRepos = float64(repoCount) / float64(len(users))
+		c.HireablePercent = 100 * float64(hireable) / float64(len(users))
+	}
+	for _, r := range dedupeByRoot(repos) {
+		c.TotalStars += r.StargazersCount
+	}
+	for _, l := range summarize(nil, repos, 0).Languages {
+		if l.Name != "(none)" {
+			c.TopLanguage = l.Name
+			break
+		}
+	}
+	return c
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// commitEmailRepos is how many of a user's most recently pushed repos are
+// inspected for a commit email.
+const commitEmailRepos = 3
+
+// isNoreplyEmail reports whether an address is one of GitHub's private
+// placeholders or a machine-local git default (user@host.local), neither
+// of which may be recorded as a contact address.
+func isNoreplyEmail(email string) bool {
+	email = strings.ToLower(email)
+	return email == "" || strings.HasSuffix(email, "@users.noreply.github.com") ||
+		strings.HasPrefix(email, "noreply@") || strings.HasSuffix(email, ".local")
+}
+
+// loadOptOuts reads logins, one per line, that must not be harvested.
+// Blank lines and # comments are ignored. An empty path means no opt-outs.
+func loadOptOuts(path string) (map[string]bool, error) {
+	out := map[string]bool{}
+	if path == "" {
+		return out, nil
+	}
+	list, err := readLoginList(path)
+	for _, login := range list {
+		out[strings.ToLower(login)] = true
+	}
+	return out, err
+}
+
+// readLoginList reads logins, one per line, skipping blank lines and #
+// comments. A path of "-" reads standard input.
+func readLoginList(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	var out []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// usersFromLogins returns a user for each distinct login, ignoring case,
+// for fetching their details.
+func usersFromLogins(list []string) []User {
+	seen := map[string]bool{}
+	var users []User
+	for _, login := range list {
+		if key := strings.ToLower(login); !seen[key] {
+			seen[key] = true
+			users = append(users, User{Login: login})
+		}
+	}
+	return users
+}
+
+// commitEmail returns the public address a user commits with in their own
+// recently pushed repos, or "" if none is found.
+func (c *apiClient) commitEmail(ctx context.Context, login string) (string, error) {
+	body, err := c.getCached(ctx, fmt.Sprintf("%s/users/%s/repos?type=owner&sort=pushed&per_page=10", c.baseURL, login))
+	if err != nil {
+		return "", err
+	}
+	var repos []Repo
+	if err := json.Unmarshal(body, &repos); err != nil {
+		return "", err
+	}
+	checked := 0
+	for _, r := range repos {
+		if r.Fork || checked == commitEmailRepos {
+			continue
+		}
+		checked++
+		body, err := c.getCached(ctx, fmt.Sprintf("%s/repos/%s/commits?author=%s&per_page=5", c.baseURL, r.FullName, login))
+		var status *statusError
+		if errors.As(err, &status) && status.status == http.StatusConflict {
+			continue // empty repos have no commits
+		}
+		if err != nil {
+			return "", err
+		}
+		var commits []struct {
+			Commit struct {
+				Author struct {
+					Email string `json:"email"`
+				} `json:"author"`
+			} `json:"commit"`
+		}
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return "", err
+		}
+		for _, cm := range commits {
+			if !isNoreplyEmail(cm.Commit.Author.Email) {
+				return cm.Commit.Author.Email, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// harvestCommitEmails fills in the email of users whose profile has none
+// from their commits, skipping opted-out logins. It returns the source of
+// every user's email: "profile", "commit", or "".
+func (c *apiClient) harvestCommitEmails(ctx context.Context, users []User, optOut map[string]bool) map[string]string {
+	source := map[string]string{}
+	for _, u := range users {
+		if u.Email != "" {
+			source[u.Login] = "profile"
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchConcurrency)
+	for i := range users {
+		u := &users[i]
+		if u.Email != "" || optOut[strings.ToLower(u.Login)] {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			email, err := c.commitEmail(ctx, u.Login)
+			if err != nil || email == "" {
+				return
+			}
+			u.Email = email
+			mu.Lock()
+			source[u.Login] = "commit"
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return source
+}
+
+func emailSourceColumns(source map[string]string) columnSet[User] {
+	return columnSet[User]{
+		columns: []string{"email_source"},
+		record:  func(u User) []string { return []string{source[u.Login]} },
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// commands lists the subcommands for completion. A run without one is a
+// scrape.
+var commands = []struct{ name, summary string }{
+	{"analyze", "compute metric CSVs and charts from a scrape"},
+	{"bench", "benchmark a scrape against a fake GitHub"},
+	{"completion", "print a bash, zsh, or fish completion script"},
+	{"fake", "generate synthetic users and repos without the API"},
+	{"init", "interview for a scrape and write a runs config"},
+	{"profiles", "write a profile page per user"},
+	{"prune", "remove old snapshots from a history store"},
+	{"query", "run a read-only SQL query over the collected data"},
+	{"report", "write a Markdown or HTML report"},
+	{"runs", "run the scrapes of a runs config"},
+	{"score", "score and shortlist users"},
+	{"serve", "serve the dataset over HTTP"},
+	{"track", "report profile changes of tracked users"},
+	{"trending", "list the biggest movers between snapshots"},
+	{"watch", "poll the search for new matching users"},
+}
+
+// completionValues are the values offered for flags that take one of a
+// known set, keyed by command and flag.
+var completionValues = map[string]map[string][]string{
+	"scrape": {
+		"compress":     {"gzip", "zip"},
+		"partition-by": {"language", "company", "location"},
+		"publish":      {"gist", "release:"},
+	},
+	"analyze": {"chart-format": {"png", "svg"}},
+	"report":  {"format": {"md", "html"}},
+	"query":   {"format": {"box", "csv", "json", "markdown"}},
+}
+
+// completionFlag is a flag as completion scripts offer it.
+type completionFlag struct {
+	name, usage string
+	takesValue  bool
+	values      []string
+}
+
+// parseFlagDefaults reads flag.PrintDefaults output, where each flag is a
+// line such as "  -name type" followed by indented usage lines. Boolean
+// flags have no type.
+func parseFlagDefaults(r io.Reader) []completionFlag {
+	var flags []completionFlag
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "  -"):
+			name, rest, _ := strings.Cut(strings.TrimPrefix(line, "  -"), " ")
+			// One-letter flags keep their usage on the same line.
+			rest, usage, _ := strings.Cut(rest, "\t")
+			flags = append(flags, completionFlag{name: name, usage: usage, takesValue: strings.TrimSpace(rest) != ""})
+		case strings.HasPrefix(line, "    \t") && len(flags) > 0:
+			f := &flags[len(flags)-1]
+			f.usage = strings.TrimSpace(f.usage + " " + strings.TrimSpace(line))
+		}
+	}
+	for i := range flags {
+		// Keep descriptions short: no default, and only the first clause.
+		usage, _, _ := strings.Cut(flags[i].usage, " (default ")
+		usage, _, _ = strings.Cut(usage, "; ")
+		flags[i].usage = usage
+	}
+	return flags
+}
+
+// commandFlags asks the tds binary at exe for the flags of cmd ("scrape"
+// for a scrape) by running it with -h.
+func commandFlags(exe, cmd string) ([]completionFlag, error) {
+	args := []string{"-h"}
+	if cmd != "scrape" {
+		args = []string{cmd, "-h"}
+	}
+	var out bytes.Buffer
+	c := exec.Command(exe, args...)
+	c.Stdout, c.Stderr = &out, &out
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("listing the flags of %s: %w", cmd, err)
+	}
+	flags := parseFlagDefaults(&out)
+	for i := range flags {
+		flags[i].values = completionValues[cmd][flags[i].name]
+	}
+	return flags, nil
+}
+
+// shellQuote quotes s for a single-quoted shell string.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func flagNames(flags []completionFlag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "--" + f.name
+	}
+	return strings.Join(names, " ")
+}
+
+func bashCompletion(w io.Writer, flags map[string][]completionFlag) {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	fmt.Fprintln(w, "# bash completion for tds; load it with: source <(tds completion bash)")
+	fmt.Fprintln(w, "_tds() {")
+	fmt.Fprintln(w, `	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} cmd=scrape`)
+	fmt.Fprintf(w, "\tcase ${COMP_WORDS[1]} in\n\t%s) cmd=${COMP_WORDS[1]} ;;\n\tesac\n", strings.Join(names, "|"))
+	fmt.Fprintln(w, `	case "$cmd $prev" in`)
+	for _, cmd := range slices.Sorted(maps.Keys(flags)) {
+		for _, f := range flags[cmd] {
+			if len(f.values) > 0 {
+				fmt.Fprintf(w, "\t\"%s --%s\" | \"%s -%s\") COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n",
+					cmd, f.name, cmd, f.name, shellQuote(strings.Join(f.values, " ")))
+			}
+		}
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, `	if [[ $cur == -* ]]; then`)
+	fmt.Fprintln(w, "\t\tlocal flags")
+	fmt.Fprintln(w, "\t\tcase $cmd in")
+	for _, cmd := range slices.Sorted(maps.Keys(flags)) {
+		fmt.Fprintf(w, "\t\t%s) flags=%s ;;\n", cmd, shellQuote(flagNames(flags[cmd])))
+	}
+	fmt.Fprintln(w, "\t\tesac")
+	fmt.Fprintln(w, `		COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Fprintln(w, "\telif [[ $COMP_CWORD == 1 ]]; then")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(names, " ")))
+	fmt.Fprintln(w, "\telse")
+	fmt.Fprintln(w, `		COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _tds tds")
+}
+
+// zshSpec returns the _arguments spec of f.
+func zshSpec(f completionFlag) string {
+	usage := strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(f.usage)
+	if !f.takesValue {
+		return shellQuote(fmt.Sprintf("--%s[%s]", f.name, usage))
+	}
+	action := "_files"
+	if len(f.values) > 0 {
+		action = "(" + strings.Join(f.values, " ") + ")"
+	}
+	return shellQuote(fmt.Sprintf("--%s=[%s]:%s:%s", f.name, usage, f.name, action))
+}
+
+func zshCompletion(w io.Writer, flags map[string][]completionFlag) {
+	fmt.Fprintln(w, "#compdef tds")
+	fmt.Fprintln(w, "# zsh completion for tds; save it as _tds in a directory on $fpath")
+	for _, cmd := range slices.Sorted(maps.Keys(flags)) {
+		fmt.Fprintf(w, "_tds_%s() {\n\t_arguments", cmd)
+		for _, f := range flags[cmd] {
+			fmt.Fprintf(w, " \\\n\t\t%s", zshSpec(f))
+		}
+		fmt.Fprintln(w, "\n}")
+	}
+	fmt.Fprintln(w, "_tds() {")
+	fmt.Fprintln(w, "\tlocal -a commands=(")
+	for _, c := range commands {
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(c.name+":"+c.summary))
+	}
+	fmt.Fprintln(w, "\t)")
+	fmt.Fprintln(w, "\tif (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then")
+	fmt.Fprintln(w, "\t\t_describe command commands")
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "\tif (( ${+functions[_tds_$words[2]]} )); then")
+	fmt.Fprintln(w, "\t\tlocal cmd=$words[2]")
+	fmt.Fprintln(w, "\t\tshift words")
+	fmt.Fprintln(w, "\t\t(( CURRENT-- ))")
+	fmt.Fprintln(w, "\t\t_tds_$cmd")
+	fmt.Fprintln(w, "\telse")
+	fmt.Fprintln(w, "\t\t_tds_scrape")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, `_tds "$@"`)
+}
+
+func fishCompletion(w io.Writer, flags map[string][]completionFlag) {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	fmt.Fprintln(w, "# fish completion for tds; save it as ~/.config/fish/completions/tds.fish")
+	fmt.Fprintln(w, "complete -c tds -f")
+	for _, c := range commands {
+		fmt.Fprintf(w, "complete -c tds -n __fish_use_subcommand -a %s -d %s\n", c.name, shellQuote(c.summary))
+	}
+	for _, cmd := range slices.Sorted(maps.Keys(flags)) {
+		cond := "__fish_seen_subcommand_from " + cmd
+		if cmd == "scrape" {
+			cond = "not __fish_seen_subcommand_from " + strings.Join(names, " ")
+		}
+		for _, f := range flags[cmd] {
+			fmt.Fprintf(w, "complete -c tds -n %s -l %s", shellQuote(cond), f.name)
+			switch {
+			case len(f.values) > 0:
+				fmt.Fprintf(w, " -r -a %s", shellQuote(strings.Join(f.values, " ")))
+			case f.takesValue:
+				fmt.Fprint(w, " -r -F")
+			}
+			fmt.Fprintf(w, " -d %s\n", shellQuote(f.usage))
+		}
+	}
+}
+
+func runCompletion(args []string) int {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: tds completion bash|zsh|fish")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	generators := map[string]func(io.Writer, map[string][]completionFlag){
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+	}
+	generate, ok := generators[fs.Arg(0)]
+	if fs.NArg() != 1 || !ok {
+		fs.Usage()
+		return 2
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	flags := map[string][]completionFlag{}
+	names := []string{"scrape"}
+	for _, c := range commands {
+		if c.name != "completion" {
+			names = append(names, c.name)
+		}
+	}
+	for _, cmd := range names {
+		if flags[cmd], err = commandFlags(exe, cmd); err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+	}
+	generate(os.Stdout, flags)
+	return 0
+}
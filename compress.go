@@ -0,0 +1,94 @@
+package main
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// zipBundleName is the archive written by --compress zip.
+const zipBundleName = "tds-outputs.zip"
+
+// compressArtifacts replaces the output files with compressed versions and
+// returns the new file list: one .gz per file for "gzip", or a single zip
+// bundle for "zip".
+func compressArtifacts(mode string, files []string) ([]string, error) {
+	switch mode {
+	case "", "none":
+		return files, nil
+	case "gzip":
+		var out []string
+		for _, f := range files {
+			if err := gzipFile(f, f+".gz"); err != nil {
+				return nil, err
+			}
+			os.Remove(f)
+			out = append(out, f+".gz")
+		}
+		return out, nil
+	case "zip":
+		if err := zipFiles(zipBundleName, files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			os.Remove(f)
+		}
+		return []string{zipBundleName}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q (want gzip or zip)", mode)
+}
+
+func gzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createAtomic(dest)
+	if err != nil {
+		return err
+	}
+	defer out.discard()
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.commit()
+}
+
+func zipFiles(dest string, files []string) error {
+	out, err := createAtomic(dest)
+	if err != nil {
+		return err
+	}
+	defer out.discard()
+
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		in, err := os.Open(f)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(artifactName(f))
+		if err == nil {
+			_, err = io.Copy(w, in)
+		}
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.commit()
+}
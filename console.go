@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleModes are the values of --console.
+var consoleModes = []string{"pretty", "plain", "json"}
+
+// consoleControl starts a line the console reads as an instruction rather
+// than a message: "\x00phase <name>" on entering a phase, or
+// "\x00json <event>" for an event to write as it is.
+const consoleControl = "\x00"
+
+// console formats a scrape's messages. The run prints them to stdout as it
+// goes, so the console replaces stdout with a pipe and rewrites each line:
+// in pretty mode with phase banners and, on a terminal, errors in red and
+// warnings such as rate-limit waits in yellow; in json mode as one JSON
+// object per line. It also counts failed API requests for a digest at the
+// end. A nil console, as in plain mode or in pretty mode when stdout is not
+// a terminal, leaves stdout alone.
+type console struct {
+	json  bool
+	color bool
+	out   *os.File
+	pipe  *os.File
+	done  chan struct{}
+
+	mu       sync.Mutex
+	failures map[string]int
+
+	closeOnce sync.Once
+}
+
+// newConsole starts formatting stdout in mode, one of consoleModes. Pretty
+// mode is for people watching a run, so redirected and scheduled runs,
+// whose stdout is not a terminal, get plain output.
+func newConsole(mode string) (*console, error) {
+	info, err := os.Stdout.Stat()
+	terminal := err == nil && info.Mode()&os.ModeCharDevice != 0
+	if mode == "plain" || mode == "pretty" && !terminal {
+		return nil, nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &console{
+		json:     mode == "json",
+		color:    terminal && os.Getenv("NO_COLOR") == "",
+		out:      os.Stdout,
+		pipe:     w,
+		done:     make(chan struct{}),
+		failures: map[string]int{},
+	}
+	os.Stdout = w
+	go c.format(r)
+	return c, nil
+}
+
+// watch counts the failed requests of client for the error digest.
+func (c *console) watch(client *apiClient) {
+	if c == nil {
+		return
+	}
+	client.addResponseHook(c.observe)
+}
+
+// failureCause names why a request failed, or returns "" if it did not.
+// Missing users and repos are not failures, nor are cancelled hedges.
+func failureCause(resp *http.Response, err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case err != nil:
+		return "network error"
+	case resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound:
+		return resp.Status
+	}
+	return ""
+}
+
+func (c *console) observe(req *http.Request, start time.Time, resp *http.Response, err error) {
+	cause := failureCause(resp, err)
+	if cause == "" {
+		return
+	}
+	c.mu.Lock()
+	c.failures[cause]++
+	c.mu.Unlock()
+}
+
+// phase announces that the run entered the phase name.
+func (c *console) phase(name string) {
+	if c == nil {
+		return
+	}
+	fmt.Fprintln(c.pipe, consoleControl+"phase "+name)
+}
+
+// close prints the error digest and restores stdout once the messages
+// printed so far are written. It may be called more than once, and on a
+// nil console.
+func (c *console) close() {
+	if c == nil {
+		return
+	}
+	c.closeOnce.Do(func() {
+		c.digest()
+		os.Stdout = c.out
+		c.pipe.Close()
+		<-c.done
+	})
+}
+
+// digest prints how many API requests failed, by cause.
+func (c *console) digest() {
+	c.mu.Lock()
+	failures := maps.Clone(c.failures)
+	c.mu.Unlock()
+	total := 0
+	for _, n := range failures {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+	if c.json {
+		c.emit(map[string]any{"event": "error_digest", "failed_requests": total, "by_cause": failures})
+		return
+	}
+	causes := slices.SortedFunc(maps.Keys(failures), func(a, b string) int { return failures[b] - failures[a] })
+	parts := make([]string, len(causes))
+	for i, cause := range causes {
+		parts[i] = fmt.Sprintf("%d× %s", failures[cause], cause)
+	}
+	fmt.Fprintf(c.pipe, "Warning: %d API requests failed: %s\n", total, strings.Join(parts, ", "))
+}
+
+// emit writes a json mode event after the lines printed before it.
+func (c *console) emit(event map[string]any) {
+	event["time"] = time.Now().UTC().Format(time.RFC3339)
+	data, _ := json.Marshal(event)
+	fmt.Fprintln(c.pipe, consoleControl+"json "+string(data))
+}
+
+// messageLevel classifies a message by how the run words it.
+func messageLevel(line string) string {
+	switch {
+	case strings.HasPrefix(line, "Error"):
+		return "error"
+	case strings.HasPrefix(line, "Warning"),
+		strings.HasPrefix(line, "Rate limited"),
+		strings.HasPrefix(line, "Circuit open"),
+		strings.HasPrefix(line, "Circuit still open"),
+		strings.HasPrefix(line, "Phase "),
+		strings.HasPrefix(line, "Stopped early"),
+		strings.HasPrefix(line, "Data validation found"):
+		return "warn"
+	}
+	return "info"
+}
+
+var levelColors = map[string]string{"error": "\033[31m", "warn": "\033[33m"}
+
+// format rewrites the lines read from r onto the real stdout until r is
+// closed.
+func (c *console) format(r io.ReadCloser) {
+	defer close(c.done)
+	defer r.Close()
+	enc := json.NewEncoder(c.out)
+	phase := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, consoleControl+"json "); ok {
+			fmt.Fprintln(c.out, event)
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, consoleControl+"phase "); ok {
+			if name == phase {
+				continue
+			}
+			phase = name
+			switch {
+			case c.json:
+				enc.Encode(map[string]any{"time": time.Now().UTC().Format(time.RFC3339), "event": "phase", "phase": phase})
+			case c.color:
+				fmt.Fprintf(c.out, "\033[1;36m==> %s\033[0m\n", phase)
+			default:
+				fmt.Fprintf(c.out, "==> %s\n", phase)
+			}
+			continue
+		}
+		level := messageLevel(line)
+		switch {
+		case c.json:
+			enc.Encode(map[string]any{"time": time.Now().UTC().Format(time.RFC3339), "level": level, "phase": phase, "msg": line})
+		case c.color && levelColors[level] != "":
+			fmt.Fprintf(c.out, "%s%s\033[0m\n", levelColors[level], line)
+		default:
+			fmt.Fprintln(c.out, line)
+		}
+	}
+}
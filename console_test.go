@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestMessageLevel(t *testing.T) {
+	tests := map[string]string{
+		"Error fetching users: boom":          "error",
+		"Warning: 3 API requests failed":      "warn",
+		"Rate limited; retrying in 30s":       "warn",
+		"Stopped early (deadline)":            "warn",
+		"Saved 120 users to users.csv":        "info",
+		"Found 10 users across 18 searches":   "info",
+		"Circuit open after 5 failures; ...":  "warn",
+		"Data validation found 2 problems":    "warn",
+		"Phase search reached its API budget": "warn",
+	}
+	for line, want := range tests {
+		if got := messageLevel(line); got != want {
+			t.Errorf("messageLevel(%q) = %s, want %s", line, got, want)
+		}
+	}
+}
+
+// Pretty output is for terminals; a redirected run keeps its stdout.
+func TestConsolePrettyNotTerminal(t *testing.T) {
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	defer w.Close()
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	c, err := newConsole("pretty")
+	if c != nil || err != nil {
+		t.Fatalf("newConsole(pretty) on a pipe = %v, %v; want nil", c, err)
+	}
+	if os.Stdout != w {
+		t.Fatal("stdout was replaced")
+	}
+}
+
+func TestConsoleJSON(t *testing.T) {
+	r, w, _ := os.Pipe()
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	c, err := newConsole("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.phase("search")
+	fmt.Println("Error fetching users: boom")
+	c.failures["timeout"] = 2
+	c.close()
+	w.Close()
+
+	var events []map[string]any
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 3 {
+		t.Fatalf("events = %v, want phase, message, and digest", events)
+	}
+	if events[0]["event"] != "phase" || events[0]["phase"] != "search" {
+		t.Errorf("first event = %v", events[0])
+	}
+	if events[1]["level"] != "error" || events[1]["phase"] != "search" {
+		t.Errorf("message event = %v", events[1])
+	}
+	if events[2]["event"] != "error_digest" || events[2]["failed_requests"] != 2.0 {
+		t.Errorf("digest event = %v", events[2])
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// countryLocation lists the location terms a country's users are found
+// under: names of the country itself and its major tech cities. GitHub
+// returns at most 1000 users per search, so searching the cities
+// separately finds users a single country search would cut off, as well as
+// those who give only their city.
+type countryLocation struct {
+	names  []string
+	cities []string
+}
+
+// countryLocations are the countries --country knows, keyed by lower-case
+// name.
+var countryLocations = map[string]countryLocation{
+	"china": {
+		names:  []string{"China", "People's Republic of China", "中国"},
+		cities: []string{"Shanghai", "Beijing", "Shenzhen", "Hangzhou", "Guangzhou", "Chengdu", "Nanjing", "Wuhan", "Xi'an", "Suzhou", "Tianjin", "Chongqing", "Xiamen", "Changsha", "Hefei"},
+	},
+	"india": {
+		names:  []string{"India"},
+		cities: []string{"Bangalore", "Bengaluru", "Hyderabad", "Pune", "Chennai", "Mumbai", "Delhi", "New Delhi", "Noida", "Gurgaon", "Kolkata", "Ahmedabad"},
+	},
+	"japan": {
+		names:  []string{"Japan", "日本"},
+		cities: []string{"Tokyo", "Osaka", "Kyoto", "Yokohama", "Fukuoka", "Nagoya", "Sapporo"},
+	},
+	"germany": {
+		names:  []string{"Germany", "Deutschland"},
+		cities: []string{"Berlin", "Munich", "München", "Hamburg", "Frankfurt", "Cologne", "Stuttgart", "Karlsruhe"},
+	},
+	"united kingdom": {
+		names:  []string{"United Kingdom", "UK", "England", "Scotland"},
+		cities: []string{"London", "Manchester", "Cambridge", "Oxford", "Edinburgh", "Bristol"},
+	},
+	"united states": {
+		names:  []string{"United States", "USA"},
+		cities: []string{"San Francisco", "New York", "Seattle", "Los Angeles", "Boston", "Austin", "Chicago", "San Jose", "Mountain View", "Bay Area"},
+	},
+}
+
+// countryQueries returns the location qualifiers searched for country, in
+// search order: the cities, then the country's names.
+func countryQueries(country string) ([]string, error) {
+	loc, ok := countryLocations[strings.ToLower(strings.TrimSpace(country))]
+	if !ok {
+		known := make([]string, 0, len(countryLocations))
+		for name := range countryLocations {
+			known = append(known, name)
+		}
+		slices.Sort(known)
+		return nil, fmt.Errorf("unknown country %q (known: %s)", country, strings.Join(known, ", "))
+	}
+	var queries []string
+	for _, term := range slices.Concat(loc.cities, loc.names) {
+		if strings.Contains(term, " ") {
+			term = `"` + term + `"`
+		}
+		queries = append(queries, "location:"+term)
+	}
+	return queries, nil
+}
+
+// searchCountry runs the user search for each of a country's locations,
+// qualified by followers, e.g. "followers:>200", and merges the matches in
+// order of first match. It also returns the queries each user matched,
+// keyed by login. With a seen store, the store dedupes the matches and
+// drops those earlier runs fetched. On error it returns the users found
+// so far.
+func (c *apiClient) searchCountry(ctx context.Context, country, followers string, seen *seenStore) ([]User, map[string][]string, error) {
+	queries, err := countryQueries(country)
+	if err != nil {
+		return nil, nil, err
+	}
+	var users []User
+	matched := map[string][]string{}
+	known := 0
+	for _, q := range queries {
+		found, err := c.searchUsers(ctx, url.QueryEscape(strings.TrimSpace(q+" "+followers)))
+		for _, u := range found {
+			key := strings.ToLower(u.Login)
+			first := len(matched[key]) == 0
+			if seen != nil {
+				claimed, claimErr := seen.claim(u.Login)
+				if claimErr != nil {
+					return users, matched, claimErr
+				}
+				if first && !claimed {
+					known++
+				}
+				first = claimed
+			}
+			if first {
+				users = append(users, u)
+			}
+			matched[key] = append(matched[key], q)
+		}
+		if err != nil {
+			return users, matched, err
+		}
+	}
+	if seen != nil {
+		fmt.Printf("Skipped %d users fetched by earlier runs (--seen-store)\n", known)
+	}
+	fmt.Printf("Found %d users across %d searches for %s\n", len(users), len(queries), country)
+	return users, matched, nil
+}
+
+// matchedQueryColumns returns the users.csv column listing the searches
+// each user matched.
+func matchedQueryColumns(matched map[string][]string) columnSet[User] {
+	return columnSet[User]{[]string{"matched_queries"}, func(u User) []string {
+		return []string{strings.Join(matched[strings.ToLower(u.Login)], ";")}
+	}}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// repoTally is the number of repos and the stars among them fetched for a
+// user.
+type repoTally struct {
+	repos, stars int
+}
+
+// tallyRepos adds repos to the tallies of their owners.
+func tallyRepos(tallies map[string]repoTally, repos []Repo) {
+	for _, r := range repos {
+		key := strings.ToLower(r.Login)
+		t := tallies[key]
+		t.repos++
+		t.stars += r.StargazersCount
+		tallies[key] = t
+	}
+}
+
+// ratio formats a/b to two decimals, or returns "" when b is 0.
+func ratio(a, b float64) string {
+	if b == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(a/b, 'f', 2, 64)
+}
+
+// derivedColumns returns the --derived-columns of users.csv: followers per
+// account followed, public repos per year since the account was created
+// (accounts under a month old count as a month old), and stars per repo
+// among the repos fetched, which stays empty until they are.
+func derivedColumns(now time.Time, tallies map[string]repoTally) columnSet[User] {
+	columns := []string{"followers_following_ratio", "repos_per_year", "stars_per_repo"}
+	return columnSet[User]{columns, func(u User) []string {
+		perYear := ""
+		if created, err := time.Parse(time.RFC3339, u.CreatedAt); err == nil {
+			years := max(now.Sub(created).Hours()/24/365.25, 1.0/12)
+			perYear = ratio(float64(u.PublicRepos), years)
+		}
+		t := tallies[strings.ToLower(u.Login)]
+		return []string{
+			ratio(float64(u.Followers), float64(u.Following)),
+			perYear,
+			ratio(float64(t.stars), float64(t.repos)),
+		}
+	}}
+}
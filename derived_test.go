@@ -0,0 +1,33 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDerivedColumns(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	twoYears := now.Add(-2 * 8766 * time.Hour).Format(time.RFC3339)
+	tallies := map[string]repoTally{}
+	tallyRepos(tallies, []Repo{{Login: "Alice", StargazersCount: 5}, {Login: "alice", StargazersCount: 10}})
+	columns := derivedColumns(now, tallies)
+
+	tests := []struct {
+		user User
+		want []string
+	}{
+		{User{Login: "alice", Followers: 10, Following: 4, PublicRepos: 20, CreatedAt: twoYears}, []string{"2.50", "10.00", "7.50"}},
+		// Accounts under a month old count as a month old.
+		{User{Login: "new", PublicRepos: 3, CreatedAt: now.Add(-24 * time.Hour).Format(time.RFC3339)}, []string{"", "36.00", ""}},
+		{User{Login: "bob", Followers: 3, CreatedAt: "not a time"}, []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		if got := columns.record(tt.user); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.user.Login, got, tt.want)
+		}
+	}
+	if len(columns.columns) != len(tests[0].want) {
+		t.Errorf("columns %q do not match the record", columns.columns)
+	}
+}
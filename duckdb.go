@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// duckDBExporter builds a DuckDB database with users and repositories tables
+// plus aggregate views. It writes the data as CSV next to an init script and,
+// when the duckdb CLI is on PATH, runs the script to produce the database
+// file; otherwise the script is left for the user to run. Running it against
+// an existing database migrates its schema and replaces the rows, keeping
+// anything else stored there.
+type duckDBExporter struct {
+	path string
+}
+
+func (e *duckDBExporter) name() string { return "DuckDB" }
+
+// duckDBMigrations bring a database's schema up to date. Migration i is
+// recorded as version i+1 in schema_version. Each is written to be safe to
+// run again, since the script cannot branch on the recorded version and
+// databases built before versioning have no record. Append new migrations;
+// never edit released ones.
+var duckDBMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+    login VARCHAR, name VARCHAR, company VARCHAR, location VARCHAR, email VARCHAR,
+    hireable BOOLEAN, bio VARCHAR, public_repos BIGINT, followers BIGINT,
+    following BIGINT, created_at TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS repositories (
+    login VARCHAR, full_name VARCHAR, created_at TIMESTAMP, stargazers_count BIGINT,
+    watchers_count BIGINT, language VARCHAR, has_projects BOOLEAN, has_wiki BOOLEAN,
+    license_name VARCHAR
+);`,
+	`ALTER TABLE repositories ADD COLUMN IF NOT EXISTS fork BOOLEAN;
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS root_repo VARCHAR;`,
+	`ALTER TABLE repositories ADD COLUMN IF NOT EXISTS description VARCHAR;`,
+	`ALTER TABLE repositories ADD COLUMN IF NOT EXISTS homepage VARCHAR;`,
+	`ALTER TABLE repositories ADD COLUMN IF NOT EXISTS subscribers_count BIGINT;`,
+	`ALTER TABLE repositories ADD COLUMN IF NOT EXISTS has_issues BOOLEAN;
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS has_discussions BOOLEAN;
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS has_pages BOOLEAN;`,
+}
+
+// duckDBMigrate returns SQL that applies the migrations and records them.
+func duckDBMigrate() string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, applied_at TIMESTAMP);\n")
+	for i, m := range duckDBMigrations {
+		fmt.Fprintf(&b, "%s\nINSERT OR IGNORE INTO schema_version VALUES (%d, now());\n", m, i+1)
+	}
+	return b.String()
+}
+
+// duckDBViews are created after the tables are loaded.
+const duckDBViews = `
+CREATE OR REPLACE VIEW user_stats AS
+SELECT
+    u.login,
+    u.followers,
+    count(r.full_name)                   AS repo_count,
+    coalesce(sum(r.stargazers_count), 0) AS total_stars,
+    count(DISTINCT r.language)           AS language_count,
+    mode(r.language)                     AS top_language
+FROM users u
+LEFT JOIN repositories r ON r.login = u.login
+GROUP BY u.login, u.followers;
+
+CREATE OR REPLACE VIEW language_stats AS
+SELECT
+    language,
+    count(*)              AS repo_count,
+    count(DISTINCT login) AS user_count,
+    sum(stargazers_count) AS total_stars
+FROM repositories
+WHERE language IS NOT NULL AND language <> ''
+GROUP BY language
+ORDER BY repo_count DESC;
+`
+
+func (e *duckDBExporter) export(ctx context.Context, users []User, repos []Repo) error {
+	base := strings.TrimSuffix(e.path, filepath.Ext(e.path))
+	dataDir := base + ".data"
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	usersCSV := filepath.Join(dataDir, "users.csv")
+	reposCSV := filepath.Join(dataDir, "repositories.csv")
+	if err := writeRecordsCSV(usersCSV, userColumns, users, userRecord); err != nil {
+		return err
+	}
+	if err := writeRecordsCSV(reposCSV, repoColumns, repos, repoRecord); err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	script.WriteString("BEGIN TRANSACTION;\n")
+	script.WriteString(duckDBMigrate())
+	script.WriteString(duckDBLoadTable("users", usersCSV, userColumns, reflect.TypeFor[User]()))
+	script.WriteString(duckDBLoadTable("repositories", reposCSV, repoColumns, reflect.TypeFor[Repo]()))
+	script.WriteString(duckDBViews)
+	script.WriteString("COMMIT;\n")
+	scriptPath := base + ".sql"
+	if err := writeFileAtomic(scriptPath, []byte(script.String())); err != nil {
+		return err
+	}
+
+	if _, err := exec.LookPath("duckdb"); err != nil {
+		fmt.Printf("duckdb CLI not found; run `duckdb %s < %s` to build the database\n", e.path, scriptPath)
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "duckdb", e.path)
+	cmd.Stdin = strings.NewReader(script.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("duckdb: %v: %s", err, out)
+	}
+	return nil
+}
+
+// duckDBLoadTable returns SQL that replaces a table's rows with a CSV file's,
+// with column types taken from the matching struct fields. Columns are
+// matched by name, so the table may have more than the file.
+func duckDBLoadTable(table, csvPath string, columns []string, t reflect.Type) string {
+	types := map[string]string{}
+	for _, f := range bigQuerySchema(t) {
+		switch f.Type {
+		case "INTEGER":
+			types[f.Name] = "BIGINT"
+		case "BOOLEAN":
+			types[f.Name] = "BOOLEAN"
+		case "FLOAT":
+			types[f.Name] = "DOUBLE"
+		}
+	}
+	types["created_at"] = "TIMESTAMP"
+
+	cols := make([]string, len(columns))
+	for i, c := range columns {
+		typ := types[c]
+		if typ == "" {
+			typ = "VARCHAR"
+		}
+		cols[i] = fmt.Sprintf("'%s': '%s'", c, typ)
+	}
+	abs, err := filepath.Abs(csvPath)
+	if err != nil {
+		abs = csvPath
+	}
+	return fmt.Sprintf("DELETE FROM %s;\nINSERT INTO %s BY NAME SELECT * FROM read_csv('%s', header = true, columns = {%s});\n",
+		table, table, strings.ReplaceAll(abs, "'", "''"), strings.Join(cols, ", "))
+}
+
+// writeRecordsCSV writes a header and one row per item.
+func writeRecordsCSV[T any](path string, header []string, items []T, record func(T) []string) error {
+	file, err := createAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.discard()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, item := range items {
+		writer.Write(record(item))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.commit()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// esBulkSize is the number of documents sent per _bulk request.
+const esBulkSize = 1000
+
+// esExporter bulk-indexes users and repos into Elasticsearch or OpenSearch.
+// Documents are keyed by login and full_name, so re-runs update in place.
+type esExporter struct {
+	url    string
+	prefix string
+	apiKey string
+}
+
+func (e *esExporter) name() string { return "Elasticsearch" }
+
+var (
+	esKeywordText = map[string]any{"type": "text", "fields": map[string]any{"keyword": map[string]any{"type": "keyword", "ignore_above": 256}}}
+	esDate        = map[string]any{"type": "date", "ignore_malformed": true}
+
+	esUserMappings = map[string]any{
+		"login":        map[string]any{"type": "keyword"},
+		"name":         esKeywordText,
+		"company":      esKeywordText,
+		"location":     esKeywordText,
+		"email":        map[string]any{"type": "keyword"},
+		"hireable":     map[string]any{"type": "boolean"},
+		"bio":          map[string]any{"type": "text"},
+		"public_repos": map[string]any{"type": "integer"},
+		"followers":    map[string]any{"type": "integer"},
+		"following":    map[string]any{"type": "integer"},
+		"created_at":   esDate,
+	}
+	esRepoMappings = map[string]any{
+		"login":             map[string]any{"type": "keyword"},
+		"full_name":         esKeywordText,
+		"created_at":        esDate,
+		"stargazers_count":  map[string]any{"type": "integer"},
+		"watchers_count":    map[string]any{"type": "integer"},
+		"language":          map[string]any{"type": "keyword"},
+		"has_projects":      map[string]any{"type": "boolean"},
+		"has_wiki":          map[string]any{"type": "boolean"},
+		"has_issues":        map[string]any{"type": "boolean"},
+		"has_discussions":   map[string]any{"type": "boolean"},
+		"has_pages":         map[string]any{"type": "boolean"},
+		"license_name":      map[string]any{"type": "keyword"},
+		"fork":              map[string]any{"type": "boolean"},
+		"root_repo":         map[string]any{"type": "keyword"},
+		"description":       map[string]any{"type": "text"},
+		"homepage":          map[string]any{"type": "keyword"},
+		"subscribers_count": map[string]any{"type": "integer"},
+	}
+)
+
+func (e *esExporter) export(ctx context.Context, users []User, repos []Repo) error {
+	usersIndex, reposIndex := e.prefix+"-users", e.prefix+"-repositories"
+	if err := e.ensureIndex(ctx, usersIndex, esUserMappings); err != nil {
+		return err
+	}
+	if err := e.ensureIndex(ctx, reposIndex, esRepoMappings); err != nil {
+		return err
+	}
+	if err := bulkIndex(ctx, e, usersIndex, users, func(u User) string { return u.Login }); err != nil {
+		return err
+	}
+	return bulkIndex(ctx, e, reposIndex, repos, func(r Repo) string { return r.FullName })
+}
+
+func (e *esExporter) request(ctx context.Context, method, path string, body []byte, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(e.url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// ensureIndex creates the index with its mappings unless it already exists.
+func (e *esExporter) ensureIndex(ctx context.Context, index string, properties map[string]any) error {
+	req, err := e.request(ctx, "HEAD", "/"+index, nil, "")
+	if err != nil {
+		return err
+	}
+	resp, err := sinkClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]any{"mappings": map[string]any{"properties": properties}})
+	if err != nil {
+		return err
+	}
+	req, err = e.request(ctx, "PUT", "/"+index, body, "application/json")
+	if err != nil {
+		return err
+	}
+	return doJSON(req, nil)
+}
+
+// bulkIndex sends docs to the _bulk API in batches of esBulkSize.
+func bulkIndex[T any](ctx context.Context, e *esExporter, index string, docs []T, id func(T) string) error {
+	for start := 0; start < len(docs); start += esBulkSize {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for _, doc := range docs[start:min(start+esBulkSize, len(docs))] {
+			enc.Encode(map[string]any{"index": map[string]string{"_index": index, "_id": id(doc)}})
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+		req, err := e.request(ctx, "POST", "/_bulk", body.Bytes(), "application/x-ndjson")
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Errors bool `json:"errors"`
+			Items  []map[string]struct {
+				ID    string          `json:"_id"`
+				Error json.RawMessage `json:"error"`
+			} `json:"items"`
+		}
+		if err := doJSON(req, &result); err != nil {
+			return err
+		}
+		if result.Errors {
+			for _, item := range result.Items {
+				for _, r := range item {
+					if len(r.Error) > 0 {
+						return fmt.Errorf("indexing %s into %s: %s", r.ID, index, r.Error)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
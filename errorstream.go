@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errorEvent is a failed API request as --error-stream reports it.
+type errorEvent struct {
+	Time   time.Time `json:"time"`
+	Phase  string    `json:"phase"`
+	Login  string    `json:"login,omitempty"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	Status int       `json:"status,omitempty"`
+	Error  string    `json:"error"`
+	// Retryable is set for failures worth trying again later: network
+	// errors, timeouts, rate limits, and server errors.
+	Retryable bool `json:"retryable"`
+}
+
+// errorStream writes a JSON line for every failed API request, so that an
+// orchestrator can parse failures from stderr while stdout carries the
+// run's messages.
+type errorStream struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	phase func() string
+}
+
+func newErrorStream(w io.Writer, phase func() string) *errorStream {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &errorStream{enc: enc, phase: phase}
+}
+
+// requestLogin returns the user a GitHub API path is about, if any: the
+// login in /users/{login}/... or the owner in /repos/{owner}/{repo}/....
+func requestLogin(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "users" || parts[i] == "repos" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// record is a responseHook.
+func (s *errorStream) record(req *http.Request, start time.Time, resp *http.Response, err error) {
+	cause := failureCause(resp, err)
+	if cause == "" {
+		return
+	}
+	e := errorEvent{
+		Time:      time.Now().UTC(),
+		Phase:     s.phase(),
+		Login:     requestLogin(req.URL.Path),
+		Method:    req.Method,
+		URL:       req.URL.Redacted(),
+		Error:     cause,
+		Retryable: true,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if resp != nil {
+		e.Status = resp.StatusCode
+		_, limited := rateLimitWait(cachedResponse{status: resp.StatusCode, header: resp.Header})
+		e.Retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || limited
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(e)
+}
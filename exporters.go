@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// exporter pushes a run's users and repos to a destination besides the
+// local CSV files.
+type exporter interface {
+	name() string
+	export(ctx context.Context, users []User, repos []Repo) error
+}
+
+// runExporters runs every exporter, reporting failures without stopping the
+// others. It returns the number of exporters that failed.
+func runExporters(ctx context.Context, exporters []exporter, users []User, repos []Repo) int {
+	failed := 0
+	for _, e := range exporters {
+		ctx, sp := startSpan(ctx, "export", "exporter", e.name())
+		err := e.export(ctx, users, repos)
+		sp.finish(err)
+		if err != nil {
+			fmt.Printf("Error exporting to %s: %v\n", e.name(), err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// sinkClient is used for requests to export destinations.
+var sinkClient = &http.Client{Timeout: 60 * time.Second}
+
+// doJSON sends req and decodes a JSON response into v, which may be nil.
+// Non-2xx responses are returned as errors that include the response body.
+func doJSON(req *http.Request, v any) error {
+	resp, err := sinkClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, body)
+	}
+	if v == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Material for fake users and repos.
+var (
+	fakeSurnames   = []string{"Wang", "Li", "Zhang", "Liu", "Chen", "Yang", "Huang", "Zhao", "Wu", "Zhou", "Xu", "Sun", "Ma", "Zhu", "Hu", "Guo", "He", "Lin", "Gao", "Luo"}
+	fakeGivenNames = []string{"Wei", "Fang", "Min", "Jing", "Lei", "Jun", "Yang", "Yong", "Yan", "Jie", "Tao", "Ming", "Chao", "Xiu", "Hui", "Qiang", "Peng", "Hao", "Xin", "Yu"}
+	fakeCompanies  = []string{"ALIBABA", "ANT GROUP", "TENCENT", "BYTEDANCE", "BILIBILI", "PINDUODUO", "XIAOHONGSHU", "MICROSOFT", "SHOPEE", "TRIP.COM", "NIO", "SJTU", "FUDAN UNIVERSITY", "PINGCAP", "FREELANCE"}
+	fakeRoles      = []string{"Backend engineer", "Frontend developer", "Full-stack developer", "ML engineer", "Data engineer", "SRE", "Android developer", "iOS developer", "Student", "Open source maintainer"}
+	fakeInterests  = []string{"Go", "Rust", "Kubernetes", "distributed systems", "databases", "React", "Vue", "deep learning", "compilers", "cloud native"}
+	fakeLicenses   = []string{"mit", "apache-2.0", "gpl-3.0", "bsd-3-clause", "mpl-2.0"}
+	fakeWords      = []string{"awesome", "mini", "fast", "simple", "cloud", "data", "web", "go", "rs", "ml", "kit", "lab", "bot", "api", "ui", "db", "cli", "notes", "demo", "toolkit"}
+)
+
+// fakeLanguages are repo languages with their relative frequency.
+var fakeLanguages = []struct {
+	name   string
+	weight int
+}{
+	{"JavaScript", 18}, {"Python", 16}, {"Java", 12}, {"Go", 10}, {"TypeScript", 10},
+	{"C++", 7}, {"Vue", 5}, {"C", 5}, {"Rust", 4}, {"Shell", 4},
+	{"Kotlin", 2}, {"Swift", 2}, {"PHP", 2}, {"Jupyter Notebook", 2}, {"", 5},
+}
+
+// fakeData generates users and repos that look like a scrape of a city:
+// Chinese names, a few large employers, and followers, repos, and stars
+// with the long tail of the real thing. The same seed gives the same data.
+type fakeData struct {
+	rng *rand.Rand
+	now time.Time
+}
+
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
+
+// pareto draws a count from a Pareto distribution with minimum lo and
+// shape alpha, capped at hi.
+func (f fakeData) pareto(lo, hi int, alpha float64) int {
+	return min(hi, int(float64(lo)/math.Pow(1-f.rng.Float64(), 1/alpha)))
+}
+
+// date returns a time uniformly between from and f.now.
+func (f fakeData) date(from time.Time) time.Time {
+	return from.Add(time.Duration(f.rng.Int64N(int64(f.now.Sub(from))))).Truncate(time.Second)
+}
+
+func (f fakeData) user(i int) User {
+	given := pick(f.rng, fakeGivenNames)
+	surname := pick(f.rng, fakeSurnames)
+	u := User{
+		Login:     fmt.Sprintf("%s%s%d", strings.ToLower(given), strings.ToLower(surname), i),
+		Name:      given + " " + surname,
+		Location:  pick(f.rng, []string{"Shanghai", "Shanghai, China", "上海"}),
+		Followers: f.pareto(201, 60000, 1.3),
+		Following: f.pareto(1, 2000, 1.1) - 1,
+		CreatedAt: f.date(time.Date(2008, 4, 1, 0, 0, 0, 0, time.UTC)).Format(time.RFC3339),
+		Hireable:  f.rng.IntN(4) == 0,
+		Type:      "User",
+	}
+	if f.rng.IntN(4) > 0 {
+		u.Company = pick(f.rng, fakeCompanies)
+	}
+	if f.rng.IntN(3) > 0 {
+		u.Bio = fmt.Sprintf("%s. Into %s and %s.", pick(f.rng, fakeRoles), pick(f.rng, fakeInterests), pick(f.rng, fakeInterests))
+	}
+	if f.rng.IntN(3) == 0 {
+		u.Email = u.Login + "@example.com"
+	}
+	u.PublicRepos = f.pareto(1, 300, 1.2)
+	return u
+}
+
+// language draws a repo language by its frequency; "" is a repo GitHub
+// detects no language in.
+func (f fakeData) language() string {
+	total := 0
+	for _, l := range fakeLanguages {
+		total += l.weight
+	}
+	n := f.rng.IntN(total)
+	for _, l := range fakeLanguages {
+		if n < l.weight {
+			return l.name
+		}
+		n -= l.weight
+	}
+	return ""
+}
+
+// repos returns a user's public repos, up to limit of them.
+func (f fakeData) repos(u User, limit int) []Repo {
+	created, _ := time.Parse(time.RFC3339, u.CreatedAt)
+	repos := make([]Repo, 0, min(u.PublicRepos, limit))
+	for i := range cap(repos) {
+		lang := f.language()
+		stars := f.pareto(1, 50000, 0.9) - 1
+		r := Repo{
+			Login:           u.Login,
+			FullName:        fmt.Sprintf("%s/%s-%s-%d", u.Login, pick(f.rng, fakeWords), pick(f.rng, fakeWords), i),
+			CreatedAt:       f.date(created).Format(time.RFC3339),
+			StargazersCount: stars,
+			WatchersCount:   stars,
+			Language:        lang,
+			HasProjects:     f.rng.IntN(2) == 0,
+			HasWiki:         f.rng.IntN(3) > 0,
+			HasIssues:       f.rng.IntN(10) > 0,
+			HasDiscussions:  f.rng.IntN(10) == 0,
+			HasPages:        f.rng.IntN(8) == 0,
+			Fork:            f.rng.IntN(4) == 0,
+		}
+		if f.rng.IntN(3) > 0 {
+			r.LicenseName = pick(f.rng, fakeLicenses)
+		}
+		if f.rng.IntN(2) == 0 {
+			kind := pick(f.rng, []string{"library", "tool", "service", "demo", "framework"})
+			r.Description = fmt.Sprintf("A %s %s in %s", pick(f.rng, fakeWords), kind, cmp.Or(lang, "several languages"))
+		}
+		repos = append(repos, r)
+	}
+	return repos
+}
+
+func runFake(args []string) int {
+	fs := flag.NewFlagSet("fake", flag.ExitOnError)
+	users := fs.Int("users", 500, "number of users to generate")
+	maxRepos := fs.Int("max-repos", 100, "most repos generated per user")
+	seed := fs.Uint64("seed", 1, "random seed; the same seed gives the same data")
+	outDir := fs.String("out-dir", ".", "directory for users.csv and repositories.csv")
+	fs.Parse(args)
+
+	if *users < 0 || *maxRepos < 0 {
+		fmt.Println("Error: --users and --max-repos must not be negative")
+		return 2
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	// The CSV writers write to the working directory, as a scrape does.
+	if err := os.Chdir(*outDir); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	// A fixed clock keeps the data the same from one day to the next.
+	f := fakeData{rng: rand.New(rand.NewPCG(*seed, 0)), now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fakeUsers := make([]User, *users)
+	var repos []Repo
+	for i := range fakeUsers {
+		fakeUsers[i] = f.user(i)
+		repos = append(repos, f.repos(fakeUsers[i], *maxRepos)...)
+	}
+	// Give forks roots as the scrape does.
+	assignRootRepos(repos)
+	slices.SortStableFunc(fakeUsers, func(a, b User) int { return b.Followers - a.Followers })
+
+	if err := saveUsersToCSV(fakeUsers, nil, nil); err != nil {
+		fmt.Println("Error saving users to CSV:", err)
+		return 1
+	}
+	if err := saveReposToCSV(slices.Values(repos), nil, nil); err != nil {
+		fmt.Println("Error saving repos to CSV:", err)
+		return 1
+	}
+	fmt.Printf("Wrote %d fake users and %d repos to %s\n", len(fakeUsers), len(repos), *outDir)
+	return 0
+}
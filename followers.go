@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// followersQualifier matches the followers qualifier of a search query,
+// e.g. "followers:>200" or "followers:>=50".
+var followersQualifier = regexp.MustCompile(`followers:(>=|>)?(\d+)\b`)
+
+// queryMinFollowers returns the fewest followers a user matching query has,
+// or 0 if the query does not bound followers from below.
+func queryMinFollowers(query string) int {
+	m := followersQualifier.FindStringSubmatch(query)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[2])
+	if m[1] == ">" {
+		n++
+	}
+	return n
+}
+
+// verifyFollowers drops users with fewer than minFollowers followers. The
+// search index lags behind profiles, so it can return users who have since
+// fallen below the threshold. Users within marginPct percent above it are
+// borderline: with a response cache their profiles are checked with the
+// server again, since the cached copy may be as stale as the index. It
+// returns the users kept, with refreshed profiles, and the number dropped.
+func (c *apiClient) verifyFollowers(ctx context.Context, users []User, minFollowers, marginPct int) ([]User, int) {
+	borderline := minFollowers + minFollowers*marginPct/100
+	checked := make([]User, len(users))
+	copy(checked, users)
+	if c.cache != nil {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, fetchConcurrency)
+		for i, u := range users {
+			if u.Followers > borderline {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if fresh, _, err := c.userDetails(ctx, u.Login, true); err == nil {
+					checked[i] = fresh
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	var kept []User
+	for _, u := range checked {
+		if u.Followers >= minFollowers {
+			kept = append(kept, u)
+		}
+	}
+	return kept, len(users) - len(kept)
+}
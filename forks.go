@@ -0,0 +1,200 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// resolveForkRoots looks up the upstream source of every fork and records
+// it as the fork's root repo. Forks whose lookup fails keep an empty root
+// for assignRootRepos to fill in. It costs one API call per fork.
+func (c *apiClient) resolveForkRoots(ctx context.Context, repos []Repo) {
+	c.lookupRepos(ctx, repos, func(r Repo) bool { return r.Fork && r.RootRepo == "" })
+}
+
+// fetchSubscribers fills in the subscribers count, the repo's true
+// watchers, of every repo; the repo lists only carry watchers_count, which
+// mirrors the stars. Since it looks up every repo, it resolves fork roots
+// too. It costs one API call per repo.
+func (c *apiClient) fetchSubscribers(ctx context.Context, repos []Repo) {
+	c.lookupRepos(ctx, repos, func(Repo) bool { return true })
+}
+
+// lookupRepos fetches the full record of each repo that want selects and
+// records its subscribers count and, for forks without one, its root.
+func (c *apiClient) lookupRepos(ctx context.Context, repos []Repo, want func(Repo) bool) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchConcurrency)
+	for i := range repos {
+		if !want(repos[i]) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *Repo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			body, err := c.getCached(ctx, fmt.Sprintf("%s/repos/%s", c.baseURL, r.FullName))
+			if err != nil {
+				return
+			}
+			var detail struct {
+				SubscribersCount int `json:"subscribers_count"`
+				Source           struct {
+					FullName string `json:"full_name"`
+				} `json:"source"`
+			}
+			if json.Unmarshal(body, &detail) != nil {
+				return
+			}
+			r.SubscribersCount = detail.SubscribersCount
+			if r.Fork && r.RootRepo == "" {
+				r.RootRepo = detail.Source.FullName
+			}
+		}(&repos[i])
+	}
+	wg.Wait()
+}
+
+// repoBaseName reduces a repo name to the key used to match forks to
+// originals: lower case, without the owner, separators, or a .git suffix.
+func repoBaseName(fullName string) string {
+	name := fullName[strings.LastIndex(fullName, "/")+1:]
+	name = strings.TrimSuffix(strings.ToLower(name), ".git")
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(name)
+}
+
+// assignRootRepos fills in RootRepo where it is empty. A non-fork is its
+// own root. A fork is attributed to the oldest non-fork in the population
+// with a near-identical name, else to the oldest fork of that name, so
+// copies of one upstream group together even when it was not scraped.
+func assignRootRepos(repos []Repo) {
+	ix := newRootIndex()
+	for _, r := range repos {
+		ix.add(r)
+	}
+	for i := range repos {
+		repos[i].RootRepo = ix.root(repos[i])
+	}
+}
+
+// rootIndex holds, per base name, the oldest non-fork and the oldest fork
+// seen, for assigning roots without keeping every repo in memory.
+type rootIndex struct {
+	originals  map[string]rootCandidate
+	oldestFork map[string]rootCandidate
+}
+
+type rootCandidate struct {
+	fullName  string
+	createdAt string
+}
+
+func newRootIndex() *rootIndex {
+	return &rootIndex{originals: map[string]rootCandidate{}, oldestFork: map[string]rootCandidate{}}
+}
+
+func (ix *rootIndex) add(r Repo) {
+	key := repoBaseName(r.FullName)
+	target := ix.originals
+	if r.Fork {
+		target = ix.oldestFork
+	}
+	if cur, ok := target[key]; !ok || r.CreatedAt < cur.createdAt {
+		target[key] = rootCandidate{r.FullName, r.CreatedAt}
+	}
+}
+
+// root returns r's root repo, keeping one that is already set.
+func (ix *rootIndex) root(r Repo) string {
+	if r.RootRepo != "" {
+		return r.RootRepo
+	}
+	if !r.Fork {
+		return r.FullName
+	}
+	key := repoBaseName(r.FullName)
+	if orig, ok := ix.originals[key]; ok {
+		return orig.fullName
+	}
+	return ix.oldestFork[key].fullName
+}
+
+// dedupeByRoot keeps one repo per root: the root itself when it is in the
+// list, else its most-starred copy. Aggregates over the result count each
+// upstream's stars once.
+func dedupeByRoot(repos []Repo) []Repo {
+	best := map[string]int{}
+	var order []string
+	for i, r := range repos {
+		root := cmp.Or(r.RootRepo, r.FullName)
+		j, ok := best[root]
+		switch {
+		case !ok:
+			best[root] = i
+			order = append(order, root)
+		case r.FullName == root && repos[j].FullName != root,
+			repos[j].FullName != root && r.StargazersCount > repos[j].StargazersCount:
+			best[root] = i
+		}
+	}
+	out := make([]Repo, len(order))
+	for i, root := range order {
+		out[i] = repos[best[root]]
+	}
+	return out
+}
+
+// forkCluster is a root repo with the forks attributed to it.
+type forkCluster struct {
+	Root      string
+	Forks     []string
+	RootStars int
+	ForkStars int
+}
+
+// forkClusters groups the repos by root, keeping roots with at least one
+// fork, largest first.
+func forkClusters(repos []Repo) []forkCluster {
+	byRoot := map[string]*forkCluster{}
+	for _, r := range repos {
+		root := cmp.Or(r.RootRepo, r.FullName)
+		c := byRoot[root]
+		if c == nil {
+			c = &forkCluster{Root: root}
+			byRoot[root] = c
+		}
+		if r.FullName == root {
+			c.RootStars = r.StargazersCount
+		} else {
+			c.Forks = append(c.Forks, r.FullName)
+			c.ForkStars += r.StargazersCount
+		}
+	}
+	var out []forkCluster
+	for _, c := range byRoot {
+		if len(c.Forks) > 0 {
+			slices.Sort(c.Forks)
+			out = append(out, *c)
+		}
+	}
+	slices.SortFunc(out, func(a, b forkCluster) int {
+		if c := cmp.Compare(len(b.Forks), len(a.Forks)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Root, b.Root)
+	})
+	return out
+}
+
+var forkClusterColumns = []string{"root_repo", "forks", "root_stars", "fork_stars", "fork_repos"}
+
+func forkClusterRecord(c forkCluster) []string {
+	return []string{c.Root, strconv.Itoa(len(c.Forks)), strconv.Itoa(c.RootStars), strconv.Itoa(c.ForkStars), strings.Join(c.Forks, ";")}
+}
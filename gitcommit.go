@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitPublisher commits a run's output files to a git repository and pushes
+// them, so dataset history lives in version control. The repository may be
+// a local working copy or anything git can clone. Either way the commit is
+// made in a temporary checkout of the branch's upstream tip: a clone, or a
+// worktree of the local repository, whose own checkout, index, and
+// branches are left alone. It shells out to the git CLI.
+type gitPublisher struct {
+	repo   string
+	branch string
+	dir    string
+}
+
+func (g *gitPublisher) git(ctx context.Context, workdir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = workdir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (g *gitPublisher) publish(ctx context.Context, files []string, s runSummary) error {
+	workdir, err := os.MkdirTemp("", "tds-git-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+	if _, err := os.Stat(filepath.Join(g.repo, ".git")); err != nil {
+		if err := g.git(ctx, "", "clone", "--depth", "1", "--branch", g.branch, g.repo, workdir); err != nil {
+			return err
+		}
+	} else {
+		if err := g.git(ctx, g.repo, "fetch", "origin", g.branch); err != nil {
+			return err
+		}
+		if err := g.git(ctx, g.repo, "worktree", "add", "--detach", workdir, "FETCH_HEAD"); err != nil {
+			return err
+		}
+		defer g.git(context.Background(), g.repo, "worktree", "remove", "--force", workdir)
+	}
+
+	target := filepath.Join(workdir, g.dir)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return err
+	}
+	var paths []string
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, filepath.FromSlash(artifactName(f)))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(dest, data); err != nil {
+			return err
+		}
+		if err := g.git(ctx, workdir, "add", "--", dest); err != nil {
+			return err
+		}
+		paths = append(paths, dest)
+	}
+
+	// Nothing to commit when the data is unchanged since the last run.
+	diff := append([]string{"-C", workdir, "diff", "--cached", "--quiet", "--"}, paths...)
+	if exec.CommandContext(ctx, "git", diff...).Run() == nil {
+		fmt.Println("Git: outputs unchanged, nothing to commit")
+		return nil
+	}
+	msg := fmt.Sprintf("Scrape run %s: %d users, %d repos", s.Started.UTC().Format(time.RFC3339), s.Users, s.Repos)
+	if s.StopReason != "" {
+		msg += " (stopped early: " + s.StopReason + ")"
+	}
+	if err := g.git(ctx, workdir, append([]string{"commit", "-m", msg, "--"}, paths...)...); err != nil {
+		return err
+	}
+	return g.git(ctx, workdir, "push", "origin", "HEAD:"+g.branch)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Publishing through a local working copy leaves its branch, index, and
+// files alone and commits only the outputs.
+func TestGitPublisherLocalRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for k, v := range map[string]string{"GIT_AUTHOR_NAME": "t", "GIT_AUTHOR_EMAIL": "t@example.com", "GIT_COMMITTER_NAME": "t", "GIT_COMMITTER_EMAIL": "t@example.com", "GIT_CONFIG_GLOBAL": os.DevNull} {
+		t.Setenv(k, v)
+	}
+	root := t.TempDir()
+	origin, local := filepath.Join(root, "origin.git"), filepath.Join(root, "local")
+	gitOutput(t, root, "init", "--bare", "-b", "main", origin)
+	gitOutput(t, root, "clone", origin, local)
+	os.WriteFile(filepath.Join(local, "README"), []byte("data\n"), 0o644)
+	gitOutput(t, local, "add", "README")
+	gitOutput(t, local, "commit", "-m", "init")
+	gitOutput(t, local, "push", "origin", "HEAD:main")
+	// The user is on another branch with work staged and unstaged.
+	gitOutput(t, local, "checkout", "-b", "feature")
+	os.WriteFile(filepath.Join(local, "staged.txt"), []byte("wip\n"), 0o644)
+	gitOutput(t, local, "add", "staged.txt")
+	os.WriteFile(filepath.Join(local, "README"), []byte("edited\n"), 0o644)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	os.WriteFile("users.csv", []byte("login\nalice\n"), 0o644)
+	g := &gitPublisher{repo: local, branch: "main", dir: "data"}
+	if err := g.publish(context.Background(), []string{"users.csv"}, runSummary{Started: time.Now(), Users: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if branch := gitOutput(t, local, "branch", "--show-current"); branch != "feature" {
+		t.Errorf("local checkout moved to %s", branch)
+	}
+	if staged, edited := gitOutput(t, local, "diff", "--cached", "--name-only"), gitOutput(t, local, "diff", "--name-only"); staged != "staged.txt" || edited != "README" {
+		t.Errorf("local changes: staged %q, unstaged %q", staged, edited)
+	}
+	if files := gitOutput(t, origin, "show", "--name-only", "--format=", "main"); files != "data/users.csv" {
+		t.Errorf("the commit has %q, want only data/users.csv", files)
+	}
+	if worktrees := gitOutput(t, local, "worktree", "list"); strings.Count(worktrees, "\n") != 0 {
+		t.Errorf("worktree left behind:\n%s", worktrees)
+	}
+
+	// Unchanged outputs make no commit.
+	head := gitOutput(t, origin, "rev-parse", "main")
+	if err := g.publish(context.Background(), []string{"users.csv"}, runSummary{Started: time.Now(), Users: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if gitOutput(t, origin, "rev-parse", "main") != head {
+		t.Error("unchanged outputs were committed again")
+	}
+}
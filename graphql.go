@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// graphQLUserFields selects the GraphQL equivalents of the REST user
+// fields from a repositoryOwner. user(login:) is null for organisations,
+// so owners are queried instead and told apart by their __typename, which
+// matches the REST type.
+const graphQLUserFields = `__typename
+	... on User { login name company location email isHireable bio createdAt
+		repositories(privacy: PUBLIC, ownerAffiliations: OWNER) { totalCount }
+		followers { totalCount } following { totalCount } }
+	... on Organization { login name location email createdAt
+		repositories(privacy: PUBLIC) { totalCount } }`
+
+type graphQLUser struct {
+	Typename     string `json:"__typename"`
+	Login        string `json:"login"`
+	Name         string `json:"name"`
+	Company      string `json:"company"`
+	Location     string `json:"location"`
+	Email        string `json:"email"`
+	IsHireable   bool   `json:"isHireable"`
+	Bio          string `json:"bio"`
+	CreatedAt    string `json:"createdAt"`
+	Repositories struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"repositories"`
+	Followers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"followers"`
+	Following struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"following"`
+}
+
+// graphQLURL returns the GraphQL endpoint of the API. GitHub Enterprise
+// serves REST under /api/v3 and GraphQL at /api/graphql, api.github.com
+// and test servers serve it at /graphql.
+func (c *apiClient) graphQLURL() string {
+	return strings.TrimSuffix(c.baseURL, "/v3") + "/graphql"
+}
+
+func (g graphQLUser) user() User {
+	return User{
+		Login: g.Login, Name: g.Name, Company: cleanCompanyName(g.Company),
+		Location: g.Location, Email: g.Email, Hireable: g.IsHireable, Bio: g.Bio,
+		PublicRepos: g.Repositories.TotalCount, Followers: g.Followers.TotalCount,
+		Following: g.Following.TotalCount, CreatedAt: g.CreatedAt, Type: g.Typename,
+	}
+}
+
+// fetchUserDetailsGraphQL fetches user details through the GraphQL API,
+// batch users per aliased query instead of one REST call each. Users of a
+// batch that fails are fetched over REST instead.
+func (c *apiClient) fetchUserDetailsGraphQL(ctx context.Context, users []User, batch int) []User {
+	var detailed, retry []User
+	for start := 0; start < len(users); start += batch {
+		part := users[start:min(start+batch, len(users))]
+		var q strings.Builder
+		q.WriteString("query {")
+		for i, u := range part {
+			fmt.Fprintf(&q, " u%d: repositoryOwner(login: %s) { %s }", i, strconv.Quote(u.Login), graphQLUserFields)
+		}
+		q.WriteString(" }")
+
+		batchCtx, sp := startSpan(ctx, "user batch", "users", strconv.Itoa(len(part)))
+		var resp struct {
+			Data   map[string]*graphQLUser `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		err := c.sendJSON(batchCtx, "POST", c.graphQLURL(), map[string]string{"query": q.String()}, &resp)
+		if err == nil && resp.Data == nil && len(resp.Errors) > 0 {
+			err = fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+		}
+		sp.finish(err)
+		c.progress.report("details", start+len(part), len(users))
+		if err != nil {
+			if stopReason(ctx, c) != "" {
+				break
+			}
+			fmt.Println("Error fetching user details over GraphQL, falling back to REST:", err)
+			retry = append(retry, part...)
+			continue
+		}
+		// Accounts that no longer exist come back as null and are skipped.
+		for i := range part {
+			if g := resp.Data["u"+strconv.Itoa(i)]; g != nil {
+				u := g.user()
+				detailed = append(detailed, u)
+				if c.onUser != nil {
+					c.onUser(u)
+				}
+			}
+		}
+	}
+	if len(retry) > 0 {
+		detailed = append(detailed, c.fetchUserDetailsConcurrently(ctx, retry)...)
+	}
+	return detailed
+}
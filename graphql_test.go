@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// With --graphql, organisation accounts must keep their type so that they
+// are listed in orgs.csv rather than dropped as deleted.
+func TestFetchUserDetailsGraphQLOrgs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(req.Query, "user(login:") {
+			t.Errorf("query uses user(login:), which is null for organisations: %s", req.Query)
+		}
+		w.Write([]byte(`{"data": {
+			"u0": {"__typename": "User", "login": "alice", "company": "@acme", "followers": {"totalCount": 300}},
+			"u1": {"__typename": "Organization", "login": "acme", "repositories": {"totalCount": 12}},
+			"u2": null}}`))
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+
+	users := c.fetchUserDetailsGraphQL(context.Background(), usersFromLogins([]string{"alice", "acme", "gone"}), 10)
+	people, orgs := splitOrgs(users)
+	if len(people) != 1 || people[0].Login != "alice" || people[0].Company != "ACME" || people[0].Followers != 300 {
+		t.Errorf("people = %+v", people)
+	}
+	if len(orgs) != 1 || orgs[0].Login != "acme" || orgs[0].PublicRepos != 12 {
+		t.Errorf("orgs = %+v", orgs)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct{ base, want string }{
+		{defaultBaseURL, "https://api.github.com/graphql"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/graphql"},
+		{"http://127.0.0.1:8080", "http://127.0.0.1:8080/graphql"},
+	}
+	for _, tt := range tests {
+		if got := newClient(withBaseURL(tt.base)).graphQLURL(); got != tt.want {
+			t.Errorf("graphQLURL for %s = %s, want %s", tt.base, got, tt.want)
+		}
+	}
+}
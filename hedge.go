@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// userContext bounds the work for one user, such as fetching their details
+// or every page of their repos, by the client's per-user timeout.
+func (c *apiClient) userContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.userTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.userTimeout)
+}
+
+// hedgedDo sends req and, for a GET still unanswered after the client's
+// hedge delay, sends a second copy, returning whichever response arrives
+// first. The other is cancelled. A hedge is one more API call, so it is
+// only sent while the budget allows, and never while pacing an
+// unauthenticated run or throttled by --rps.
+func (c *apiClient) hedgedDo(req *http.Request) (*http.Response, error) {
+	if c.hedgeAfter <= 0 || req.Method != http.MethodGet || c.pace != nil || c.throttle != nil {
+		return c.http.Do(req)
+	}
+	type result struct {
+		resp  *http.Response
+		err   error
+		which int
+	}
+	results := make(chan result, 2)
+	var cancels []context.CancelFunc
+	launch := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		go func(which int) {
+			resp, err := c.http.Do(req.Clone(ctx))
+			results <- result{resp, err, which}
+		}(len(cancels) - 1)
+	}
+	launch()
+	pending := 1
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+	var r result
+	select {
+	case r = <-results:
+		pending--
+	case <-timer.C:
+		if n := c.calls.Add(1); c.maxCalls == 0 || n <= c.maxCalls {
+			launch()
+			pending++
+		} else {
+			c.calls.Add(-1)
+		}
+		r = <-results
+		pending--
+		// A failed attempt gives way to the other one, if there is one.
+		if r.err != nil && pending > 0 {
+			r = <-results
+			pending--
+		}
+	}
+	if pending > 0 {
+		// Cancel the slower copy and release its connection once it
+		// returns.
+		cancels[1-r.which]()
+		go func() {
+			loser := <-results
+			if loser.resp != nil {
+				io.Copy(io.Discard, loser.resp.Body)
+				loser.resp.Body.Close()
+			}
+		}()
+	}
+	if r.err != nil {
+		cancels[r.which]()
+		return nil, r.err
+	}
+	r.resp.Body = cancelOnClose{r.resp.Body, cancels[r.which]}
+	return r.resp, nil
+}
+
+// cancelOnClose releases a hedged request's context once its body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A GET unanswered after the hedge delay is sent again, and the faster
+// copy wins.
+func TestHedgedDo(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL), withHedge(20*time.Millisecond))
+
+	start := time.Now()
+	resp, err := c.get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fast" || time.Since(start) > 2*time.Second {
+		t.Errorf("got %q after %v, want the hedge's answer", body, time.Since(start))
+	}
+	if calls.Load() != 2 || c.callCount() != 2 {
+		t.Errorf("%d requests, %d API calls; want 2 and 2", calls.Load(), c.callCount())
+	}
+}
+
+// No hedge is sent once the budget is spent, nor for anything but a GET.
+func TestHedgedDoLimits(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c := newClient(withToken("x"), withBaseURL(srv.URL), withHedge(5*time.Millisecond), withMaxCalls(1))
+	if resp, err := c.get(ctx, srv.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	c = newClient(withToken("x"), withBaseURL(srv.URL), withHedge(5*time.Millisecond))
+	if resp, err := c.do(ctx, "POST", srv.URL, nil, ""); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	if calls.Load() != 2 {
+		t.Errorf("%d requests, want 2 without hedges", calls.Load())
+	}
+}
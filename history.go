@@ -0,0 +1,266 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// snapshotLayout names the per-run directories of the history store.
+const snapshotLayout = "20060102T150405Z"
+
+// saveSnapshot copies a run's users.csv and repositories.csv into
+// dir/<UTC start time>/, building up a history of runs to compare.
+func saveSnapshot(dir string, started time.Time, files []string) (string, error) {
+	target := filepath.Join(dir, started.UTC().Format(snapshotLayout))
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		if err := writeFileAtomic(filepath.Join(target, filepath.Base(f)), data); err != nil {
+			return "", err
+		}
+	}
+	return target, nil
+}
+
+// snapshot is one run in the history store.
+type snapshot struct {
+	dir   string
+	taken time.Time
+}
+
+// listSnapshots returns the snapshots in dir, oldest first.
+func listSnapshots(dir string) ([]snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []snapshot
+	for _, e := range entries {
+		if t, err := time.Parse(snapshotLayout, e.Name()); err == nil && e.IsDir() {
+			out = append(out, snapshot{filepath.Join(dir, e.Name()), t})
+		}
+	}
+	slices.SortFunc(out, func(a, b snapshot) int { return a.taken.Compare(b.taken) })
+	return out, nil
+}
+
+// load reads the snapshot's users and repos, accepting gzipped copies.
+func (s snapshot) load() ([]User, []Repo, error) {
+	pick := func(name string) string {
+		if _, err := os.Stat(filepath.Join(s.dir, name)); errors.Is(err, os.ErrNotExist) {
+			return filepath.Join(s.dir, name+".gz")
+		}
+		return filepath.Join(s.dir, name)
+	}
+	users, err := loadUsersCSV(pick("users.csv"))
+	if err != nil {
+		return nil, nil, err
+	}
+	repos, err := loadReposCSV(pick("repositories.csv"))
+	return users, repos, err
+}
+
+// retainSnapshots applies a retention policy to snapshots sorted oldest
+// first: it keeps the keepLast newest and the newest of each of the
+// keepMonthly most recent months with a snapshot. It returns the snapshots
+// the policy does not keep.
+func retainSnapshots(snaps []snapshot, keepLast, keepMonthly int) []snapshot {
+	keep := make([]bool, len(snaps))
+	months := map[string]bool{}
+	for i := len(snaps) - 1; i >= 0; i-- {
+		if len(snaps)-1-i < keepLast {
+			keep[i] = true
+		}
+		month := snaps[i].taken.Format("2006-01")
+		if !months[month] && len(months) < keepMonthly {
+			months[month] = true
+			keep[i] = true
+		}
+	}
+	var drop []snapshot
+	for i, s := range snaps {
+		if !keep[i] {
+			drop = append(drop, s)
+		}
+	}
+	return drop
+}
+
+// mover is a user or repo whose count changed between two snapshots.
+type mover struct {
+	Name   string
+	Before int
+	After  int
+	Gain   int
+	PerDay float64
+	Growth float64 // percent
+}
+
+// movers compares counts keyed by name between snapshots days apart and
+// returns the ones that grew, fastest first. Names missing from either
+// snapshot are skipped.
+func movers(before, after map[string]int, days float64) []mover {
+	var out []mover
+	for name, a := range after {
+		b, ok := before[name]
+		if !ok || a <= b {
+			continue
+		}
+		m := mover{Name: name, Before: b, After: a, Gain: a - b}
+		if days > 0 {
+			m.PerDay = float64(m.Gain) / days
+		}
+		if b > 0 {
+			m.Growth = 100 * float64(m.Gain) / float64(b)
+		}
+		out = append(out, m)
+	}
+	slices.SortFunc(out, func(x, y mover) int {
+		if c := cmp.Compare(y.Gain, x.Gain); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.Name, y.Name)
+	})
+	return out
+}
+
+var moverColumns = []string{"name", "before", "after", "gain", "per_day", "growth_percent"}
+
+func moverRecord(m mover) []string {
+	return []string{
+		m.Name, strconv.Itoa(m.Before), strconv.Itoa(m.After), strconv.Itoa(m.Gain),
+		strconv.FormatFloat(m.PerDay, 'f', 2, 64), strconv.FormatFloat(m.Growth, 'f', 2, 64),
+	}
+}
+
+func runTrending(args []string) int {
+	fs := flag.NewFlagSet("trending", flag.ExitOnError)
+	historyDir := fs.String("history-dir", "history", "history store written by scrape runs with --history-dir")
+	since := fs.Duration("since", 0, "compare against the newest snapshot at least this old (default: the previous one)")
+	top := fs.Int("top", 20, "number of movers to keep and print")
+	outDir := fs.String("out-dir", "trending", "directory for trending_users.csv and trending_repos.csv")
+	fs.Parse(args)
+
+	if *top < 0 {
+		fmt.Println("Error: --top must not be negative")
+		return 2
+	}
+
+	snaps, err := listSnapshots(*historyDir)
+	if err != nil {
+		fmt.Println("Error reading history:", err)
+		return 1
+	}
+	if len(snaps) < 2 {
+		fmt.Println("Need at least two snapshots in", *historyDir)
+		return 1
+	}
+	latest := snaps[len(snaps)-1]
+	base := snaps[len(snaps)-2]
+	if *since > 0 {
+		i := len(snaps) - 2
+		for i > 0 && latest.taken.Sub(snaps[i].taken) < *since {
+			i--
+		}
+		base = snaps[i]
+	}
+
+	oldUsers, oldRepos, err := base.load()
+	if err != nil {
+		fmt.Println("Error loading snapshot:", err)
+		return 1
+	}
+	newUsers, newRepos, err := latest.load()
+	if err != nil {
+		fmt.Println("Error loading snapshot:", err)
+		return 1
+	}
+	followers := func(users []User) map[string]int {
+		m := make(map[string]int, len(users))
+		for _, u := range users {
+			m[u.Login] = u.Followers
+		}
+		return m
+	}
+	stars := func(repos []Repo) map[string]int {
+		m := make(map[string]int, len(repos))
+		for _, r := range repos {
+			m[r.FullName] = r.StargazersCount
+		}
+		return m
+	}
+	days := latest.taken.Sub(base.taken).Hours() / 24
+	userMovers := movers(followers(oldUsers), followers(newUsers), days)
+	repoMovers := movers(stars(oldRepos), stars(newRepos), days)
+	userMovers = userMovers[:min(*top, len(userMovers))]
+	repoMovers = repoMovers[:min(*top, len(repoMovers))]
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Println("Error creating output directory:", err)
+		return 1
+	}
+	if err := writeRecordsCSV(filepath.Join(*outDir, "trending_users.csv"), moverColumns, userMovers, moverRecord); err != nil {
+		fmt.Println("Error saving trending users:", err)
+		return 1
+	}
+	if err := writeRecordsCSV(filepath.Join(*outDir, "trending_repos.csv"), moverColumns, repoMovers, moverRecord); err != nil {
+		fmt.Println("Error saving trending repos:", err)
+		return 1
+	}
+
+	fmt.Printf("Trending between %s and %s (%.1f days)\n", base.taken.Format(time.DateOnly), latest.taken.Format(time.DateOnly), days)
+	fmt.Println("Fastest-growing users (followers):")
+	for _, m := range userMovers {
+		fmt.Printf("  %-30s +%d (%.1f/day)\n", m.Name, m.Gain, m.PerDay)
+	}
+	fmt.Println("Fastest-growing repos (stars):")
+	for _, m := range repoMovers {
+		fmt.Printf("  %-50s +%d (%.1f/day)\n", m.Name, m.Gain, m.PerDay)
+	}
+	return 0
+}
+
+func runPrune(args []string) int {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	historyDir := fs.String("history-dir", "history", "history store written by scrape runs with --history-dir")
+	keepLast := fs.Int("keep-last", 0, "keep this many of the newest snapshots")
+	keepMonthly := fs.Int("keep-monthly", 0, "keep the newest snapshot of each of this many recent months")
+	dryRun := fs.Bool("dry-run", false, "list the snapshots that would be removed without removing them")
+	fs.Parse(args)
+
+	if *keepLast <= 0 && *keepMonthly <= 0 {
+		fmt.Println("Error: set --keep-last or --keep-monthly; prune never removes every snapshot")
+		return 1
+	}
+	snaps, err := listSnapshots(*historyDir)
+	if err != nil {
+		fmt.Println("Error reading history:", err)
+		return 1
+	}
+	drop := retainSnapshots(snaps, *keepLast, *keepMonthly)
+	for _, s := range drop {
+		if *dryRun {
+			fmt.Println("Would remove", s.dir)
+			continue
+		}
+		if err := os.RemoveAll(s.dir); err != nil {
+			fmt.Println("Error removing snapshot:", err)
+			return 1
+		}
+		fmt.Println("Removed", s.dir)
+	}
+	fmt.Printf("Kept %d of %d snapshots\n", len(snaps)-len(drop), len(snaps))
+	return 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMovers(t *testing.T) {
+	before := map[string]int{"a": 10, "b": 0, "c": 5, "d": 7}
+	after := map[string]int{"a": 15, "b": 5, "c": 5, "d": 3, "new": 50}
+	want := []mover{
+		{Name: "a", Before: 10, After: 15, Gain: 5, PerDay: 2.5, Growth: 50},
+		{Name: "b", Before: 0, After: 5, Gain: 5, PerDay: 2.5},
+	}
+	if got := movers(before, after, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("movers = %+v, want %+v", got, want)
+	}
+	// Snapshots taken together have no rate.
+	if got := movers(before, after, 0); got[0].PerDay != 0 {
+		t.Errorf("PerDay over 0 days = %v", got[0].PerDay)
+	}
+	if got := movers(nil, after, 1); len(got) != 0 {
+		t.Errorf("movers without a baseline = %+v", got)
+	}
+}
+
+func TestRunTrendingNegativeTop(t *testing.T) {
+	if status := runTrending([]string{"--top", "-1", "--history-dir", t.TempDir()}); status != 2 {
+		t.Errorf("--top -1 exited %d, want 2", status)
+	}
+}
+
+func TestRetainSnapshots(t *testing.T) {
+	var snaps []snapshot
+	for _, day := range []string{"2024-01-05", "2024-01-20", "2024-02-03", "2024-02-10", "2024-03-01", "2024-03-02"} {
+		taken, _ := time.Parse(time.DateOnly, day)
+		snaps = append(snaps, snapshot{dir: day, taken: taken})
+	}
+	tests := []struct {
+		keepLast, keepMonthly int
+		drop                  []string
+	}{
+		{2, 2, []string{"2024-01-05", "2024-01-20", "2024-02-03"}},
+		{1, 3, []string{"2024-01-05", "2024-02-03", "2024-03-01"}},
+		{0, 0, []string{"2024-01-05", "2024-01-20", "2024-02-03", "2024-02-10", "2024-03-01", "2024-03-02"}},
+		{10, 0, nil},
+	}
+	for _, tt := range tests {
+		var drop []string
+		for _, s := range retainSnapshots(snaps, tt.keepLast, tt.keepMonthly) {
+			drop = append(drop, s.dir)
+		}
+		if !reflect.DeepEqual(drop, tt.drop) {
+			t.Errorf("keep %d last, %d monthly: dropped %v, want %v", tt.keepLast, tt.keepMonthly, drop, tt.drop)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// initEnrichments are the enrichment flags tds init offers, with what each
+// adds.
+var initEnrichments = []struct{ flag, help string }{
+	{"events", "last public activity per user (one API call per user)"},
+	{"sponsors", "GitHub Sponsors listings"},
+	{"subscribers", "true watcher counts per repo (one API call per repo)"},
+	{"resolve-forks", "the upstream of every fork (one API call per fork)"},
+	{"harvest-commit-emails", "missing emails filled from public commits"},
+}
+
+// prompter asks questions on out and reads the answers from in.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints question and returns the trimmed answer, or def when the
+// answer is empty.
+func (p prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	if answer := strings.TrimSpace(p.in.Text()); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes/no question.
+func (p prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := p.ask(question+" ("+hint+")", "")
+	if err != nil || answer == "" {
+		return def, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// initAnswers are the choices tds init collects.
+type initAnswers struct {
+	name      string
+	flags     map[string]string
+	hasSecret bool
+}
+
+// interview asks for the location, threshold, token, outputs, and
+// enrichments of a scrape.
+func interview(p prompter) (initAnswers, error) {
+	a := initAnswers{flags: map[string]string{}}
+	location, err := p.ask("City or country to search (e.g. Shanghai, or China for its major cities)", "Shanghai")
+	if err != nil {
+		return a, err
+	}
+	threshold, err := p.ask("Only users with more followers than", "200")
+	if err != nil {
+		return a, err
+	}
+	n, err := strconv.Atoi(threshold)
+	if err != nil || n < 0 {
+		return a, fmt.Errorf("invalid follower threshold %q", threshold)
+	}
+	followers := fmt.Sprintf("followers:>%d", n)
+	if _, ok := countryLocations[strings.ToLower(location)]; ok {
+		a.flags["country"] = location
+		a.flags["query"] = followers
+	} else {
+		term := location
+		if strings.Contains(term, " ") {
+			term = `"` + term + `"`
+		}
+		a.flags["query"] = "location:" + term + " " + followers
+	}
+	a.name = partitionFileName(strings.ReplaceAll(strings.ToLower(location), " ", "-"))
+
+	token, err := p.ask("GitHub token (leave empty to use $GITHUB_TOKEN at run time)", "")
+	if err != nil {
+		return a, err
+	}
+	if token != "" {
+		a.flags["token"] = token
+		a.hasSecret = true
+	}
+
+	if stats, err := p.confirm("Write stats.json with headline numbers for badges?", false); err != nil {
+		return a, err
+	} else if stats {
+		a.flags["stats-json"] = "stats.json"
+	}
+	if schemas, err := p.confirm("Write CSVW metadata and JSON Schemas next to the CSVs?", false); err != nil {
+		return a, err
+	} else if schemas {
+		a.flags["schemas"] = "true"
+	}
+	duckdb, err := p.ask("Also build a DuckDB database (file name, empty to skip)", "")
+	if err != nil {
+		return a, err
+	}
+	if duckdb != "" {
+		a.flags["duckdb"] = duckdb
+	}
+
+	fmt.Fprintln(p.out, "Enrichments:")
+	for _, e := range initEnrichments {
+		fmt.Fprintf(p.out, "  %-22s %s\n", e.flag, e.help)
+	}
+	chosen, err := p.ask("Enrichments to add, comma-separated (empty for none)", "")
+	if err != nil {
+		return a, err
+	}
+	for _, name := range strings.Split(chosen, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(initEnrichments, func(e struct{ flag, help string }) bool { return e.flag == name }) {
+			return a, fmt.Errorf("unknown enrichment %q", name)
+		}
+		a.flags[name] = "true"
+	}
+	return a, nil
+}
+
+// renderRunsConfig writes the answers as a runs config for tds runs.
+func renderRunsConfig(a initAnswers) string {
+	var b strings.Builder
+	b.WriteString("# Written by tds init. Run it with: tds runs --config <this file>\n")
+	b.WriteString("runs:\n")
+	fmt.Fprintf(&b, "  - name: %s\n", strconv.Quote(a.name))
+	b.WriteString("    flags:\n")
+	keys := make([]string, 0, len(a.flags))
+	for k := range a.flags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := a.flags[k]
+		if v != "true" {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, "      %s: %s\n", k, v)
+	}
+	return b.String()
+}
+
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	out := fs.String("out", "runs.yaml", "config file to write")
+	force := fs.Bool("force", false, "overwrite the config file if it exists")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fmt.Printf("Error: %s already exists; pass --force to overwrite it\n", *out)
+		return 2
+	}
+	a, err := interview(prompter{bufio.NewScanner(os.Stdin), os.Stdout})
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		fmt.Println()
+		fmt.Println("Error: input ended before the questions did")
+		return 1
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 2
+	}
+	if err := writeFileAtomic(*out, []byte(renderRunsConfig(a))); err != nil {
+		fmt.Println("Error writing config:", err)
+		return 1
+	}
+	if a.hasSecret {
+		// The token is a secret; keep the file private to its owner.
+		if err := os.Chmod(*out, 0o600); err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		fmt.Printf("Note: %s contains your token; keep it out of version control.\n", *out)
+	}
+	fmt.Printf("Wrote %s. Run the scrape with: tds runs --config %s\n", *out, *out)
+	return 0
+}
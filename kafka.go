@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	kafkaBatchSize     = 100
+	kafkaFlushInterval = 500 * time.Millisecond
+)
+
+// kafkaProducer publishes records as JSON to Kafka topics through a Kafka
+// REST Proxy (v2 API) while the run is in progress. Records are batched per
+// topic and sent from a background goroutine.
+type kafkaProducer struct {
+	url        string
+	usersTopic string
+	reposTopic string
+	records    chan kafkaRecord
+	done       chan struct{}
+	failed     int
+	firstErr   error
+	published  int
+}
+
+type kafkaRecord struct {
+	topic string
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+func newKafkaProducer(url, usersTopic, reposTopic string) *kafkaProducer {
+	p := &kafkaProducer{
+		url:        strings.TrimSuffix(url, "/"),
+		usersTopic: usersTopic,
+		reposTopic: reposTopic,
+		records:    make(chan kafkaRecord, 4*kafkaBatchSize),
+		done:       make(chan struct{}),
+	}
+	go p.loop()
+	return p
+}
+
+func (p *kafkaProducer) publishUser(u User) {
+	p.records <- kafkaRecord{topic: p.usersTopic, Key: u.Login, Value: u}
+}
+
+func (p *kafkaProducer) publishRepos(repos []Repo) {
+	for _, r := range repos {
+		p.records <- kafkaRecord{topic: p.reposTopic, Key: r.FullName, Value: r}
+	}
+}
+
+// close flushes pending records and reports whether any failed to publish.
+func (p *kafkaProducer) close() error {
+	close(p.records)
+	<-p.done
+	if p.failed > 0 {
+		return fmt.Errorf("%d of %d records not published to Kafka: %w", p.failed, p.failed+p.published, p.firstErr)
+	}
+	return nil
+}
+
+func (p *kafkaProducer) loop() {
+	defer close(p.done)
+	pending := map[string][]kafkaRecord{}
+	ticker := time.NewTicker(kafkaFlushInterval)
+	defer ticker.Stop()
+
+	flush := func(topic string) {
+		if len(pending[topic]) == 0 {
+			return
+		}
+		if err := p.send(topic, pending[topic]); err != nil {
+			p.failed += len(pending[topic])
+			if p.firstErr == nil {
+				p.firstErr = err
+			}
+		} else {
+			p.published += len(pending[topic])
+		}
+		pending[topic] = pending[topic][:0]
+	}
+
+	for {
+		select {
+		case r, ok := <-p.records:
+			if !ok {
+				for topic := range pending {
+					flush(topic)
+				}
+				return
+			}
+			pending[r.topic] = append(pending[r.topic], r)
+			if len(pending[r.topic]) >= kafkaBatchSize {
+				flush(r.topic)
+			}
+		case <-ticker.C:
+			for topic := range pending {
+				flush(topic)
+			}
+		}
+	}
+}
+
+func (p *kafkaProducer) send(topic string, records []kafkaRecord) error {
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url+"/topics/"+topic, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	return doJSON(req, nil)
+}
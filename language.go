@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageMap renames language labels so that reports do not split one
+// language, or one family of languages, across several rows. Keys are
+// lower-cased labels as GitHub reports them.
+type languageMap map[string]string
+
+// loadLanguageMap reads a YAML mapping from a label to the name it is
+// reported under, e.g.
+//
+//	Jupyter Notebook: Python (notebooks)
+//	HTML: HTML+CSS
+//	CSS: HTML+CSS
+//	SCSS: HTML+CSS
+//
+// Labels are matched case-insensitively. An empty path returns a nil map,
+// which leaves every label as it is.
+func loadLanguageMap(path string) (languageMap, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cfg, err := loadYAMLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := languageMap{}
+	for label := range cfg {
+		name := yamlString(cfg, label, "")
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s: %s must map to a language name", path, label)
+		}
+		m[strings.ToLower(strings.TrimSpace(label))] = strings.TrimSpace(name)
+	}
+	return m, nil
+}
+
+// normalize returns the name label is reported under.
+func (m languageMap) normalize(label string) string {
+	if name, ok := m[strings.ToLower(label)]; ok {
+		return name
+	}
+	return label
+}
+
+// apply renames the language of each repo in place and returns how many
+// changed.
+func (m languageMap) apply(repos []Repo) int {
+	if len(m) == 0 {
+		return 0
+	}
+	changed := 0
+	for i := range repos {
+		if name := m.normalize(repos[i].Language); name != repos[i].Language {
+			repos[i].Language = name
+			changed++
+		}
+	}
+	return changed
+}
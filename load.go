@@ -0,0 +1,190 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// csvRecord gives access to a CSV row by column name.
+type csvRecord struct {
+	cols map[string]int
+	row  []string
+}
+
+func (r csvRecord) str(name string) string {
+	if i, ok := r.cols[name]; ok && i < len(r.row) {
+		return r.row[i]
+	}
+	return ""
+}
+
+func (r csvRecord) int(name string) int {
+	n, _ := strconv.Atoi(r.str(name))
+	return n
+}
+
+func (r csvRecord) bool(name string) bool {
+	b, _ := strconv.ParseBool(r.str(name))
+	return b
+}
+
+// readCSV reads a CSV file with a header row and calls fn for every record.
+// Files ending in .gz are decompressed.
+func readCSV(path string, fn func(csvRecord)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	cols := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		cols[name] = i
+	}
+	for _, row := range rows[1:] {
+		fn(csvRecord{cols: cols, row: row})
+	}
+	return nil
+}
+
+// csvTable is a CSV file held whole, for rewriting it with columns the
+// code writing it does not know about.
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+// readCSVTable reads the CSV file at path. A missing file reads as an
+// empty table.
+func readCSVTable(path string) (csvTable, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return csvTable{}, nil
+	}
+	if err != nil {
+		return csvTable{}, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return csvTable{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return csvTable{}, nil
+	}
+	return csvTable{header: rows[0], rows: rows[1:]}, nil
+}
+
+// keyed returns the rows by the lower-cased value of the column key.
+func (t csvTable) keyed(key string) map[string][]string {
+	out := map[string][]string{}
+	if i := slices.Index(t.header, key); i >= 0 {
+		for _, row := range t.rows {
+			if i < len(row) {
+				out[strings.ToLower(row[i])] = row
+			}
+		}
+	}
+	return out
+}
+
+// rewriter returns the header and a record function for writing the file
+// t was read from again with rows built from the columns std, keyed by
+// the column key: the header is t's, with any column of std it lacks
+// added, and columns outside std keep the value of the old row with the
+// same key, or are left blank.
+func (t csvTable) rewriter(std []string, key string) ([]string, func(record []string) []string) {
+	header := slices.Clone(t.header)
+	for _, name := range std {
+		if !slices.Contains(header, name) {
+			header = append(header, name)
+		}
+	}
+	old := t.keyed(key)
+	keyIndex := slices.Index(std, key)
+	return header, func(record []string) []string {
+		prev := old[strings.ToLower(record[keyIndex])]
+		out := make([]string, len(header))
+		for i, name := range header {
+			if j := slices.Index(std, name); j >= 0 && j < len(record) {
+				out[i] = record[j]
+			} else if j := slices.Index(t.header, name); j >= 0 && j < len(prev) {
+				out[i] = prev[j]
+			}
+		}
+		return out
+	}
+}
+
+func loadUsersCSV(path string) ([]User, error) {
+	var users []User
+	err := readCSV(path, func(r csvRecord) {
+		users = append(users, User{
+			Login:       r.str("login"),
+			Name:        r.str("name"),
+			Company:     r.str("company"),
+			Location:    r.str("location"),
+			Email:       r.str("email"),
+			Hireable:    r.bool("hireable"),
+			Bio:         r.str("bio"),
+			PublicRepos: r.int("public_repos"),
+			Followers:   r.int("followers"),
+			Following:   r.int("following"),
+			CreatedAt:   r.str("created_at"),
+		})
+	})
+	return users, err
+}
+
+func loadReposCSV(path string) ([]Repo, error) {
+	var repos []Repo
+	err := readCSV(path, func(r csvRecord) {
+		repos = append(repos, Repo{
+			Login:            r.str("login"),
+			FullName:         r.str("full_name"),
+			CreatedAt:        r.str("created_at"),
+			StargazersCount:  r.int("stargazers_count"),
+			WatchersCount:    r.int("watchers_count"),
+			Language:         r.str("language"),
+			HasProjects:      r.bool("has_projects"),
+			HasWiki:          r.bool("has_wiki"),
+			HasIssues:        r.bool("has_issues"),
+			HasDiscussions:   r.bool("has_discussions"),
+			HasPages:         r.bool("has_pages"),
+			LicenseName:      r.str("license_name"),
+			Fork:             r.bool("fork"),
+			RootRepo:         r.str("root_repo"),
+			Description:      r.str("description"),
+			Homepage:         r.str("homepage"),
+			SubscribersCount: r.int("subscribers_count"),
+		})
+	})
+	return repos, err
+}
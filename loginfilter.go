@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// loginFilter drops users by login before their details are fetched.
+// Patterns are matched case-insensitively and may use path.Match
+// wildcards, e.g. "*-bot"; brackets must be escaped, as in *\[bot\].
+// Organisation accounts are matched by their login like users.
+type loginFilter struct {
+	exclude []string
+	// only, when set, keeps just the users matching one of its patterns.
+	only []string
+}
+
+// loadLoginFilter reads the --exclude-logins and --only-logins files.
+// Empty paths leave that side of the filter off.
+func loadLoginFilter(excludePath, onlyPath string) (loginFilter, error) {
+	var f loginFilter
+	for _, l := range []struct {
+		path string
+		dst  *[]string
+	}{{excludePath, &f.exclude}, {onlyPath, &f.only}} {
+		if l.path == "" {
+			continue
+		}
+		list, err := readLoginList(l.path)
+		if err != nil {
+			return f, err
+		}
+		*l.dst = []string{}
+		for _, p := range list {
+			p = strings.ToLower(p)
+			if _, err := path.Match(p, ""); err != nil {
+				return f, fmt.Errorf("%s: invalid pattern %q", l.path, p)
+			}
+			*l.dst = append(*l.dst, p)
+		}
+	}
+	return f, nil
+}
+
+func matchesAny(patterns []string, login string) bool {
+	login = strings.ToLower(login)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, login); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the users the filter keeps and how many it dropped.
+func (f loginFilter) apply(users []User) ([]User, int) {
+	if f.exclude == nil && f.only == nil {
+		return users, 0
+	}
+	var kept []User
+	for _, u := range users {
+		if matchesAny(f.exclude, u.Login) || (f.only != nil && !matchesAny(f.only, u.Login)) {
+			continue
+		}
+		kept = append(kept, u)
+	}
+	return kept, len(users) - len(kept)
+}
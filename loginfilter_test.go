@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoginFilterApply(t *testing.T) {
+	users := []User{{Login: "alice"}, {Login: "Dependabot[bot]"}, {Login: "ci-bot"}, {Login: "bob"}, {Login: "acme-org"}}
+	tests := []struct {
+		name    string
+		filter  loginFilter
+		kept    []string
+		dropped int
+	}{
+		{"off", loginFilter{}, []string{"alice", "Dependabot[bot]", "ci-bot", "bob", "acme-org"}, 0},
+		{"exclude", loginFilter{exclude: []string{`*\[bot\]`, "*-bot"}}, []string{"alice", "bob", "acme-org"}, 2},
+		{"only", loginFilter{only: []string{"alice", "a*"}}, []string{"alice", "acme-org"}, 3},
+		{"both", loginFilter{exclude: []string{"acme-*"}, only: []string{"a*"}}, []string{"alice"}, 4},
+		{"empty only list", loginFilter{only: []string{}}, nil, 5},
+	}
+	for _, tt := range tests {
+		kept, dropped := tt.filter.apply(users)
+		if !slices.Equal(logins(kept), tt.kept) || dropped != tt.dropped {
+			t.Errorf("%s: kept %v, dropped %d; want %v and %d", tt.name, logins(kept), dropped, tt.kept, tt.dropped)
+		}
+	}
+}
+
+func TestLoadLoginFilter(t *testing.T) {
+	dir := t.TempDir()
+	exclude := filepath.Join(dir, "exclude.txt")
+	os.WriteFile(exclude, []byte("# bots\n*-BOT\n\n  renovate  \n"), 0o644)
+	f, err := loadLoginFilter(exclude, "")
+	if err != nil || !slices.Equal(f.exclude, []string{"*-bot", "renovate"}) || f.only != nil {
+		t.Errorf("loadLoginFilter = %+v, %v", f, err)
+	}
+	bad := filepath.Join(dir, "bad.txt")
+	os.WriteFile(bad, []byte("[bot\n"), 0o644)
+	if _, err := loadLoginFilter("", bad); err == nil {
+		t.Error("an invalid pattern loaded")
+	}
+}
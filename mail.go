@@ -0,0 +1,129 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mailSettings configures delivery of the run summary by email.
+type mailSettings struct {
+	host          string
+	user          string
+	password      string
+	from          string
+	to            []string
+	attach        bool
+	maxAttachSize int64
+}
+
+type mailAttachment struct {
+	name string
+	data []byte
+}
+
+// mailAttachments returns the files to attach: the files themselves when
+// they fit in the size limit, else a zip of them if that fits, else none.
+func mailAttachments(files []string, limit int64) ([]mailAttachment, string, error) {
+	var attachments []mailAttachment
+	var total int64
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, "", err
+		}
+		attachments = append(attachments, mailAttachment{filepath.Base(f), data})
+		total += int64(len(data))
+	}
+	if total <= limit {
+		return attachments, "", nil
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, a := range attachments {
+		w, err := zw.Create(a.name)
+		if err != nil {
+			return nil, "", err
+		}
+		w.Write(a.data)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	if int64(buf.Len()) <= limit {
+		return []mailAttachment{{"tds-outputs.zip", buf.Bytes()}}, "", nil
+	}
+	return nil, fmt.Sprintf("Outputs not attached: %d bytes zipped exceeds the %d byte limit.", buf.Len(), limit), nil
+}
+
+// sendSummaryMail mails the summary, optionally attaching the files.
+func sendSummaryMail(m mailSettings, s runSummary, files []string) error {
+	body := s.text()
+	var attachments []mailAttachment
+	if m.attach {
+		var note string
+		var err error
+		attachments, note, err = mailAttachments(files, m.maxAttachSize)
+		if err != nil {
+			return err
+		}
+		if note != "" {
+			body += "\n" + note + "\n"
+		}
+	}
+
+	var msg bytes.Buffer
+	mw := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		m.from, strings.Join(m.to, ", "),
+		mime.QEncoding.Encode("utf-8", "GitHub scrape summary "+s.Started.Format("2006-01-02 15:04")),
+		time.Now().Format(time.RFC1123Z), mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return err
+	}
+	part.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+
+	for _, a := range attachments {
+		contentType := mime.TypeByExtension(filepath.Ext(a.name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.name})},
+		})
+		if err != nil {
+			return err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.user != "" {
+		host, _, _ := net.SplitHostPort(m.host)
+		auth = smtp.PlainAuth("", m.user, m.password, host)
+	}
+	return smtp.SendMail(m.host, auth, m.from, m.to, msg.Bytes())
+}
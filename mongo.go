@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// mongoBatchSize is the number of upserts sent per update command.
+const mongoBatchSize = 500
+
+// mongoExporter upserts users and repos into MongoDB, keyed by login and
+// full_name. It speaks the OP_MSG wire protocol directly and supports
+// SCRAM-SHA-256 authentication; TLS and mongodb+srv URIs are not supported.
+type mongoExporter struct {
+	uri        *url.URL
+	usersColl  string
+	reposColl  string
+	database   string
+	authSource string
+}
+
+func newMongoExporter(uri, usersColl, reposColl string) (*mongoExporter, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "mongodb" {
+		return nil, fmt.Errorf("unsupported MongoDB URI scheme %q (want mongodb://)", u.Scheme)
+	}
+	if u.Query().Get("tls") == "true" || u.Query().Get("ssl") == "true" {
+		return nil, errors.New("TLS connections to MongoDB are not supported")
+	}
+	e := &mongoExporter{uri: u, usersColl: usersColl, reposColl: reposColl}
+	e.database = strings.TrimPrefix(u.Path, "/")
+	if e.database == "" {
+		e.database = "tds"
+	}
+	e.authSource = u.Query().Get("authSource")
+	if e.authSource == "" {
+		e.authSource = e.database
+	}
+	return e, nil
+}
+
+func (e *mongoExporter) name() string { return "MongoDB" }
+
+func (e *mongoExporter) export(ctx context.Context, users []User, repos []Repo) error {
+	host := strings.Split(e.uri.Host, ",")[0]
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "27017")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	m := &mongoConn{conn: conn}
+
+	if user := e.uri.User; user != nil {
+		password, _ := user.Password()
+		if err := m.authSCRAM(e.authSource, user.Username(), password); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := upsertMongo(m, e.database, e.usersColl, "login", users, func(u User) string { return u.Login }); err != nil {
+		return err
+	}
+	return upsertMongo(m, e.database, e.reposColl, "full_name", repos, func(r Repo) string { return r.FullName })
+}
+
+// upsertMongo replaces the fields of the document matching key, inserting it
+// if missing.
+func upsertMongo[T any](m *mongoConn, db, coll, keyField string, docs []T, key func(T) string) error {
+	for start := 0; start < len(docs); start += mongoBatchSize {
+		var updates bsonArray
+		for _, doc := range docs[start:min(start+mongoBatchSize, len(docs))] {
+			updates = append(updates, bsonDoc{
+				{"q", bsonDoc{{keyField, key(doc)}}},
+				{"u", bsonDoc{{"$set", structToBSON(doc)}}},
+				{"upsert", true},
+			})
+		}
+		reply, err := m.command(bsonDoc{
+			{"update", coll},
+			{"updates", updates},
+			{"ordered", false},
+			{"$db", db},
+		})
+		if err != nil {
+			return err
+		}
+		if errs, ok := reply["writeErrors"].([]any); ok && len(errs) > 0 {
+			first, _ := errs[0].(map[string]any)
+			return fmt.Errorf("%d write errors in %s.%s, first: %v", len(errs), db, coll, first["errmsg"])
+		}
+	}
+	return nil
+}
+
+// mongoConn is a single connection speaking OP_MSG.
+type mongoConn struct {
+	conn      net.Conn
+	requestID int32
+}
+
+const opMsg = 2013
+
+// command sends a command document and returns the reply, failing if the
+// reply's ok field is not 1.
+func (m *mongoConn) command(cmd bsonDoc) (map[string]any, error) {
+	body, err := cmd.marshal()
+	if err != nil {
+		return nil, err
+	}
+	m.requestID++
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.LittleEndian, []int32{int32(16 + 4 + 1 + len(body)), m.requestID, 0, opMsg})
+	binary.Write(&msg, binary.LittleEndian, uint32(0))
+	msg.WriteByte(0) // section kind 0: a single body document
+	msg.Write(body)
+	if _, err := m.conn.Write(msg.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var header [4]int32
+	if err := binary.Read(m.conn, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header[3] != opMsg || header[0] < 21 {
+		return nil, fmt.Errorf("unexpected MongoDB reply opcode %d", header[3])
+	}
+	payload := make([]byte, header[0]-16)
+	if _, err := io.ReadFull(m.conn, payload); err != nil {
+		return nil, err
+	}
+	reply, _, err := unmarshalBSON(payload[5:])
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := reply["ok"].(float64); ok != 1 {
+		return reply, fmt.Errorf("MongoDB command %s failed: %v", cmd[0].Key, reply["errmsg"])
+	}
+	return reply, nil
+}
+
+// authSCRAM performs a SCRAM-SHA-256 conversation (RFC 7677).
+func (m *mongoConn) authSCRAM(db, user, password string) error {
+	nonceBytes := make([]byte, 24)
+	rand.Read(nonceBytes)
+	nonce := base64.StdEncoding.EncodeToString(nonceBytes)
+	escaped := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(user)
+	clientFirstBare := "n=" + escaped + ",r=" + nonce
+
+	reply, err := m.command(bsonDoc{
+		{"saslStart", int32(1)},
+		{"mechanism", "SCRAM-SHA-256"},
+		{"payload", []byte("n,," + clientFirstBare)},
+		{"autoAuthorize", int32(1)},
+		{"$db", db},
+	})
+	if err != nil {
+		return err
+	}
+	serverFirst, _ := reply["payload"].([]byte)
+	clientFinal, serverSignature, err := scramClientFinal(password, nonce, clientFirstBare, string(serverFirst))
+	if err != nil {
+		return err
+	}
+	id, _ := reply["conversationId"].(float64)
+	conversationID := int32(id)
+
+	reply, err = m.command(bsonDoc{
+		{"saslContinue", int32(1)},
+		{"conversationId", conversationID},
+		{"payload", []byte(clientFinal)},
+		{"$db", db},
+	})
+	if err != nil {
+		return err
+	}
+	serverFinal, _ := reply["payload"].([]byte)
+	if scramFields(string(serverFinal))["v"] != serverSignature {
+		return errors.New("server signature mismatch")
+	}
+	for done, _ := reply["done"].(bool); !done; done, _ = reply["done"].(bool) {
+		reply, err = m.command(bsonDoc{
+			{"saslContinue", int32(1)},
+			{"conversationId", conversationID},
+			{"payload", []byte{}},
+			{"$db", db},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scramClientFinal computes the client-final message and the expected
+// server signature from the server-first message.
+func scramClientFinal(password, nonce, clientFirstBare, serverFirst string) (clientFinal, serverSignature string, err error) {
+	fields := scramFields(serverFirst)
+	if !strings.HasPrefix(fields["r"], nonce) {
+		return "", "", errors.New("server nonce does not extend client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(fields["s"])
+	if err != nil {
+		return "", "", err
+	}
+	var iterations int
+	fmt.Sscan(fields["i"], &iterations)
+	if iterations <= 0 {
+		return "", "", errors.New("invalid SCRAM iteration count")
+	}
+
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", "", err
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	clientFinalNoProof := "c=biws,r=" + fields["r"]
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinalNoProof
+	signature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	serverKey := hmacSHA256(salted, "Server Key")
+	return clientFinalNoProof + ",p=" + base64.StdEncoding.EncodeToString(proof),
+		base64.StdEncoding.EncodeToString(hmacSHA256(serverKey, authMessage)), nil
+}
+
+// scramFields parses "k=v,k=v" SCRAM messages.
+func scramFields(msg string) map[string]string {
+	fields := map[string]string{}
+	for _, part := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// bsonDoc is an ordered BSON document; command documents are
+// order-sensitive.
+type bsonDoc []bsonElem
+
+type bsonElem struct {
+	Key   string
+	Value any
+}
+
+type bsonArray []any
+
+// structToBSON converts the JSON-tagged fields of a struct to a document.
+func structToBSON(v any) bsonDoc {
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	var doc bsonDoc
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		doc = append(doc, bsonElem{name, rv.Field(i).Interface()})
+	}
+	return doc
+}
+
+func (d bsonDoc) marshal() ([]byte, error) {
+	var b bytes.Buffer
+	b.Write([]byte{0, 0, 0, 0})
+	for _, e := range d {
+		if err := appendBSONValue(&b, e.Key, e.Value); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte(0)
+	out := b.Bytes()
+	binary.LittleEndian.PutUint32(out, uint32(len(out)))
+	return out, nil
+}
+
+func appendBSONValue(b *bytes.Buffer, key string, v any) error {
+	writeKey := func(kind byte) {
+		b.WriteByte(kind)
+		b.WriteString(key)
+		b.WriteByte(0)
+	}
+	switch v := v.(type) {
+	case float64:
+		writeKey(0x01)
+		binary.Write(b, binary.LittleEndian, math.Float64bits(v))
+	case string:
+		writeKey(0x02)
+		binary.Write(b, binary.LittleEndian, int32(len(v)+1))
+		b.WriteString(v)
+		b.WriteByte(0)
+	case bsonDoc:
+		writeKey(0x03)
+		data, err := v.marshal()
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	case bsonArray:
+		writeKey(0x04)
+		doc := make(bsonDoc, len(v))
+		for i, item := range v {
+			doc[i] = bsonElem{fmt.Sprint(i), item}
+		}
+		data, err := doc.marshal()
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	case []byte:
+		writeKey(0x05)
+		binary.Write(b, binary.LittleEndian, int32(len(v)))
+		b.WriteByte(0)
+		b.Write(v)
+	case bool:
+		writeKey(0x08)
+		if v {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case nil:
+		writeKey(0x0A)
+	case int32:
+		writeKey(0x10)
+		binary.Write(b, binary.LittleEndian, v)
+	case int:
+		writeKey(0x12)
+		binary.Write(b, binary.LittleEndian, int64(v))
+	case int64:
+		writeKey(0x12)
+		binary.Write(b, binary.LittleEndian, v)
+	default:
+		return fmt.Errorf("cannot encode %T as BSON", v)
+	}
+	return nil
+}
+
+// errBSONTruncated is returned for BSON values running past their
+// document.
+var errBSONTruncated = errors.New("truncated BSON document")
+
+// bsonFixedSize is the size of the fixed-width part of the values of each
+// BSON type unmarshalBSON decodes: the whole value, or its length prefix.
+var bsonFixedSize = map[byte]int{0x01: 8, 0x02: 4, 0x05: 5, 0x07: 12, 0x08: 1, 0x09: 8, 0x10: 4, 0x11: 8, 0x12: 8}
+
+// unmarshalBSON decodes the subset of BSON types that command replies use.
+// It returns the document and the number of bytes consumed.
+func unmarshalBSON(data []byte) (map[string]any, int, error) {
+	if len(data) < 5 {
+		return nil, 0, errors.New("short BSON document")
+	}
+	size := int(binary.LittleEndian.Uint32(data))
+	if size > len(data) || size < 5 || data[size-1] != 0 {
+		return nil, 0, errors.New("invalid BSON document length")
+	}
+	doc := map[string]any{}
+	pos := 4
+	for pos < size-1 {
+		kind := data[pos]
+		end := bytes.IndexByte(data[pos+1:size], 0)
+		if end < 0 {
+			return nil, 0, errors.New("unterminated BSON key")
+		}
+		key := string(data[pos+1 : pos+1+end])
+		pos += end + 2
+		if pos+bsonFixedSize[kind] > size-1 {
+			return nil, 0, errBSONTruncated
+		}
+		switch kind {
+		case 0x01:
+			doc[key] = math.Float64frombits(binary.LittleEndian.Uint64(data[pos:]))
+			pos += 8
+		case 0x02:
+			n := int(int32(binary.LittleEndian.Uint32(data[pos:])))
+			if n < 1 || pos+4+n > size-1 {
+				return nil, 0, errBSONTruncated
+			}
+			doc[key] = string(data[pos+4 : pos+4+n-1])
+			pos += 4 + n
+		case 0x03, 0x04:
+			sub, n, err := unmarshalBSON(data[pos : size-1])
+			if err != nil {
+				return nil, 0, err
+			}
+			if kind == 0x04 {
+				arr := make([]any, len(sub))
+				for i := range arr {
+					arr[i] = sub[fmt.Sprint(i)]
+				}
+				doc[key] = arr
+			} else {
+				doc[key] = sub
+			}
+			pos += n
+		case 0x05:
+			n := int(int32(binary.LittleEndian.Uint32(data[pos:])))
+			if n < 0 || pos+5+n > size-1 {
+				return nil, 0, errBSONTruncated
+			}
+			doc[key] = data[pos+5 : pos+5+n]
+			pos += 5 + n
+		case 0x07:
+			pos += 12 // ObjectId
+		case 0x08:
+			doc[key] = data[pos] == 1
+			pos++
+		case 0x09:
+			doc[key] = time.UnixMilli(int64(binary.LittleEndian.Uint64(data[pos:])))
+			pos += 8
+		case 0x0A:
+			doc[key] = nil
+		case 0x10:
+			// Replies mix int32 and double for numeric fields such as "ok",
+			// so numbers are normalized to float64.
+			doc[key] = float64(int32(binary.LittleEndian.Uint32(data[pos:])))
+			pos += 4
+		case 0x11, 0x12:
+			doc[key] = float64(int64(binary.LittleEndian.Uint64(data[pos:])))
+			pos += 8
+		default:
+			return nil, 0, fmt.Errorf("unsupported BSON type 0x%02x for %q", kind, key)
+		}
+	}
+	return doc, size, nil
+}
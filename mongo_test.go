@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The example document of bsonspec.org.
+var helloWorldBSON = []byte("\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00")
+
+func TestBSONKnownBytes(t *testing.T) {
+	got, err := bsonDoc{{"hello", "world"}}.marshal()
+	if err != nil || !bytes.Equal(got, helloWorldBSON) {
+		t.Fatalf("marshal = %q, %v; want %q", got, err, helloWorldBSON)
+	}
+	doc, n, err := unmarshalBSON(helloWorldBSON)
+	if err != nil || n != len(helloWorldBSON) || doc["hello"] != "world" {
+		t.Fatalf("unmarshal = %v, %d, %v", doc, n, err)
+	}
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	doc := bsonDoc{
+		{"double", 1.5},
+		{"string", "héllo"},
+		{"empty", ""},
+		{"doc", bsonDoc{{"n", int32(7)}}},
+		{"array", bsonArray{"a", int64(2), true}},
+		{"binary", []byte("n,,n=user")},
+		{"true", true},
+		{"false", false},
+		{"null", nil},
+		{"int32", int32(-3)},
+		{"int", 42},
+		{"int64", int64(1) << 40},
+	}
+	data, err := doc.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, n, err := unmarshalBSON(data)
+	if err != nil || n != len(data) {
+		t.Fatalf("unmarshal: %d of %d bytes, %v", n, len(data), err)
+	}
+	// Numbers come back as float64, as replies mix integer and double.
+	want := map[string]any{
+		"double": 1.5, "string": "héllo", "empty": "",
+		"doc":    map[string]any{"n": 7.0},
+		"array":  []any{"a", 2.0, true},
+		"binary": []byte("n,,n=user"), "true": true, "false": false, "null": nil,
+		"int32": -3.0, "int": 42.0, "int64": float64(int64(1) << 40),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %#v\nwant %#v", got, want)
+	}
+	if _, err := (bsonDoc{{"f", float32(1)}}).marshal(); err == nil {
+		t.Error("marshalling an unsupported type succeeded")
+	}
+}
+
+// Truncated or corrupt replies are errors, not panics.
+func TestBSONTruncated(t *testing.T) {
+	data, _ := bsonDoc{{"s", "a string"}, {"n", int64(5)}, {"d", bsonDoc{{"x", 1.0}}}}.marshal()
+	for i := range len(data) - 1 {
+		cut := append([]byte(nil), data[:i]...)
+		if len(cut) >= 4 {
+			// Claim the cut length, so the document passes the length check.
+			binary.LittleEndian.PutUint32(cut, uint32(len(cut)))
+		}
+		if _, _, err := unmarshalBSON(cut); err == nil {
+			t.Errorf("unmarshal of the first %d bytes succeeded", i)
+		}
+	}
+	bad := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(bad[7:], 1<<30) // the string's length
+	if _, _, err := unmarshalBSON(bad); err == nil {
+		t.Error("unmarshal with an oversized string length succeeded")
+	}
+}
+
+func TestStructToBSON(t *testing.T) {
+	doc := structToBSON(User{Login: "alice", Followers: 3})
+	if doc[0] != (bsonElem{"login", "alice"}) {
+		t.Fatalf("first field = %v", doc[0])
+	}
+	for _, e := range doc {
+		if e.Key == "followers" && e.Value != 3 {
+			t.Errorf("followers = %v", e.Value)
+		}
+	}
+}
+
+// The example conversation of RFC 7677, section 3.
+func TestSCRAMSHA256Vector(t *testing.T) {
+	const (
+		nonce           = "rOprNGfwEbeRWgbNEkqO"
+		clientFirstBare = "n=user,r=" + nonce
+		serverFirst     = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+		wantFinal       = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+		wantSignature   = "6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+	)
+	final, signature, err := scramClientFinal("pencil", nonce, clientFirstBare, serverFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final != wantFinal {
+		t.Errorf("client final = %s\nwant %s", final, wantFinal)
+	}
+	if signature != wantSignature {
+		t.Errorf("server signature = %s, want %s", signature, wantSignature)
+	}
+
+	for name, first := range map[string]string{
+		"foreign nonce": "r=other,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+		"bad salt":      "r=" + nonce + "x,s=!!,i=4096",
+		"no iterations": "r=" + nonce + "x,s=W22ZaJ0SNY7soEsUEjb6gQ==",
+	} {
+		if _, _, err := scramClientFinal("pencil", nonce, clientFirstBare, first); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+// fakeMongod answers one OP_MSG command on conn with reply and returns
+// the command.
+func fakeMongod(conn net.Conn, reply bsonDoc) (map[string]any, error) {
+	var header [4]int32
+	if err := binary.Read(conn, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, header[0]-16)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, err
+	}
+	cmd, _, err := unmarshalBSON(payload[5:])
+	if err != nil {
+		return nil, err
+	}
+	body, _ := reply.marshal()
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.LittleEndian, []int32{int32(16 + 5 + len(body)), 1, header[1], opMsg})
+	msg.Write([]byte{0, 0, 0, 0, 0})
+	msg.Write(body)
+	_, err = conn.Write(msg.Bytes())
+	return cmd, err
+}
+
+func TestMongoCommand(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	m := &mongoConn{conn: client}
+
+	got := make(chan map[string]any, 2)
+	go func() {
+		for _, reply := range []bsonDoc{{{"ok", 1.0}}, {{"ok", int32(0)}, {"errmsg", "no such command"}}} {
+			cmd, err := fakeMongod(server, reply)
+			if err != nil {
+				t.Error(err)
+			}
+			got <- cmd
+		}
+	}()
+	if _, err := m.command(bsonDoc{{"ping", int32(1)}, {"$db", "admin"}}); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if cmd := <-got; cmd["ping"] != 1.0 || cmd["$db"] != "admin" {
+		t.Errorf("server received %v", cmd)
+	}
+	if _, err := m.command(bsonDoc{{"bogus", int32(1)}}); err == nil {
+		t.Error("a reply with ok 0 was not an error")
+	}
+	<-got
+}
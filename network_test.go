@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeInfluence(t *testing.T) {
+	users := []User{{Login: "a"}, {Login: "b"}, {Login: "c"}, {Login: "d"}}
+	tests := []struct {
+		name  string
+		edges []edge
+		// rank is each user's expected PageRank; 0 skips the check.
+		rank   map[string]float64
+		top    string
+		degree map[string]float64
+	}{
+		{"cycle", []edge{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"d", "a"}},
+			map[string]float64{"a": 0.25, "b": 0.25, "c": 0.25, "d": 0.25}, "", map[string]float64{"a": 1.0 / 3}},
+		// b, c, and d follow a, who follows no one; a's rank is spread
+		// evenly, and self-follows and outsiders are ignored.
+		{"star", []edge{{"b", "a"}, {"c", "a"}, {"d", "a"}, {"a", "a"}, {"a", "zed"}, {"zed", "b"}},
+			nil, "a", map[string]float64{"a": 1, "b": 0}},
+		{"no edges", nil, map[string]float64{"a": 0.25, "d": 0.25}, "", map[string]float64{"a": 0}},
+	}
+	for _, tt := range tests {
+		got := computeInfluence(users, tt.edges, 0.85)
+		if len(got) != len(users) {
+			t.Fatalf("%s: %d results for %d users", tt.name, len(got), len(users))
+		}
+		sum := 0.0
+		for i, inf := range got {
+			sum += inf.PageRank
+			if want, ok := tt.rank[inf.Login]; ok && math.Abs(inf.PageRank-want) > 1e-6 {
+				t.Errorf("%s: %s PageRank %v, want %v", tt.name, inf.Login, inf.PageRank, want)
+			}
+			if want, ok := tt.degree[inf.Login]; ok && math.Abs(inf.Degree-want) > 1e-9 {
+				t.Errorf("%s: %s degree %v, want %v", tt.name, inf.Login, inf.Degree, want)
+			}
+			if i > 0 && inf.PageRank > got[i-1].PageRank {
+				t.Errorf("%s: not sorted by PageRank", tt.name)
+			}
+		}
+		if math.Abs(sum-1) > 1e-6 {
+			t.Errorf("%s: ranks sum to %v", tt.name, sum)
+		}
+		if tt.top != "" && got[0].Login != tt.top {
+			t.Errorf("%s: %s ranks first, want %s", tt.name, got[0].Login, tt.top)
+		}
+	}
+	if got := computeInfluence(nil, []edge{{"a", "b"}}, 0.85); len(got) != 0 {
+		t.Errorf("no users: %+v", got)
+	}
+}
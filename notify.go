@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// runSummary describes a finished run for notifications and the summary
+// file.
+type runSummary struct {
+	Started        time.Time     `json:"started"`
+	Duration       time.Duration `json:"-"`
+	Searched       int           `json:"searched"`
+	Users          int           `json:"users"`
+	Repos          int           `json:"repos"`
+	APICalls       int64         `json:"api_calls"`
+	DetailFailures int           `json:"detail_failures"`
+	RepoFailures   int           `json:"repo_failures"`
+	ExportFailures int           `json:"export_failures"`
+	StopReason     string        `json:"stop_reason,omitempty"`
+	Artifacts      []string      `json:"artifacts"`
+	Phases         []phaseStat   `json:"phases"`
+}
+
+func (s runSummary) text() string {
+	var b strings.Builder
+	status := "completed"
+	if s.StopReason != "" {
+		status = "stopped early (" + s.StopReason + ")"
+	}
+	fmt.Fprintf(&b, "GitHub scrape %s in %s\n", status, s.Duration.Round(time.Second))
+	fmt.Fprintf(&b, "Users: %d of %d found, repos: %d, API calls: %d\n", s.Users, s.Searched, s.Repos, s.APICalls)
+	if failures := s.DetailFailures + s.RepoFailures + s.ExportFailures; failures > 0 {
+		fmt.Fprintf(&b, "Failures: %d user details, %d repo lists, %d exports\n", s.DetailFailures, s.RepoFailures, s.ExportFailures)
+	}
+	if len(s.Phases) > 0 {
+		b.WriteString("Phases:\n")
+		for _, p := range s.Phases {
+			fmt.Fprintf(&b, "• %s: %s, %d API calls\n", p.Name, p.Duration.Round(time.Millisecond), p.APICalls)
+		}
+	}
+	if len(s.Artifacts) > 0 {
+		b.WriteString("Artifacts:\n")
+		for _, a := range s.Artifacts {
+			b.WriteString("• " + a + "\n")
+		}
+	}
+	return b.String()
+}
+
+// postWebhook sends the summary to a Slack or Discord incoming webhook.
+func postWebhook(ctx context.Context, webhook string, s runSummary) error {
+	return postWebhookText(ctx, webhook, s.text())
+}
+
+// postWebhookText posts text to a Slack or Discord incoming webhook,
+// choosing the payload format from the webhook host.
+func postWebhookText(ctx context.Context, webhook, text string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return err
+	}
+	field := "text"
+	if strings.HasSuffix(u.Hostname(), "discord.com") || strings.HasSuffix(u.Hostname(), "discordapp.com") {
+		field = "content"
+	}
+	body, err := json.Marshal(map[string]string{field: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(req, nil)
+}
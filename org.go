@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fetchOrgMembers seeds the user set with an organisation's members. Only
+// public members are visible unless the token belongs to a member.
+func (c *apiClient) fetchOrgMembers(ctx context.Context, org string) ([]User, error) {
+	return paginate[User](ctx, c, fmt.Sprintf("%s/orgs/%s/members?filter=all", c.baseURL, org), nil)
+}
+
+// orgAccount is an organisation account found among the searched users,
+// with the profile fields only organisations have.
+type orgAccount struct {
+	Login       string `json:"login"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Blog        string `json:"blog"`
+	Location    string `json:"location"`
+	Email       string `json:"email"`
+	IsVerified  bool   `json:"is_verified"`
+	PublicRepos int    `json:"public_repos"`
+	Followers   int    `json:"followers"`
+	CreatedAt   string `json:"created_at"`
+}
+
+var orgColumns = []string{"login", "name", "description", "blog", "location", "email", "is_verified", "public_repos", "followers", "created_at"}
+
+func orgRecord(o orgAccount) []string {
+	return []string{
+		o.Login, o.Name, o.Description, o.Blog, o.Location, o.Email, strconv.FormatBool(o.IsVerified),
+		strconv.Itoa(o.PublicRepos), strconv.Itoa(o.Followers), o.CreatedAt,
+	}
+}
+
+// splitOrgs separates organisation accounts, which the user search also
+// returns, from people.
+func splitOrgs(users []User) (people, orgs []User) {
+	for _, u := range users {
+		if u.Type == "Organization" {
+			orgs = append(orgs, u)
+		} else {
+			people = append(people, u)
+		}
+	}
+	return people, orgs
+}
+
+// fetchOrgAccounts fetches the organisation profiles of org accounts. An
+// organisation whose profile cannot be fetched keeps the fields its user
+// profile had.
+func (c *apiClient) fetchOrgAccounts(ctx context.Context, orgs []User) []orgAccount {
+	out := make([]orgAccount, len(orgs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, fetchConcurrency)
+	for i, u := range orgs {
+		out[i] = orgAccount{Login: u.Login, Name: u.Name, Location: u.Location, Email: u.Email,
+			PublicRepos: u.PublicRepos, Followers: u.Followers, CreatedAt: u.CreatedAt}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			body, err := c.getCached(ctx, fmt.Sprintf("%s/orgs/%s", c.baseURL, u.Login))
+			if err == nil {
+				var o orgAccount
+				if json.Unmarshal(body, &o) == nil && o.Login != "" {
+					out[i] = o
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// team is an organisation team with its members.
+type team struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Privacy     string `json:"privacy"`
+	Description string `json:"description"`
+	Parent      *struct {
+		Slug string `json:"slug"`
+	} `json:"parent"`
+	Members []string `json:"-"`
+}
+
+// fetchTeams lists the organisation's teams and their members. It needs a
+// token with the read:org scope.
+func (c *apiClient) fetchTeams(ctx context.Context, org string) ([]team, error) {
+	teams, err := paginate[team](ctx, c, fmt.Sprintf("%s/orgs/%s/teams", c.baseURL, org), nil)
+	if err != nil {
+		return teams, err
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(teams))
+	sem := make(chan struct{}, fetchConcurrency)
+	for i := range teams {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t *team, errp *error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			members, err := paginate[User](ctx, c, fmt.Sprintf("%s/orgs/%s/teams/%s/members", c.baseURL, org, t.Slug), nil)
+			*errp = err
+			for _, m := range members {
+				t.Members = append(t.Members, m.Login)
+			}
+		}(&teams[i], &errs[i])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return teams, err
+		}
+	}
+	return teams, nil
+}
+
+var teamColumns = []string{"slug", "name", "parent", "privacy", "members", "member_logins"}
+
+func teamRecord(t team) []string {
+	parent := ""
+	if t.Parent != nil {
+		parent = t.Parent.Slug
+	}
+	return []string{t.Slug, t.Name, parent, t.Privacy, strconv.Itoa(len(t.Members)), strings.Join(t.Members, ";")}
+}
+
+// teamsColumns returns the users.csv column listing each member's teams.
+func teamsColumns(teams []team) columnSet[User] {
+	byLogin := map[string][]string{}
+	for _, t := range teams {
+		for _, m := range t.Members {
+			byLogin[m] = append(byLogin[m], t.Slug)
+		}
+	}
+	for _, slugs := range byLogin {
+		slices.Sort(slugs)
+	}
+	return columnSet[User]{[]string{"teams"}, func(u User) []string {
+		return []string{strings.Join(byLogin[u.Login], ";")}
+	}}
+}
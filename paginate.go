@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listPageSize is the per_page sent to list endpoints, GitHub's maximum.
+const listPageSize = 100
+
+// paginate GETs every page of a list endpoint and collects the items of
+// pageItems. It returns the items read so far along with any error.
+func paginate[T any](ctx context.Context, c *apiClient, rawURL string, decode func([]byte) ([]T, error)) ([]T, error) {
+	var items []T
+	for item, err := range pageItems(ctx, c, rawURL, decode) {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// pageItems iterates over the items of a list endpoint as its pages
+// arrive, following the rel="next" Link header GitHub sends, so callers
+// can stop early without fetching the remaining pages. Each page is
+// decoded with decode, or as a JSON array when decode is nil. per_page is
+// set to 100 unless url sets it. An error is yielded once and ends the
+// iteration.
+func pageItems[T any](ctx context.Context, c *apiClient, rawURL string, decode func([]byte) ([]T, error)) iter.Seq2[T, error] {
+	if decode == nil {
+		decode = func(body []byte) ([]T, error) {
+			var batch []T
+			err := json.Unmarshal(body, &batch)
+			return batch, err
+		}
+	}
+	return func(yield func(T, error) bool) {
+		next, err := withPerPage(rawURL)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		for next != "" {
+			pageCtx, sp := startSpan(ctx, "list page", "url", next)
+			resp, err := c.getListPage(pageCtx, next)
+			var batch []T
+			if err == nil {
+				batch, err = decode(resp.body)
+			}
+			sp.finish(err)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range batch {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			next = nextPageURL(next, resp.header, len(batch))
+		}
+	}
+}
+
+// getListPage GETs one page of a list endpoint through the cache. When the
+// rate limit is hit it waits for the limit to reset and tries again.
+func (c *apiClient) getListPage(ctx context.Context, url string) (cachedResponse, error) {
+	resp, _, err := c.getOK(ctx, url)
+	return resp, err
+}
+
+// statusError is a response other than 200 OK.
+type statusError struct {
+	url    string
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("%s: %d %s", e.url, e.status, e.body) }
+
+// getOK GETs url through the cache as getPage does, waiting out rate
+// limits, and returns a *statusError for any response but 200 OK.
+func (c *apiClient) getOK(ctx context.Context, url string) (cachedResponse, bool, error) {
+	for {
+		resp, changed, err := c.getPage(ctx, url)
+		if err != nil {
+			return resp, false, err
+		}
+		if resp.status == http.StatusOK {
+			return resp, changed, nil
+		}
+		wait, limited := rateLimitWait(resp)
+		if !limited {
+			return resp, false, &statusError{url, resp.status, strings.TrimSpace(string(resp.body))}
+		}
+		fmt.Printf("Rate limited; retrying %s in %s\n", url, wait.Round(time.Second))
+		c.progress.rateLimited(time.Now().Add(wait))
+		select {
+		case <-ctx.Done():
+			return resp, false, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitWait reports whether resp was refused by a primary or
+// secondary rate limit and, if so, how long until it is worth retrying.
+func rateLimitWait(resp cachedResponse) (time.Duration, bool) {
+	if resp.status != http.StatusForbidden && resp.status != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if resp.header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Minute, true
+	}
+	return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+}
+
+// withPerPage sets per_page on rawURL unless it is already set.
+func withPerPage(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Has("per_page") {
+		return rawURL, nil
+	}
+	q.Set("per_page", strconv.Itoa(listPageSize))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// nextPageURL returns the URL of the page after current, or "" on the last
+// page. It follows the Link header; responses without one, such as cache
+// entries stored before links were kept, continue by page number while
+// pages come back full.
+func nextPageURL(current string, header http.Header, n int) string {
+	if link := header.Get("Link"); link != "" {
+		for part := range strings.SplitSeq(link, ",") {
+			target, params, _ := strings.Cut(part, ";")
+			if strings.Contains(params, `rel="next"`) {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+		return ""
+	}
+	u, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if n == 0 || n < perPage {
+		return ""
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	q.Set("page", strconv.Itoa(max(page, 1)+1))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// partitionFileName turns a partition value into a safe file name.
+func partitionFileName(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "_unknown"
+	}
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, value)
+	if name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name
+}
+
+// writePartitions writes one CSV per distinct value of the partition key:
+// repos/<language>.csv for "language", users/<company>.csv or
+// users/<location>.csv for the user fields. It returns the files written.
+func writePartitions(by string, users []User, repos []Repo) ([]string, error) {
+	switch by {
+	case "language":
+		return partitionCSV("repos", repoColumns, repos, repoRecord, func(r Repo) string { return r.Language })
+	case "company":
+		return partitionCSV("users", userColumns, users, userRecord, func(u User) string { return u.Company })
+	case "location":
+		return partitionCSV("users", userColumns, users, userRecord, func(u User) string { return u.Location })
+	}
+	return nil, fmt.Errorf("unknown partition key %q (want language, company, or location)", by)
+}
+
+func partitionCSV[T any](dir string, header []string, items []T, record func(T) []string, key func(T) string) ([]string, error) {
+	groups := map[string][]T{}
+	// Values differing only in case would collide on case-insensitive file
+	// systems, so they share the file named after the first one seen.
+	canonical := map[string]string{}
+	for _, item := range items {
+		name := partitionFileName(key(item))
+		if first, ok := canonical[strings.ToLower(name)]; ok {
+			name = first
+		} else {
+			canonical[strings.ToLower(name)] = name
+		}
+		groups[name] = append(groups[name], item)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []string
+	for _, name := range names {
+		path := filepath.Join(dir, name+".csv")
+		if err := writeRecordsCSV(path, header, groups[name], record); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
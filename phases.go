@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// phaseStat is the wall time and API calls spent in one phase of a run.
+type phaseStat struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"-"`
+	APICalls int64         `json:"api_calls"`
+}
+
+func (p phaseStat) MarshalJSON() ([]byte, error) {
+	type plain phaseStat
+	return json.Marshal(struct {
+		plain
+		Seconds float64 `json:"seconds"`
+	}{plain(p), p.Duration.Seconds()})
+}
+
+// phaseClock attributes wall time and API calls to the phase the run is
+// in. Entering a phase again adds to its earlier totals, so interleaved
+// work such as enrichments before and after the repos phase is summed.
+type phaseClock struct {
+	client *apiClient
+	// budget and console, when set, are told which phase the run is in.
+	budget  *phaseBudget
+	console *console
+	stats   []phaseStat
+	current int
+	since   time.Time
+	calls   int64
+	// name is the phase last entered, for request hooks.
+	name atomic.Value
+}
+
+func newPhaseClock(c *apiClient) *phaseClock {
+	return &phaseClock{client: c, current: -1}
+}
+
+// enter ends the current phase and starts name.
+func (p *phaseClock) enter(name string) {
+	p.stop()
+	p.current = len(p.stats)
+	for i, s := range p.stats {
+		if s.Name == name {
+			p.current = i
+		}
+	}
+	if p.current == len(p.stats) {
+		p.stats = append(p.stats, phaseStat{Name: name})
+	}
+	p.since, p.calls = time.Now(), p.client.callCount()
+	p.name.Store(name)
+	p.budget.enter(name)
+	p.console.phase(name)
+}
+
+// phase returns the phase last entered. It may be called concurrently
+// with enter.
+func (p *phaseClock) phase() string {
+	name, _ := p.name.Load().(string)
+	return name
+}
+
+// stop ends the current phase, if any.
+func (p *phaseClock) stop() {
+	if p.current < 0 {
+		return
+	}
+	p.stats[p.current].Duration += time.Since(p.since)
+	p.stats[p.current].APICalls += p.client.callCount() - p.calls
+	p.current = -1
+}
+
+// runPhases are the phases of a scrape run, as named in the run summary.
+var runPhases = []string{"search", "details", "enrichments", "repos", "dependencies", "edges", "export"}
+
+// errPhaseBudgetExhausted is returned for requests made once the current
+// phase has spent its share of the API budget.
+var errPhaseBudgetExhausted = errors.New("api call budget of this phase exhausted")
+
+// phaseBudget caps the share of the API budget each phase may spend, so
+// enrichments cannot starve the core pipeline. A phase that reaches its
+// cap has its remaining requests refused; the run then moves on to the
+// next phase. Phases without a share are not capped.
+type phaseBudget struct {
+	shares map[string]float64
+
+	mu    sync.Mutex
+	phase string
+	used  map[string]int64
+	// total is the budget the shares are of: --max-api-calls, or else the
+	// rate limit reported by the API.
+	total  int64
+	warned map[string]bool
+}
+
+// parsePhaseBudget parses a comma-separated list of phase shares, e.g.
+// "search=20%,details=40%,repos=40%". The shares may not add up to more
+// than 100%.
+func parsePhaseBudget(s string) (map[string]float64, error) {
+	shares := map[string]float64{}
+	sum := 0.0
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		phase, pct, ok := strings.Cut(field, "=")
+		if !ok || !slices.Contains(runPhases, phase) {
+			return nil, fmt.Errorf("invalid phase budget %q (want phase=percent for a phase of %s)", field, strings.Join(runPhases, ", "))
+		}
+		v, err := strconv.ParseFloat(strings.TrimSuffix(pct, "%"), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid share %q for phase %s", pct, phase)
+		}
+		shares[phase] = v
+		sum += v
+	}
+	if sum > 100 {
+		return nil, fmt.Errorf("phase budget shares add up to %g%%, more than 100%%", sum)
+	}
+	return shares, nil
+}
+
+// setPhaseBudget caps the calls of each phase to its share of the client's
+// --max-api-calls or, without one, of the rate limit, which is taken to be
+// GitHub's 5000 an hour until a response reports it.
+func (c *apiClient) setPhaseBudget(shares map[string]float64) *phaseBudget {
+	b := &phaseBudget{shares: shares, used: map[string]int64{}, total: c.maxCalls, warned: map[string]bool{}}
+	fixed := c.maxCalls > 0
+	if !fixed {
+		b.total = 5000
+	}
+	c.addRequestHook(func(req *http.Request) error {
+		return b.acquire()
+	})
+	c.addResponseHook(func(req *http.Request, start time.Time, resp *http.Response, err error) {
+		if fixed || resp == nil {
+			return
+		}
+		if limit, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Limit"), 10, 64); err == nil && limit > 0 {
+			b.mu.Lock()
+			b.total = limit
+			b.mu.Unlock()
+		}
+	})
+	return b
+}
+
+func (b *phaseBudget) enter(phase string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.phase = phase
+}
+
+// acquire counts a request against the current phase, or refuses it once
+// the phase has spent its share.
+func (b *phaseBudget) acquire() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	share, ok := b.shares[b.phase]
+	if ok && float64(b.used[b.phase]) >= share/100*float64(b.total) {
+		if !b.warned[b.phase] {
+			b.warned[b.phase] = true
+			fmt.Printf("Phase %s reached its API budget of %g%% (%d calls); skipping its remaining requests\n", b.phase, share, b.used[b.phase])
+		}
+		return errPhaseBudgetExhausted
+	}
+	b.used[b.phase]++
+	return nil
+}
+
+// saveRunSummary writes the summary as JSON, with durations in seconds.
+func saveRunSummary(path string, s runSummary) error {
+	data, err := json.MarshalIndent(struct {
+		runSummary
+		Seconds float64 `json:"duration_seconds"`
+	}{s, s.Duration.Seconds()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParsePhaseBudget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"", map[string]float64{}, false},
+		{"search=20%, details=40%,repos=40", map[string]float64{"search": 20, "details": 40, "repos": 40}, false},
+		{"search=60%,repos=50%", nil, true},
+		{"crawl=10%", nil, true},
+		{"search", nil, true},
+		{"search=-5%", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePhaseBudget(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePhaseBudget(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parsePhaseBudget(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("parsePhaseBudget(%q)[%s] = %g, want %g", tt.in, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestPhaseBudgetAcquire(t *testing.T) {
+	b := &phaseBudget{shares: map[string]float64{"search": 10}, used: map[string]int64{}, total: 50, warned: map[string]bool{}}
+	b.enter("search")
+	for i := range 5 {
+		if err := b.acquire(); err != nil {
+			t.Fatalf("call %d refused: %v", i+1, err)
+		}
+	}
+	if err := b.acquire(); !errors.Is(err, errPhaseBudgetExhausted) {
+		t.Fatalf("call past the share: err = %v, want errPhaseBudgetExhausted", err)
+	}
+	// Phases without a share are not capped.
+	b.enter("details")
+	for range 100 {
+		if err := b.acquire(); err != nil {
+			t.Fatalf("uncapped phase refused a call: %v", err)
+		}
+	}
+}
+
+// A search that spends its share of the budget ends the search phase, not
+// the run.
+func TestSearchPhaseBudgetContinues(t *testing.T) {
+	srv := fakeGitHub(300, 1, 0)
+	defer srv.Close()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	runScrape([]string{"--token", "x", "--api-url", srv.URL, "--console", "plain",
+		"--max-api-calls", "10000", "--phase-budget", "search=0.01%"})
+
+	f, err := os.Open("users.csv")
+	if err != nil {
+		t.Fatalf("no users.csv: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 2 {
+		t.Fatalf("users.csv has %d rows, want the users of the first search page", len(rows))
+	}
+	if len(rows) > 101 {
+		t.Fatalf("users.csv has %d rows; the search went past its share", len(rows))
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// pinyinTable maps Han characters to their usual Mandarin reading, in
+// pinyin without tone marks.
+type pinyinTable map[rune]string
+
+// toneless maps the pinyin vowels with tone marks, and ü, to plain ASCII.
+var toneless = strings.NewReplacer(
+	"ā", "a", "á", "a", "ǎ", "a", "à", "a",
+	"ē", "e", "é", "e", "ě", "e", "è", "e", "ê", "e",
+	"ī", "i", "í", "i", "ǐ", "i", "ì", "i",
+	"ō", "o", "ó", "o", "ǒ", "o", "ò", "o",
+	"ū", "u", "ú", "u", "ǔ", "u", "ù", "u",
+	"ǖ", "u", "ǘ", "u", "ǚ", "u", "ǜ", "u", "ü", "u",
+	"ń", "n", "ň", "n", "ǹ", "n", "ḿ", "m",
+)
+
+// loadPinyinTable reads the kMandarin readings of Unihan_Readings.txt from
+// the Unicode Character Database, lines such as
+//
+//	U+5F20	kMandarin	zhāng
+//
+// taking the first reading of characters that have several.
+func loadPinyinTable(path string) (pinyinTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t := pinyinTable{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 || fields[1] != "kMandarin" {
+			continue
+		}
+		cp, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "U+"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad code point %q", path, fields[0])
+		}
+		if reading, _, _ := strings.Cut(fields[2], " "); reading != "" {
+			t[rune(cp)] = toneless.Replace(reading)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(t) == 0 {
+		return nil, fmt.Errorf("%s: no kMandarin readings", path)
+	}
+	return t, nil
+}
+
+// transliterate spells the Han characters of name in pinyin, one
+// capitalised syllable each, e.g. "Zhang Wei" for 张伟, keeping the rest
+// of the name as it is. It returns "" for names without Han characters.
+func (t pinyinTable) transliterate(name string) string {
+	var b strings.Builder
+	found := false
+	// A syllable is set off by a space from letters and digits next to it.
+	prevWord, prevHan := false, false
+	for _, r := range name {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		syllable, ok := t[r]
+		if (ok && prevWord) || (!ok && word && prevHan) {
+			b.WriteByte(' ')
+		}
+		if ok {
+			found = true
+			b.WriteString(strings.ToUpper(syllable[:1]) + syllable[1:])
+		} else {
+			b.WriteRune(r)
+		}
+		prevWord, prevHan = word, ok
+	}
+	if !found {
+		return ""
+	}
+	return b.String()
+}
+
+// pinyinColumns returns the users.csv column with the pinyin of each
+// user's name, for sorting Chinese names in tools that order by Latin
+// letters.
+func pinyinColumns(t pinyinTable) columnSet[User] {
+	return columnSet[User]{[]string{"name_pinyin"}, func(u User) []string {
+		return []string{t.transliterate(u.Name)}
+	}}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+// exitPolicyFailed is the exit status used when --fail-on-errors or
+// --fail-under-users finds the run wanting.
+const exitPolicyFailed = 5
+
+// policyViolations returns why a run with summary s fails the exit-status
+// policies of o.
+func (o scrapeOptions) policyViolations(s runSummary) []string {
+	var reasons []string
+	if o.failOnErrors {
+		if n := s.DetailFailures + s.RepoFailures + s.ExportFailures; n > 0 {
+			reasons = append(reasons, fmt.Sprintf("--fail-on-errors: %d users lacked details, %d lacked repos, and %d exports failed", s.DetailFailures, s.RepoFailures, s.ExportFailures))
+		}
+	}
+	if o.failUnderUsers > 0 && s.Users < o.failUnderUsers {
+		reasons = append(reasons, fmt.Sprintf("--fail-under-users: only %d users, fewer than %d", s.Users, o.failUnderUsers))
+	}
+	return reasons
+}
+
+// policyFailed prints each reason the run fails, as a warning with
+// --warn-only, and reports whether the process should fail.
+func (o scrapeOptions) policyFailed(reasons ...string) bool {
+	for _, r := range reasons {
+		if o.warnOnly {
+			fmt.Println("Warning:", r)
+		} else {
+			fmt.Println("Error:", r)
+		}
+	}
+	return len(reasons) > 0 && !o.warnOnly
+}
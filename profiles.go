@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"text/template"
+)
+
+// profileTemplate is the built-in per-candidate Markdown profile.
+const profileTemplate = `# {{or .Name .Login}}
+
+[@{{.Login}}](https://github.com/{{.Login}})
+{{- with .Bio}}
+
+> {{md .}}
+{{- end}}
+
+| | |
+|---|---|
+| Company | {{md .Company}} |
+| Location | {{md .Location}} |
+| Email | {{md .Email}} |
+| Hireable | {{if .Hireable}}yes{{else}}no{{end}} |
+| Followers | {{.Followers}} |
+| Following | {{.Following}} |
+| Public repos | {{.PublicRepos}} |
+| Joined | {{date .CreatedAt}} |
+
+## Languages
+
+{{with languages .Repos}}{{join . ", "}}{{else}}None recorded.{{end}}
+
+## Top repositories
+{{with topRepos topN .Repos}}
+| Repository | Language | Stars | Created |
+|---|---|---:|---|
+{{- range .}}
+| [{{md .FullName}}](https://github.com/{{.FullName}}) | {{md .Language}} | {{.StargazersCount}} | {{date .CreatedAt}} |
+{{- end}}
+{{else}}
+No public repositories recorded.
+{{end}}
+## Activity
+
+- Total stars across repositories: {{stars .Repos}}
+- Most recent repository created: {{with lastCreated .Repos}}{{date .}}{{else}}n/a{{end}}
+`
+
+func runProfiles(args []string) int {
+	fs := flag.NewFlagSet("profiles", flag.ExitOnError)
+	usersPath := fs.String("users", "users.csv", "users CSV")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV")
+	outDir := fs.String("out-dir", "profiles", "directory for the profile files")
+	top := fs.Int("top", 5, "number of top repositories per profile")
+	tmplPath := fs.String("template", "", "use this Go template instead of the built-in profile")
+	fs.Parse(args)
+
+	users, err := loadUsersCSV(*usersPath)
+	if err != nil {
+		fmt.Println("Error loading users:", err)
+		return 1
+	}
+	repos, err := loadReposCSV(*reposPath)
+	if err != nil {
+		fmt.Println("Error loading repos:", err)
+		return 1
+	}
+
+	tmpl := template.New("profile").Funcs(templateFuncs).Funcs(template.FuncMap{
+		"topN": func() int { return *top },
+	})
+	if *tmplPath != "" {
+		tmpl, err = tmpl.ParseFiles(*tmplPath)
+		if err == nil {
+			tmpl = tmpl.Lookup(filepath.Base(*tmplPath))
+		}
+	} else {
+		tmpl, err = tmpl.Parse(profileTemplate)
+	}
+	if err != nil {
+		fmt.Println("Error loading template:", err)
+		return 1
+	}
+
+	files, err := renderProfiles(tmpl, filepath.Join(*outDir, "{{.Login}}.md"), buildProfiles(users, repos))
+	if err != nil {
+		fmt.Println("Error writing profiles:", err)
+		return 1
+	}
+	fmt.Printf("Wrote %d profiles to %s\n", len(files), *outDir)
+	return 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// progress is a snapshot of how far a run has got, for applications that
+// embed the scraper and render their own progress display.
+type progress struct {
+	Phase string
+	Done  int
+	// Total is 0 when it is not known in advance, as in the search phase.
+	Total int
+	// RateLimitRemaining is the API calls left in the rate limit window,
+	// or -1 until a response has said.
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+	// RateLimited is set while the client waits for the limit to reset.
+	RateLimited bool
+}
+
+// progressReporter passes progress updates to a callback. A nil reporter
+// reports nothing.
+type progressReporter struct {
+	fn   func(progress)
+	mu   sync.Mutex
+	last progress
+}
+
+// setProgress registers fn to be called as each phase advances and when
+// the client is held up by the rate limit. fn is never called
+// concurrently.
+func (c *apiClient) setProgress(fn func(progress)) {
+	c.progress = &progressReporter{fn: fn, last: progress{RateLimitRemaining: -1}}
+	c.addResponseHook(func(req *http.Request, start time.Time, resp *http.Response, err error) {
+		if resp != nil {
+			c.progress.observe(resp.Header)
+		}
+	})
+}
+
+// report records that done of total items of phase are finished.
+func (p *progressReporter) report(phase string, done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last.Phase, p.last.Done, p.last.Total, p.last.RateLimited = phase, done, total, false
+	p.fn(p.last)
+}
+
+// rateLimited reports that requests are paused until the limit resets at
+// until.
+func (p *progressReporter) rateLimited(until time.Time) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last.RateLimited, p.last.RateLimitRemaining, p.last.RateLimitReset = true, 0, until
+	p.fn(p.last)
+}
+
+// observe keeps the rate limit state from a response's headers.
+func (p *progressReporter) observe(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last.RateLimitRemaining = remaining
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		p.last.RateLimitReset = time.Unix(reset, 0)
+	}
+}
+
+// printProgress is the --progress callback: it redraws a status line on
+// stderr.
+func printProgress(p progress) {
+	line := fmt.Sprintf("%s: %d", p.Phase, p.Done)
+	if p.Total > 0 {
+		line += fmt.Sprintf("/%d", p.Total)
+	}
+	switch {
+	case p.RateLimited:
+		line += fmt.Sprintf(", rate limited until %s", p.RateLimitReset.Format(time.TimeOnly))
+	case p.RateLimitRemaining >= 0:
+		line += fmt.Sprintf(", %d calls left", p.RateLimitRemaining)
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+	if p.Total > 0 && p.Done == p.Total {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// publishTarget is a parsed --publish value: "gist" or "release:owner/repo".
+type publishTarget struct {
+	kind string
+	repo string
+}
+
+func parsePublishTarget(s string) (publishTarget, error) {
+	kind, repo, _ := strings.Cut(s, ":")
+	switch {
+	case kind == "gist" && repo == "":
+		return publishTarget{kind: kind}, nil
+	case kind == "release" && strings.Count(repo, "/") == 1:
+		return publishTarget{kind: kind, repo: repo}, nil
+	}
+	return publishTarget{}, fmt.Errorf("--publish must be gist or release:owner/repo, got %q", s)
+}
+
+// publish uploads the files back to GitHub and returns the URL of the gist
+// or release.
+func (c *apiClient) publish(ctx context.Context, t publishTarget, files []string, s runSummary) (string, error) {
+	switch t.kind {
+	case "gist":
+		return c.publishGist(ctx, files, s)
+	case "release":
+		return c.publishRelease(ctx, t.repo, files, s)
+	}
+	return "", errors.New("unknown publish target")
+}
+
+// publishName is the name of an output file in a gist or among a
+// release's assets, which cannot hold directories: its artifact name with
+// each '/' replaced, so partitions keep their directory in the name.
+func publishName(file string) string {
+	return strings.ReplaceAll(artifactName(file), "/", "_")
+}
+
+// publishGist creates a secret gist of the files. Gists hold text only, so
+// compressed or other binary files are refused; releases take them.
+func (c *apiClient) publishGist(ctx context.Context, files []string, s runSummary) (string, error) {
+	content := map[string]any{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", fmt.Errorf("%s is binary and cannot be published to a gist; use --publish release:owner/repo", f)
+		}
+		name := publishName(f)
+		if _, dup := content[name]; dup {
+			return "", fmt.Errorf("two outputs would both be named %s in the gist", name)
+		}
+		content[name] = map[string]string{"content": string(data)}
+	}
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := c.sendJSON(ctx, "POST", c.baseURL+"/gists", map[string]any{
+		"description": fmt.Sprintf("GitHub scrape %s: %d users, %d repos", s.Started.UTC().Format("2006-01-02 15:04"), s.Users, s.Repos),
+		"public":      false,
+		"files":       content,
+	}, &gist)
+	return gist.HTMLURL, err
+}
+
+func (c *apiClient) publishRelease(ctx context.Context, repo string, files []string, s runSummary) (string, error) {
+	stamp := s.Started.UTC().Format("20060102T150405Z")
+	var release struct {
+		ID        int64  `json:"id"`
+		HTMLURL   string `json:"html_url"`
+		UploadURL string `json:"upload_url"`
+	}
+	err := c.sendJSON(ctx, "POST", fmt.Sprintf("%s/repos/%s/releases", c.baseURL, repo), map[string]any{
+		"tag_name": "tds-" + stamp,
+		"name":     "Scrape " + stamp,
+		"body":     s.text(),
+	}, &release)
+	if err != nil {
+		return "", err
+	}
+
+	// upload_url is a URI template such as ".../assets{?name,label}".
+	uploadURL, _, _ := strings.Cut(release.UploadURL, "{")
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return release.HTMLURL, err
+		}
+		resp, err := c.do(ctx, "POST", uploadURL+"?name="+url.QueryEscape(publishName(f)), bytes.NewReader(data), "application/octet-stream")
+		if err != nil {
+			return release.HTMLURL, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return release.HTMLURL, fmt.Errorf("uploading %s: %s", f, resp.Status)
+		}
+	}
+	return release.HTMLURL, nil
+}
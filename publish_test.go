@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePublishTarget(t *testing.T) {
+	for in, want := range map[string]publishTarget{
+		"gist":                 {kind: "gist"},
+		"release:octo/dataset": {kind: "release", repo: "octo/dataset"},
+	} {
+		if got, err := parsePublishTarget(in); err != nil || got != want {
+			t.Errorf("parsePublishTarget(%q) = %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "gist:x", "release:octo", "release:a/b/c", "pages"} {
+		if _, err := parsePublishTarget(in); err == nil {
+			t.Errorf("parsePublishTarget(%q) succeeded", in)
+		}
+	}
+}
+
+func TestPublishGist(t *testing.T) {
+	var files map[string]map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Files map[string]map[string]string `json:"files"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		files = body.Files
+		w.Write([]byte(`{"html_url":"https://gist.example/1"}`))
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	os.Mkdir("users", 0o755)
+	os.WriteFile("users.csv", []byte("login\nalice\n"), 0o644)
+	os.WriteFile("users/users.csv", []byte("login\nbob\n"), 0o644)
+	os.WriteFile("users.csv.gz", []byte{0x1f, 0x8b, 0x08, 0x00, 0xff}, 0o644)
+	ctx := context.Background()
+	s := runSummary{Started: time.Now()}
+
+	// A partition named users keeps its directory in the name.
+	url, err := c.publishGist(ctx, []string{"users.csv", "users/users.csv"}, s)
+	if err != nil || url != "https://gist.example/1" {
+		t.Fatalf("publishGist = %q, %v", url, err)
+	}
+	if files["users.csv"]["content"] != "login\nalice\n" || files["users_users.csv"]["content"] != "login\nbob\n" {
+		t.Errorf("gist files = %v", files)
+	}
+
+	files = nil
+	if _, err := c.publishGist(ctx, []string{"users.csv", "users.csv.gz"}, s); err == nil || !strings.Contains(err.Error(), "release") || files != nil {
+		t.Errorf("publishing a gzip file: err %v, sent %v", err, files)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// queryFormats maps --format values of tds query to duckdb CLI flags.
+var queryFormats = map[string]string{"box": "-box", "csv": "-csv", "json": "-json", "markdown": "-markdown"}
+
+// readOnlyQuery matches the statements tds query runs: a single query
+// that reads, such as SELECT, WITH, or DESCRIBE.
+var readOnlyQuery = regexp.MustCompile(`(?is)^\s*(SELECT|WITH|FROM|VALUES|DESCRIBE|SHOW|SUMMARIZE|EXPLAIN|TABLE)\b[^;]*;?\s*$`)
+
+// quoteSQL quotes s as an SQL string literal.
+func quoteSQL(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// queryScript returns the SQL that runs sql over the CSVs as users and
+// repositories views, for when there is no database.
+func queryScript(sql, usersPath, reposPath string) (string, error) {
+	var b strings.Builder
+	found := false
+	for _, t := range []struct{ name, path string }{{"users", usersPath}, {"repositories", reposPath}} {
+		if _, err := os.Stat(t.path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		found = true
+		fmt.Fprintf(&b, "CREATE VIEW %s AS SELECT * FROM read_csv(%s, header = true, auto_detect = true);\n", t.name, quoteSQL(t.path))
+	}
+	if !found {
+		return "", fmt.Errorf("neither %s nor %s exists; pass --db or run a scrape first", usersPath, reposPath)
+	}
+	return b.String() + strings.TrimRight(strings.TrimSpace(sql), ";") + ";\n", nil
+}
+
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	db := fs.String("db", "", "DuckDB database written by a scrape with --duckdb (default: query users.csv and repositories.csv)")
+	usersPath := fs.String("users", "users.csv", "users CSV, queried as the users table without --db")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV, queried as the repositories table without --db")
+	format := fs.String("format", "box", "output format: box, csv, json, or markdown")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: tds query [flags] "SELECT login, followers FROM users ORDER BY followers DESC LIMIT 10"`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	sql := fs.Arg(0)
+	if !readOnlyQuery.MatchString(sql) {
+		fmt.Println("Error: tds query runs a single read-only statement, such as SELECT or WITH")
+		return 2
+	}
+	outFlag, ok := queryFormats[*format]
+	if !ok {
+		fmt.Println("Error: --format must be box, csv, json, or markdown")
+		return 2
+	}
+	if _, err := exec.LookPath("duckdb"); err != nil {
+		fmt.Println("Error: tds query needs the duckdb CLI on PATH; see https://duckdb.org/docs/installation")
+		return 1
+	}
+
+	var cmd *exec.Cmd
+	if *db != "" {
+		if _, err := os.Stat(*db); err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		cmd = exec.Command("duckdb", "-readonly", outFlag, *db)
+		cmd.Stdin = strings.NewReader(strings.TrimRight(strings.TrimSpace(sql), ";") + ";\n")
+	} else {
+		script, err := queryScript(sql, *usersPath, *reposPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return 1
+		}
+		cmd = exec.Command("duckdb", outFlag)
+		cmd.Stdin = strings.NewReader(script)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return 1
+		}
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// redaction names the exported columns --redact blanks or hashes. A column
+// maps to true when its values are hashed rather than blanked.
+type redaction map[string]bool
+
+// parseRedaction parses a comma-separated list of columns, each optionally
+// suffixed with ":hash", e.g. "email:hash,bio".
+func parseRedaction(s string) (redaction, error) {
+	r := redaction{}
+	for _, field := range strings.Split(s, ",") {
+		column, mode, _ := strings.Cut(strings.TrimSpace(field), ":")
+		if column == "" {
+			continue
+		}
+		switch mode {
+		case "", "blank":
+			r[column] = false
+		case "hash":
+			r[column] = true
+		default:
+			return nil, fmt.Errorf("invalid --redact mode %q for %s (want blank or hash)", mode, column)
+		}
+	}
+	return r, nil
+}
+
+// value redacts one value of column. Hashes are the first 16 hex digits of
+// the value's SHA-256, so equal values still match across rows and files.
+func (r redaction) value(column, v string) string {
+	hash, ok := r[column]
+	switch {
+	case !ok:
+		return v
+	case !hash || v == "":
+		return ""
+	}
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:8])
+}
+
+// redactItem redacts the JSON-tagged fields of a User or Repo. Non-string
+// fields are zeroed whether blanked or hashed.
+func redactItem[T any](r redaction, item T) T {
+	if len(r) == 0 {
+		return item
+	}
+	v := reflect.ValueOf(&item).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if _, ok := r[name]; !ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.String {
+			f.SetString(r.value(name, f.String()))
+		} else {
+			f.SetZero()
+		}
+	}
+	return item
+}
+
+// redactItems returns a redacted copy of items, or items itself when
+// nothing is redacted.
+func redactItems[T any](r redaction, items []T) []T {
+	if len(r) == 0 {
+		return items
+	}
+	out := make([]T, len(items))
+	for i, item := range items {
+		out[i] = redactItem(r, item)
+	}
+	return out
+}
+
+// redactColumns wraps extra column sets so their values are redacted too.
+// The sets must be given the unredacted item: most look it up by login or
+// full_name.
+func redactColumns[T any](r redaction, sets []columnSet[T]) []columnSet[T] {
+	if len(r) == 0 {
+		return sets
+	}
+	out := make([]columnSet[T], len(sets))
+	for i, x := range sets {
+		out[i] = columnSet[T]{x.columns, func(item T) []string {
+			record := x.record(item)
+			for j, c := range x.columns {
+				record[j] = r.value(c, record[j])
+			}
+			return record
+		}}
+	}
+	return out
+}
+
+// unmatched returns the redacted columns that are in none of headers, so
+// a misspelt column is reported rather than silently exported.
+func (r redaction) unmatched(headers ...[]string) []string {
+	var out []string
+	for column := range r {
+		found := false
+		for _, h := range headers {
+			found = found || slices.Contains(h, column)
+		}
+		if !found {
+			out = append(out, column)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
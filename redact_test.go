@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseRedaction(t *testing.T) {
+	got, err := parseRedaction("email, login:hash,bio:blank,,")
+	if want := (redaction{"email": false, "login": true, "bio": false}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRedaction = %v, %v; want %v", got, err, want)
+	}
+	if _, err := parseRedaction("email:rot13"); err == nil {
+		t.Error("an unknown mode parsed")
+	}
+}
+
+// Extra columns keyed by login keep their values when the login itself is
+// hashed.
+func TestSaveUsersToCSVRedacted(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	red := redaction{"login": true, "email": false, "company_hq": true}
+	matched := map[string]string{"alice": "q1;q2"}
+	extras := []columnSet[User]{
+		{[]string{"matched_queries"}, func(u User) []string { return []string{matched[u.Login]} }},
+		{[]string{"company_hq"}, func(u User) []string { return []string{u.Company + " HQ"} }},
+	}
+	users := []User{{Login: "alice", Email: "a@example.com", Company: "Acme", Followers: 3}}
+	if err := saveUsersToCSV(users, red, redactColumns(red, extras)); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.Open("users.csv")
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("users.csv: %v, %v", rows, err)
+	}
+	row := map[string]string{}
+	for i, c := range rows[0] {
+		row[c] = rows[1][i]
+	}
+	want := map[string]string{
+		"login": red.value("login", "alice"), "email": "", "company": "Acme", "followers": "3",
+		"matched_queries": "q1;q2", "company_hq": red.value("company_hq", "Acme HQ"),
+	}
+	for c, v := range want {
+		if row[c] != v {
+			t.Errorf("%s = %q, want %q", c, row[c], v)
+		}
+	}
+	if users[0].Login != "alice" {
+		t.Error("saving redacted the caller's users")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	format := fs.String("format", "md", "report format: md or html")
+	usersPath := fs.String("users", "users.csv", "users CSV to report on")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV to report on")
+	top := fs.Int("top", 20, "number of rows in the top users and repos tables")
+	out := fs.String("out", "", "write the report to this file instead of stdout")
+	languageMapPath := fs.String("language-map", "", "YAML file renaming language labels before they are counted, e.g. \"HTML: HTML+CSS\"")
+	fs.Parse(args)
+
+	if *format != "md" && *format != "html" {
+		fmt.Println("Unknown report format:", *format)
+		return 2
+	}
+	languages, err := loadLanguageMap(*languageMapPath)
+	if err != nil {
+		fmt.Println("Error loading language map:", err)
+		return 2
+	}
+
+	users, err := loadUsersCSV(*usersPath)
+	if err != nil {
+		fmt.Println("Error loading users:", err)
+		return 1
+	}
+	repos, err := loadReposCSV(*reposPath)
+	if err != nil {
+		fmt.Println("Error loading repos:", err)
+		return 1
+	}
+	languages.apply(repos)
+
+	var w io.Writer = os.Stdout
+	var file *atomicFile
+	if *out != "" {
+		if file, err = createAtomic(*out); err != nil {
+			fmt.Println("Error creating report:", err)
+			return 1
+		}
+		defer file.discard()
+		w = file
+	}
+
+	s := summarize(users, repos, *top)
+	if *format == "html" {
+		err = writeHTMLReport(w, s, users)
+	} else {
+		err = writeMarkdownReport(w, s)
+	}
+	if err == nil && file != nil {
+		err = file.commit()
+	}
+	if err != nil {
+		fmt.Println("Error writing report:", err)
+		return 1
+	}
+	return 0
+}
+
+// mdCell escapes a value for use inside a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func writeMarkdownReport(w io.Writer, s summary) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# GitHub users report\n\n%d users, %d repositories.\n\n", s.Users, s.Repos)
+
+	fmt.Fprintf(&b, "## Top %d users by followers\n\n", len(s.TopUsers))
+	b.WriteString("| # | Login | Name | Company | Followers | Public repos |\n|---|---|---|---|---:|---:|\n")
+	for i, u := range s.TopUsers {
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %d | %d |\n", i+1, mdCell(u.Login), mdCell(u.Name), mdCell(u.Company), u.Followers, u.PublicRepos)
+	}
+
+	fmt.Fprintf(&b, "\n## Top %d repositories by stars\n\n", len(s.TopRepos))
+	b.WriteString("| # | Repository | Language | Stars | License |\n|---|---|---|---:|---|\n")
+	for i, r := range s.TopRepos {
+		fmt.Fprintf(&b, "| %d | %s | %s | %d | %s |\n", i+1, mdCell(r.FullName), mdCell(r.Language), r.StargazersCount, mdCell(r.LicenseName))
+	}
+
+	writeMarkdownShares(&b, "Language share", "Language", s.Languages)
+	writeMarkdownShares(&b, "License share", "License", s.Licenses)
+
+	b.WriteString("\n## Hireable\n\n| Hireable | Users |\n|---|---:|\n")
+	fmt.Fprintf(&b, "| yes | %d |\n| no | %d |\n", s.Hireable, s.NotHireable)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownShares(b *strings.Builder, title, column string, shares []share) {
+	fmt.Fprintf(b, "\n## %s\n\n| %s | Repos | Share |\n|---|---:|---:|\n", title, column)
+	for _, s := range shares {
+		fmt.Fprintf(b, "| %s | %d | %.1f%% |\n", mdCell(s.Name), s.Count, s.Percent)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlReport is the data passed to htmlReportTemplate.
+type htmlReport struct {
+	summary
+	FollowerHistogram []share
+	LanguagePie       []share
+	Timeline          []share
+}
+
+func writeHTMLReport(w io.Writer, s summary, users []User) error {
+	return htmlReportTemplate.Execute(w, htmlReport{
+		summary:           s,
+		FollowerHistogram: followerHistogram(users),
+		LanguagePie:       topShares(s.Languages, 10),
+		Timeline:          creationTimeline(users),
+	})
+}
+
+// htmlReportTemplate renders a self-contained page. The charts are drawn on
+// canvases by the inline script, so the file needs no network access.
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GitHub users report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 960px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2rem; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: .3rem .5rem; text-align: left; }
+td.num { text-align: right; }
+.chart { position: relative; margin-bottom: 2rem; }
+.tip { position: absolute; pointer-events: none; background: #222; color: #fff; padding: .2rem .4rem; border-radius: 3px; font-size: .85rem; display: none; }
+</style>
+</head>
+<body>
+<h1>GitHub users report</h1>
+<p>{{.Users}} users, {{.Repos}} repositories. {{.Hireable}} hireable, {{.NotHireable}} not hireable.</p>
+
+<h2>Followers</h2>
+<div class="chart"><canvas id="followers" width="900" height="300"></canvas><div class="tip"></div></div>
+
+<h2>Languages</h2>
+<div class="chart"><canvas id="languages" width="900" height="320"></canvas><div class="tip"></div></div>
+
+<h2>Accounts created per year</h2>
+<div class="chart"><canvas id="timeline" width="900" height="300"></canvas><div class="tip"></div></div>
+
+<h2>Top {{len .TopUsers}} users by followers</h2>
+<table>
+<tr><th>#</th><th>Login</th><th>Name</th><th>Company</th><th>Followers</th><th>Public repos</th></tr>
+{{range $i, $u := .TopUsers}}<tr><td>{{inc $i}}</td><td><a href="https://github.com/{{$u.Login}}">{{$u.Login}}</a></td><td>{{$u.Name}}</td><td>{{$u.Company}}</td><td class="num">{{$u.Followers}}</td><td class="num">{{$u.PublicRepos}}</td></tr>
+{{end}}</table>
+
+<h2>Top {{len .TopRepos}} repositories by stars</h2>
+<table>
+<tr><th>#</th><th>Repository</th><th>Language</th><th>Stars</th><th>License</th></tr>
+{{range $i, $r := .TopRepos}}<tr><td>{{inc $i}}</td><td><a href="https://github.com/{{$r.FullName}}">{{$r.FullName}}</a></td><td>{{$r.Language}}</td><td class="num">{{$r.StargazersCount}}</td><td>{{$r.LicenseName}}</td></tr>
+{{end}}</table>
+
+<script>
+const data = {
+  followers: [{{range .FollowerHistogram}}{label: {{.Name}}, value: {{.Count}}},{{end}}],
+  languages: [{{range .LanguagePie}}{label: {{.Name}}, value: {{.Count}}},{{end}}],
+  timeline: [{{range .Timeline}}{label: {{.Name}}, value: {{.Count}}},{{end}}],
+};
+const colors = ["#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#888"];
+
+function hover(canvas, hit) {
+  const tip = canvas.nextElementSibling;
+  canvas.addEventListener("mousemove", e => {
+    const r = canvas.getBoundingClientRect();
+    const d = hit((e.clientX - r.left) * canvas.width / r.width, (e.clientY - r.top) * canvas.height / r.height);
+    if (!d) { tip.style.display = "none"; return; }
+    tip.textContent = d.label + ": " + d.value;
+    tip.style.left = (e.clientX - r.left + 12) + "px";
+    tip.style.top = (e.clientY - r.top + 12) + "px";
+    tip.style.display = "block";
+  });
+  canvas.addEventListener("mouseleave", () => { tip.style.display = "none"; });
+}
+
+function bars(id, items) {
+  const c = document.getElementById(id), g = c.getContext("2d");
+  const pad = 40, w = (c.width - pad) / Math.max(items.length, 1);
+  const top = Math.max(1, ...items.map(d => d.value));
+  g.font = "12px sans-serif";
+  g.textAlign = "center";
+  items.forEach((d, i) => {
+    const h = (c.height - 2 * pad) * d.value / top;
+    g.fillStyle = colors[0];
+    g.fillRect(pad + i * w + 2, c.height - pad - h, w - 4, h);
+    g.fillStyle = "#222";
+    g.fillText(d.label, pad + i * w + w / 2, c.height - pad + 16);
+  });
+  hover(c, x => items[Math.floor((x - pad) / w)]);
+}
+
+function pie(id, items) {
+  const c = document.getElementById(id), g = c.getContext("2d");
+  const total = items.reduce((n, d) => n + d.value, 0) || 1;
+  const cx = c.height / 2, cy = c.height / 2, r = c.height / 2 - 10;
+  let a = -Math.PI / 2;
+  const arcs = items.map((d, i) => {
+    const span = 2 * Math.PI * d.value / total;
+    g.beginPath(); g.moveTo(cx, cy); g.arc(cx, cy, r, a, a + span); g.closePath();
+    g.fillStyle = colors[i % colors.length]; g.fill();
+    g.fillRect(c.height + 20, 20 + i * 22, 14, 14);
+    g.fillStyle = "#222"; g.font = "13px sans-serif";
+    g.fillText(d.label + " (" + (100 * d.value / total).toFixed(1) + "%)", c.height + 42, 32 + i * 22);
+    const arc = {from: a, to: a + span, d: d};
+    a += span;
+    return arc;
+  });
+  hover(c, (x, y) => {
+    if (Math.hypot(x - cx, y - cy) > r) return null;
+    let t = Math.atan2(y - cy, x - cx);
+    if (t < -Math.PI / 2) t += 2 * Math.PI;
+    const arc = arcs.find(s => t >= s.from && t < s.to);
+    return arc && arc.d;
+  });
+}
+
+bars("followers", data.followers);
+pie("languages", data.languages);
+bars("timeline", data.timeline);
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunReportOut(t *testing.T) {
+	dir := t.TempDir()
+	users, repos := filepath.Join(dir, "users.csv"), filepath.Join(dir, "repositories.csv")
+	os.WriteFile(users, []byte("login,name,company,followers,public_repos\nalice,Alice,Acme,10,2\n"), 0o644)
+	os.WriteFile(repos, []byte("login,full_name,stargazers_count,language\nalice,alice/x,5,Go\n"), 0o644)
+	out := filepath.Join(dir, "report.md")
+	args := []string{"--users", users, "--repos", repos, "--out", out}
+
+	if status := runReport(args); status != 0 {
+		t.Fatalf("runReport exited %d", status)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.HasPrefix(string(data), "# GitHub users report") || !strings.Contains(string(data), "alice") {
+		t.Fatalf("report: %q, %v", data, err)
+	}
+	if status := runReport(append(args, "--format", "pdf")); status != 2 {
+		t.Errorf("unknown format exited %d, want 2", status)
+	}
+	if status := runReport([]string{"--users", users, "--repos", repos, "--out", filepath.Join(dir, "missing", "report.md")}); status != 1 {
+		t.Errorf("unwritable --out exited %d, want 1", status)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("left behind %v", entries)
+	}
+}
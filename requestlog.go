@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// requestLog appends one JSON line per API request to a file, for
+// debugging and as a record of where the data came from.
+type requestLog struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+type requestLogEntry struct {
+	Time               time.Time `json:"time"`
+	Method             string    `json:"method"`
+	URL                string    `json:"url"`
+	Status             int       `json:"status,omitempty"`
+	DurationMS         float64   `json:"duration_ms"`
+	RateLimitRemaining *int      `json:"rate_limit_remaining,omitempty"`
+	Error              string    `json:"error,omitempty"`
+}
+
+func openRequestLog(path string) (*requestLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	return &requestLog{file: file, enc: enc}, nil
+}
+
+// record logs a request that started at start and ended with resp or err.
+// It is a responseHook. A nil log records nothing.
+func (l *requestLog) record(req *http.Request, start time.Time, resp *http.Response, err error) {
+	if l == nil {
+		return
+	}
+	e := requestLogEntry{
+		Time:       start.UTC(),
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if resp != nil {
+		e.Status = resp.StatusCode
+		if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+			e.RateLimitRemaining = &n
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(e)
+}
+
+func (l *requestLog) close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// scrapeRun is one scrape of a runs config: its scrape flags, without the
+// leading dashes, and the directory it runs in and writes its outputs to.
+type scrapeRun struct {
+	name  string
+	dir   string
+	flags map[string]string
+	opts  scrapeOptions
+}
+
+// runsConfig is a runs config, e.g.
+//
+//	parallel: false
+//	max_api_calls: 10000
+//	flags:
+//	  cache-dir: /var/cache/tds
+//	runs:
+//	  - name: beijing
+//	    flags:
+//	      query: "location:Beijing followers:>500"
+//	  - name: china
+//	    dir: out/china
+//	    flags:
+//	      country: china
+//
+// flags apply to every run, under the run's own. A run's dir defaults to
+// its name; relative paths in its flags are relative to it.
+//
+// Parallel runs split max_api_calls between them, but each keeps its own
+// view of GitHub's rate limit: runs sharing a token wait out the limit one
+// by one as they hit it rather than pacing themselves together.
+type runsConfig struct {
+	parallel bool
+	// maxCalls is the API budget the runs share; 0 means none.
+	maxCalls int64
+	runs     []scrapeRun
+}
+
+// yamlFlags reads a mapping of flag values, formatting numbers and
+// booleans as the command line would.
+func yamlFlags(v any) (map[string]string, error) {
+	if v == nil {
+		return map[string]string{}, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("flags must be a mapping")
+	}
+	flags := make(map[string]string, len(m))
+	for name, value := range m {
+		switch x := value.(type) {
+		case bool:
+			flags[name] = strconv.FormatBool(x)
+		case float64, string:
+			flags[name] = yamlString(m, name, "")
+		default:
+			return nil, fmt.Errorf("flag %s must be a string, number, or boolean", name)
+		}
+	}
+	return flags, nil
+}
+
+func loadRunsConfig(path string) (runsConfig, error) {
+	var cfg runsConfig
+	m, err := loadYAMLFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.parallel, _ = m["parallel"].(bool)
+	cfg.maxCalls = int64(yamlFloat(m, "max_api_calls", 0))
+	common, err := yamlFlags(m["flags"])
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	items, _ := m["runs"].([]any)
+	if len(items) == 0 {
+		return cfg, fmt.Errorf("%s: no runs", path)
+	}
+	seen := map[string]bool{}
+	for i, item := range items {
+		spec, ok := item.(map[string]any)
+		if !ok {
+			return cfg, fmt.Errorf("%s: run %d must be a mapping", path, i+1)
+		}
+		r := scrapeRun{name: yamlString(spec, "name", "")}
+		if r.name == "" {
+			return cfg, fmt.Errorf("%s: run %d has no name", path, i+1)
+		}
+		if seen[r.name] {
+			return cfg, fmt.Errorf("%s: duplicate run %s", path, r.name)
+		}
+		seen[r.name] = true
+		r.dir = yamlString(spec, "dir", r.name)
+		own, err := yamlFlags(spec["flags"])
+		if err != nil {
+			return cfg, fmt.Errorf("%s: run %s: %w", path, r.name, err)
+		}
+		r.flags = maps.Clone(common)
+		maps.Copy(r.flags, own)
+		// Parse the flags now, so a typo fails the config rather than a
+		// run halfway through.
+		fs := flag.NewFlagSet(r.name, flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		r.opts.register(fs)
+		if err := fs.Parse(r.args(0)); err != nil {
+			return cfg, fmt.Errorf("%s: run %s: %w", path, r.name, err)
+		}
+		// The run's API calls are read back from its summary.
+		if r.opts.summaryFile == "" {
+			return cfg, fmt.Errorf("%s: run %s: summary-file must not be empty", path, r.name)
+		}
+		cfg.runs = append(cfg.runs, r)
+	}
+	return cfg, nil
+}
+
+// args returns the run's command line, capping its API calls at budget
+// unless budget is 0 or the run sets a lower cap itself.
+func (r scrapeRun) args(budget int64) []string {
+	flags := maps.Clone(r.flags)
+	if budget > 0 {
+		own, err := strconv.ParseInt(flags["max-api-calls"], 10, 64)
+		if err != nil || own <= 0 || own > budget {
+			flags["max-api-calls"] = strconv.FormatInt(budget, 10)
+		}
+	}
+	var args []string
+	for _, name := range slices.Sorted(maps.Keys(flags)) {
+		args = append(args, "--"+name+"="+flags[name])
+	}
+	return args
+}
+
+// errRunSkipped marks a sequential run left out because the runs before it
+// spent the shared budget.
+var errRunSkipped = errors.New("skipped: the shared API budget is spent")
+
+// runResult is how one run of a runs config went.
+type runResult struct {
+	calls int64
+	err   error
+}
+
+// execute runs the scrape in its directory as a child process, logging its
+// output to scrape.log there, and reads the API calls it made from its run
+// summary.
+func (r scrapeRun) execute(ctx context.Context, exe string, budget int64) runResult {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return runResult{err: err}
+	}
+	log, err := os.Create(filepath.Join(r.dir, "scrape.log"))
+	if err != nil {
+		return runResult{err: err}
+	}
+	defer log.Close()
+	// A summary left by an earlier invocation must not pass for this one's.
+	summaryPath := r.opts.summaryFile
+	if !filepath.IsAbs(summaryPath) {
+		summaryPath = filepath.Join(r.dir, summaryPath)
+	}
+	os.Remove(summaryPath)
+	cmd := exec.CommandContext(ctx, exe, r.args(budget)...)
+	cmd.Dir, cmd.Stdout, cmd.Stderr = r.dir, log, log
+	runErr := cmd.Run()
+
+	var summary struct {
+		APICalls int64 `json:"api_calls"`
+	}
+	data, err := os.ReadFile(summaryPath)
+	if err == nil {
+		err = json.Unmarshal(data, &summary)
+	}
+	switch {
+	case runErr != nil:
+		return runResult{summary.APICalls, runErr}
+	case err != nil:
+		return runResult{0, fmt.Errorf("no run summary: %w", err)}
+	}
+	return runResult{summary.APICalls, nil}
+}
+
+func runRuns(args []string) int {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	configPath := fs.String("config", "runs.yaml", "YAML file with the runs section listing the scrapes to run")
+	dryRun := fs.Bool("dry-run", false, "print each run's directory and command line without running it")
+	fs.Parse(args)
+
+	cfg, err := loadRunsConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error loading runs:", err)
+		return 2
+	}
+	// Parallel runs split the budget evenly; sequential ones each get
+	// what the earlier runs left.
+	share := cfg.maxCalls
+	if cfg.parallel {
+		share = cfg.maxCalls / int64(len(cfg.runs))
+		if cfg.maxCalls > 0 && share == 0 {
+			fmt.Printf("Error: max_api_calls %d is too small to share among %d runs\n", cfg.maxCalls, len(cfg.runs))
+			return 2
+		}
+	}
+	if *dryRun {
+		for _, r := range cfg.runs {
+			fmt.Printf("%s (in %s): %q\n", r.name, r.dir, r.args(share))
+		}
+		return 0
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := make([]runResult, len(cfg.runs))
+	if cfg.parallel {
+		var wg sync.WaitGroup
+		for i, r := range cfg.runs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = r.execute(ctx, exe, share)
+			}()
+		}
+		wg.Wait()
+	} else {
+		remaining := cfg.maxCalls
+		for i, r := range cfg.runs {
+			if cfg.maxCalls > 0 && remaining <= 0 {
+				results[i].err = errRunSkipped
+				continue
+			}
+			fmt.Printf("Running %s in %s\n", r.name, r.dir)
+			results[i] = r.execute(ctx, exe, remaining)
+			remaining -= results[i].calls
+		}
+	}
+
+	status := 0
+	var total int64
+	for i, r := range cfg.runs {
+		res := results[i]
+		total += res.calls
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(res.err, &exitErr) && exitErr.ExitCode() == exitLimitReached:
+			fmt.Printf("  %-20s stopped early at a limit after %d API calls\n", r.name, res.calls)
+		case errors.Is(res.err, errRunSkipped):
+			fmt.Printf("  %-20s skipped, the shared API budget is spent\n", r.name)
+		case res.err != nil:
+			fmt.Printf("  %-20s failed: %v (see %s)\n", r.name, res.err, filepath.Join(r.dir, "scrape.log"))
+			status = 1
+		default:
+			fmt.Printf("  %-20s done, %d API calls\n", r.name, res.calls)
+		}
+	}
+	fmt.Printf("%d runs, %d API calls in total\n", len(cfg.runs), total)
+	return status
+}
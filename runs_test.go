@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRunsConfigEmptySummaryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.yaml")
+	os.WriteFile(path, []byte("runs:\n  - name: a\n    flags:\n      summary-file: \"\"\n"), 0o644)
+	if _, err := loadRunsConfig(path); err == nil || !strings.Contains(err.Error(), "summary-file") {
+		t.Errorf("err = %v, want an empty summary-file rejected", err)
+	}
+}
+
+// execute reads the summary where the child wrote it: an absolute
+// summary-file as is, a relative one under the run's directory.
+func TestExecuteSummaryPath(t *testing.T) {
+	dir := t.TempDir()
+	// The stand-in scrape writes its summary to whatever --summary-file says,
+	// relative to its working directory.
+	exe := filepath.Join(dir, "scrape.sh")
+	os.WriteFile(exe, []byte(`#!/bin/sh
+for a; do case $a in --summary-file=*) echo '{"api_calls": 7}' > "${a#--summary-file=}";; esac; done
+`), 0o755)
+	for _, summary := range []string{filepath.Join(dir, "abs", "summary.json"), "rel.json"} {
+		os.MkdirAll(filepath.Join(dir, "abs"), 0o755)
+		r := scrapeRun{name: "a", dir: filepath.Join(dir, "run"), flags: map[string]string{"summary-file": summary}}
+		r.opts.summaryFile = summary
+		res := r.execute(context.Background(), exe, 0)
+		if res.err != nil || res.calls != 7 {
+			t.Errorf("summary-file %s: got %+v, want 7 calls", summary, res)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// dependency is one package in a repo's dependency graph.
+type dependency struct {
+	Repo      string
+	Ecosystem string
+	Package   string
+	Version   string
+}
+
+var dependencyColumns = []string{"repo", "ecosystem", "package", "version"}
+
+func dependencyRecord(d dependency) []string {
+	return []string{d.Repo, d.Ecosystem, d.Package, d.Version}
+}
+
+// parsePURL splits a package URL such as pkg:golang/github.com/x/y@v1.2.0
+// into its ecosystem, name, and version.
+func parsePURL(purl string) (ecosystem, name, version string, ok bool) {
+	rest, found := strings.CutPrefix(purl, "pkg:")
+	if !found {
+		return "", "", "", false
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "#")
+	ecosystem, rest, found = strings.Cut(rest, "/")
+	if !found {
+		return "", "", "", false
+	}
+	if i := strings.LastIndex(rest, "@"); i > 0 {
+		rest, version = rest[:i], rest[i+1:]
+	}
+	name = strings.ReplaceAll(rest, "%40", "@")
+	return ecosystem, name, version, true
+}
+
+// fetchDependencies reads the SBOM of every non-fork repo. Repos without a
+// dependency graph, or whose lookup failed, contribute nothing.
+func (c *apiClient) fetchDependencies(ctx context.Context, repos []Repo) []dependency {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var out []dependency
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, r := range repos {
+		if r.Fork {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(fullName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			body, err := c.getCached(ctx, fmt.Sprintf("%s/repos/%s/dependency-graph/sbom", c.baseURL, fullName))
+			if err != nil {
+				return
+			}
+			var resp struct {
+				SBOM struct {
+					Packages []struct {
+						ExternalRefs []struct {
+							ReferenceType    string `json:"referenceType"`
+							ReferenceLocator string `json:"referenceLocator"`
+						} `json:"externalRefs"`
+					} `json:"packages"`
+				} `json:"sbom"`
+			}
+			if json.Unmarshal(body, &resp) != nil {
+				return
+			}
+			var deps []dependency
+			for _, p := range resp.SBOM.Packages {
+				for _, ref := range p.ExternalRefs {
+					if ref.ReferenceType != "purl" {
+						continue
+					}
+					// The repo itself is listed as pkg:github/<owner>/<repo>.
+					eco, name, version, ok := parsePURL(ref.ReferenceLocator)
+					if ok && !strings.EqualFold(name, fullName) {
+						deps = append(deps, dependency{fullName, eco, name, version})
+					}
+				}
+			}
+			mu.Lock()
+			out = append(out, deps...)
+			mu.Unlock()
+		}(r.FullName)
+	}
+	wg.Wait()
+	slices.SortFunc(out, func(a, b dependency) int {
+		return cmp.Or(cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Ecosystem, b.Ecosystem), cmp.Compare(a.Package, b.Package))
+	})
+	return out
+}
+
+func loadDependenciesCSV(path string) ([]dependency, error) {
+	var deps []dependency
+	err := readCSV(path, func(r csvRecord) {
+		deps = append(deps, dependency{r.str("repo"), r.str("ecosystem"), r.str("package"), r.str("version")})
+	})
+	return deps, err
+}
+
+// topDependencies counts, per ecosystem and package, the repos that depend
+// on it, most used first.
+func topDependencies(deps []dependency) []share {
+	seen := map[[2]string]bool{}
+	var keys []string
+	repos := map[string]bool{}
+	for _, d := range deps {
+		repos[d.Repo] = true
+		if k := [2]string{d.Repo, d.Ecosystem + ":" + d.Package}; !seen[k] {
+			seen[k] = true
+			keys = append(keys, k[1])
+		}
+	}
+	shares := shareOf(keys)
+	// Percent of depending repos, rather than of all dependency edges.
+	for i := range shares {
+		shares[i].Percent = 100 * float64(shares[i].Count) / float64(len(repos))
+	}
+	return shares
+}
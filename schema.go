@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// jsonTypes maps the JSON-tagged fields of a struct type to JSON Schema
+// types.
+func jsonTypes(t reflect.Type) map[string]string {
+	types := map[string]string{}
+	for _, f := range bigQuerySchema(t) {
+		switch f.Type {
+		case "BOOLEAN":
+			types[f.Name] = "boolean"
+		case "INTEGER":
+			types[f.Name] = "integer"
+		case "FLOAT":
+			types[f.Name] = "number"
+		default:
+			types[f.Name] = "string"
+		}
+	}
+	return types
+}
+
+// jsonSchema describes the JSON form of a User or Repo, as served by serve
+// and written by watch and the NDJSON exporters.
+func jsonSchema(title, key string, t reflect.Type) map[string]any {
+	props := map[string]any{}
+	for name, typ := range jsonTypes(t) {
+		p := map[string]string{"type": typ}
+		if name == "created_at" {
+			p["format"] = "date-time"
+		}
+		props[name] = p
+	}
+	return map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      title,
+		"type":       "object",
+		"properties": props,
+		"required":   []string{key},
+	}
+}
+
+// csvwTable describes one CSV file in CSVW metadata. Columns that do not
+// come from the struct, such as enrichment columns, are strings.
+func csvwTable(url, key string, columns []string, t reflect.Type) map[string]any {
+	types := jsonTypes(t)
+	cols := make([]map[string]string, len(columns))
+	for i, c := range columns {
+		datatype := types[c]
+		switch {
+		case c == "created_at":
+			datatype = "datetime"
+		case datatype == "":
+			datatype = "string"
+		}
+		cols[i] = map[string]string{"name": c, "titles": c, "datatype": datatype}
+	}
+	return map[string]any{
+		"url":         url,
+		"tableSchema": map[string]any{"columns": cols, "primaryKey": key},
+	}
+}
+
+// writeSchemas writes csv-metadata.json, CSVW metadata for users.csv and,
+// when repoColumns is set, repositories.csv, along with JSON Schemas for
+// users and repos. It returns the files written.
+func writeSchemas(userColumns, repoColumns []string) ([]string, error) {
+	tables := []any{csvwTable("users.csv", "login", userColumns, reflect.TypeFor[User]())}
+	if repoColumns != nil {
+		tables = append(tables, csvwTable("repositories.csv", "full_name", repoColumns, reflect.TypeFor[Repo]()))
+	}
+	docs := []struct {
+		path string
+		doc  any
+	}{
+		{"csv-metadata.json", map[string]any{"@context": "http://www.w3.org/ns/csvw", "tables": tables}},
+		{"user.schema.json", jsonSchema("User", "login", reflect.TypeFor[User]())},
+		{"repo.schema.json", jsonSchema("Repo", "full_name", reflect.TypeFor[Repo]())},
+	}
+	var files []string
+	for _, d := range docs {
+		data, err := json.MarshalIndent(d.doc, "", "  ")
+		if err != nil {
+			return files, err
+		}
+		if err := writeFileAtomic(d.path, data); err != nil {
+			return files, err
+		}
+		files = append(files, d.path)
+	}
+	return files, nil
+}
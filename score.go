@@ -0,0 +1,197 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scoreModel weights normalised candidate signals into a 0-100 score. It is
+// configured from YAML, for example:
+//
+//	weights:
+//	  followers: 3
+//	  stars: 2
+//	  recent_activity: 2
+//	  hireable: 1
+//	  bio_keywords: 2
+//	bio_keywords: [kubernetes, rust, machine learning]
+//	activity_days: 365
+//	shortlist: 50
+type scoreModel struct {
+	weights      map[string]float64
+	keywords     []string
+	activityDays float64
+	shortlist    int
+}
+
+// scoreSignals are the signals a model can weight, each normalised to 0-1.
+var scoreSignals = []string{"followers", "stars", "recent_activity", "hireable", "bio_keywords"}
+
+func defaultScoreModel() scoreModel {
+	return scoreModel{
+		weights:      map[string]float64{"followers": 3, "stars": 2, "recent_activity": 2, "hireable": 1, "bio_keywords": 0},
+		activityDays: 365,
+		shortlist:    50,
+	}
+}
+
+func loadScoreModel(path string) (scoreModel, error) {
+	m := defaultScoreModel()
+	cfg, err := loadYAMLFile(path)
+	if err != nil {
+		return m, err
+	}
+	if raw, ok := cfg["weights"]; ok {
+		weights, ok := raw.(map[string]any)
+		if !ok {
+			return m, fmt.Errorf("%s: weights must be a mapping", path)
+		}
+		m.weights = map[string]float64{}
+		for name, v := range weights {
+			if !slices.Contains(scoreSignals, name) {
+				return m, fmt.Errorf("%s: unknown signal %q (want one of %s)", path, name, strings.Join(scoreSignals, ", "))
+			}
+			w, ok := v.(float64)
+			if !ok || w < 0 {
+				return m, fmt.Errorf("%s: weight for %s must be a non-negative number", path, name)
+			}
+			m.weights[name] = w
+		}
+	}
+	m.keywords = yamlStrings(cfg, "bio_keywords")
+	m.activityDays = yamlFloat(cfg, "activity_days", m.activityDays)
+	if raw, ok := cfg["shortlist"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			return m, fmt.Errorf("%s: shortlist must be a non-negative integer", path)
+		}
+		m.shortlist = int(n)
+	}
+	if m.activityDays <= 0 {
+		return m, fmt.Errorf("%s: activity_days must be positive", path)
+	}
+	return m, nil
+}
+
+// scoredUser is a user with their total stars, matched bio keywords, and
+// score.
+type scoredUser struct {
+	User
+	Stars    int
+	Keywords []string
+	Score    float64
+}
+
+// scoreUsers scores every user and returns them best first. Followers and
+// stars are log-scaled against the population maximum; recent activity
+// decays linearly with the age of the user's newest repo over activityDays.
+func (m scoreModel) scoreUsers(users []User, repos []Repo, now time.Time) []scoredUser {
+	stars := map[string]int{}
+	newest := map[string]string{}
+	for _, r := range repos {
+		stars[r.Login] += r.StargazersCount
+		newest[r.Login] = max(newest[r.Login], r.CreatedAt)
+	}
+	maxFollowers, maxStars := 0, 0
+	for _, u := range users {
+		maxFollowers = max(maxFollowers, u.Followers)
+		maxStars = max(maxStars, stars[u.Login])
+	}
+	logScale := func(v, top int) float64 {
+		if top == 0 {
+			return 0
+		}
+		return math.Log1p(float64(v)) / math.Log1p(float64(top))
+	}
+	total := 0.0
+	for _, w := range m.weights {
+		total += w
+	}
+
+	scored := make([]scoredUser, len(users))
+	for i, u := range users {
+		s := scoredUser{User: u, Stars: stars[u.Login], Keywords: matchKeywords(u.Bio, m.keywords)}
+		signals := map[string]float64{
+			"followers": logScale(u.Followers, maxFollowers),
+			"stars":     logScale(s.Stars, maxStars),
+		}
+		if t, err := time.Parse(time.RFC3339, newest[u.Login]); err == nil {
+			age := now.Sub(t).Hours() / 24
+			signals["recent_activity"] = math.Max(0, 1-age/m.activityDays)
+		}
+		if u.Hireable {
+			signals["hireable"] = 1
+		}
+		if len(m.keywords) > 0 {
+			signals["bio_keywords"] = float64(len(s.Keywords)) / float64(len(m.keywords))
+		}
+		if total > 0 {
+			for name, w := range m.weights {
+				s.Score += w * signals[name]
+			}
+			s.Score = math.Round(s.Score/total*10000) / 100
+		}
+		scored[i] = s
+	}
+	slices.SortStableFunc(scored, func(a, b scoredUser) int { return cmp.Compare(b.Score, a.Score) })
+	return scored
+}
+
+var scoredColumns = append(slices.Clone(userColumns), "stars", "bio_keywords", "score")
+
+func scoredRecord(s scoredUser) []string {
+	return append(userRecord(s.User), strconv.Itoa(s.Stars), strings.Join(s.Keywords, ";"), strconv.FormatFloat(s.Score, 'f', 2, 64))
+}
+
+func runScore(args []string) int {
+	fs := flag.NewFlagSet("score", flag.ExitOnError)
+	usersPath := fs.String("users", "users.csv", "users CSV to score")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV to score")
+	configPath := fs.String("config", "", "YAML scoring config (weights, bio_keywords, activity_days, shortlist)")
+	out := fs.String("out", "scores.csv", "write every user with their score here")
+	shortlistOut := fs.String("shortlist", "shortlist.csv", "write the top-ranked users here")
+	fs.Parse(args)
+
+	model := defaultScoreModel()
+	if *configPath != "" {
+		var err error
+		if model, err = loadScoreModel(*configPath); err != nil {
+			fmt.Println("Error loading scoring config:", err)
+			return 2
+		}
+	}
+	users, err := loadUsersCSV(*usersPath)
+	if err != nil {
+		fmt.Println("Error loading users:", err)
+		return 1
+	}
+	repos, err := loadReposCSV(*reposPath)
+	if err != nil {
+		fmt.Println("Error loading repos:", err)
+		return 1
+	}
+
+	scored := model.scoreUsers(users, repos, time.Now())
+	if err := writeRecordsCSV(*out, scoredColumns, scored, scoredRecord); err != nil {
+		fmt.Println("Error saving scores:", err)
+		return 1
+	}
+	shortlist := scored[:min(model.shortlist, len(scored))]
+	rank := 0
+	err = writeRecordsCSV(*shortlistOut, append([]string{"rank"}, scoredColumns...), shortlist, func(s scoredUser) []string {
+		rank++
+		return append([]string{strconv.Itoa(rank)}, scoredRecord(s)...)
+	})
+	if err != nil {
+		fmt.Println("Error saving shortlist:", err)
+		return 1
+	}
+	fmt.Printf("Scored %d users; shortlisted %d in %s\n", len(scored), len(shortlist), *shortlistOut)
+	return 0
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoreUsers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := []User{
+		{Login: "quiet", Bio: "Go developer"},
+		{Login: "famous", Followers: 100, Hireable: true, Bio: "Rust and Go"},
+		{Login: "rusty", Followers: 9, Bio: "rust person"},
+	}
+	repos := []Repo{
+		{Login: "famous", StargazersCount: 10, CreatedAt: now.AddDate(0, 0, -50).Format(time.RFC3339)},
+		{Login: "famous", StargazersCount: 5, CreatedAt: now.AddDate(-2, 0, 0).Format(time.RFC3339)},
+	}
+	tests := []struct {
+		name  string
+		model scoreModel
+		want  map[string]float64
+	}{
+		{"followers", scoreModel{weights: map[string]float64{"followers": 1}, activityDays: 100},
+			map[string]float64{"famous": 100, "rusty": 49.89, "quiet": 0}},
+		{"recent activity", scoreModel{weights: map[string]float64{"recent_activity": 1}, activityDays: 100},
+			map[string]float64{"famous": 50, "rusty": 0, "quiet": 0}},
+		{"hireable and keywords", scoreModel{weights: map[string]float64{"hireable": 1, "bio_keywords": 1}, keywords: []string{"rust", "go"}, activityDays: 100},
+			map[string]float64{"famous": 100, "rusty": 25, "quiet": 25}},
+		{"no weights", scoreModel{weights: map[string]float64{}, activityDays: 100},
+			map[string]float64{"famous": 0, "rusty": 0, "quiet": 0}},
+	}
+	for _, tt := range tests {
+		scored := tt.model.scoreUsers(users, repos, now)
+		for i, s := range scored {
+			if s.Score != tt.want[s.Login] {
+				t.Errorf("%s: %s scored %v, want %v", tt.name, s.Login, s.Score, tt.want[s.Login])
+			}
+			if i > 0 && s.Score > scored[i-1].Score {
+				t.Errorf("%s: not sorted best first", tt.name)
+			}
+		}
+	}
+	if s := defaultScoreModel().scoreUsers(users, repos, now); s[0].Login != "famous" || s[0].Stars != 15 {
+		t.Errorf("default model ranks %s with %d stars first", s[0].Login, s[0].Stars)
+	}
+}
+
+func TestLoadScoreModel(t *testing.T) {
+	tests := []struct {
+		config, err string
+	}{
+		{"weights:\n  stars: 1\nshortlist: 10\n", ""},
+		{"shortlist: -1\n", "shortlist"},
+		{"shortlist: 2.5\n", "shortlist"},
+		{"shortlist: ten\n", "shortlist"},
+		{"weights:\n  karma: 1\n", "unknown signal"},
+		{"weights:\n  stars: -1\n", "stars"},
+		{"activity_days: 0\n", "activity_days"},
+		{"bio_keywords: [rust, ]\n", ""},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "score.yaml")
+		os.WriteFile(path, []byte(tt.config), 0o644)
+		_, err := loadScoreModel(path)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%q: err = %v, want %q", tt.config, err, tt.err)
+		}
+	}
+}
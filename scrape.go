@@ -0,0 +1,1221 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// exitLimitReached is the exit status used when the run stops early because
+// its deadline or API call budget ran out.
+const exitLimitReached = 3
+
+// exitValidationFailed is the exit status used when --strict finds problems
+// in the exported data.
+const exitValidationFailed = 4
+
+// scrapeOptions holds the command-line configuration of a scrape run.
+type scrapeOptions struct {
+	deadline   time.Duration
+	maxCalls   int
+	checkpoint string
+	cacheDir   string
+	cacheTTL   time.Duration
+
+	sheetsID          string
+	sheetsCredentials string
+	sheetsUsersTab    string
+	sheetsReposTab    string
+
+	bigQueryDataset     string
+	bigQueryCredentials string
+	bigQueryUsersTable  string
+	bigQueryReposTable  string
+
+	esURL    string
+	esPrefix string
+	esAPIKey string
+
+	kafkaREST       string
+	kafkaUsersTopic string
+	kafkaReposTopic string
+
+	mongoURI       string
+	mongoUsersColl string
+	mongoReposColl string
+
+	duckDB string
+
+	notifyWebhook string
+
+	smtpHost      string
+	smtpUser      string
+	smtpPassword  string
+	mailFrom      string
+	mailTo        string
+	mailAttach    bool
+	mailAttachMax int
+
+	gitRepo   string
+	gitBranch string
+	gitDir    string
+
+	publish string
+
+	compress string
+
+	partitionBy string
+
+	template    string
+	templateOut string
+
+	upload string
+
+	bioKeywords string
+
+	edges string
+
+	historyDir string
+
+	resolveForks bool
+	subscribers  bool
+
+	events bool
+
+	sponsors bool
+
+	commitEmails      bool
+	commitEmailOptOut string
+
+	traffic bool
+
+	dependencies string
+
+	seedCodeQuery string
+	loginsFile    string
+	country       string
+	query         string
+	excludeLogins string
+	onlyLogins    string
+
+	techFilter string
+
+	org      string
+	teamsOut string
+
+	cohorts string
+
+	token string
+
+	userAgent string
+	headers   []string
+
+	proxy string
+
+	caCert             string
+	insecureSkipVerify bool
+
+	otlpEndpoint string
+
+	pprof string
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	summaryFile string
+
+	requestLog string
+
+	maxMemory string
+
+	graphQL      bool
+	graphQLBatch int
+
+	progress    bool
+	tui         bool
+	console     string
+	errorStream bool
+
+	apiURL      string
+	timeout     time.Duration
+	retries     int
+	userTimeout time.Duration
+	hedgeAfter  time.Duration
+	rps         float64
+
+	qualityReport string
+	strict        bool
+
+	schemas bool
+
+	redact string
+
+	verifyFollowers bool
+	minFollowers    int
+	followerMargin  int
+
+	includeOrgs bool
+
+	statsJSON string
+
+	languageMap string
+
+	phaseBudget string
+
+	failOnErrors   bool
+	failUnderUsers int
+	warnOnly       bool
+
+	derivedColumns bool
+
+	cleanText   bool
+	pinyinTable string
+
+	bioLanguage bool
+
+	classifyURL   string
+	classifyModel string
+	classifyKey   string
+	classifyBatch int
+	classifyCache string
+
+	seenStore string
+}
+
+func (o *scrapeOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.token, "token", "", "GitHub token (default $GITHUB_TOKEN); without one the run is unauthenticated, paced, and capped")
+	fs.StringVar(&o.userAgent, "user-agent", defaultUserAgent, "User-Agent sent with every request")
+	fs.Func("header", "extra `Name: value` header sent with every request (repeatable)", func(s string) error {
+		o.headers = append(o.headers, s)
+		return nil
+	})
+	fs.StringVar(&o.proxy, "proxy", "", "send requests through this proxy, e.g. http://host:3128 or socks5://host:1080 (default $HTTPS_PROXY)")
+	fs.StringVar(&o.caCert, "ca-cert", "", "also trust the PEM CA certificates in this file, e.g. for a TLS-intercepting proxy")
+	fs.BoolVar(&o.insecureSkipVerify, "insecure-skip-verify", false, "do not verify TLS certificates (unsafe; for testing only)")
+	fs.StringVar(&o.otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "export trace spans to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	fs.StringVar(&o.pprof, "pprof", "", "serve pprof endpoints on this address during the run, e.g. :6060")
+	fs.IntVar(&o.breakerThreshold, "breaker-threshold", defaultBreakerThreshold, "pause all requests after this many consecutive API failures (0 = never)")
+	fs.DurationVar(&o.breakerCooldown, "breaker-cooldown", defaultBreakerCooldown, "how long requests pause before a probe once the breaker opens")
+	fs.StringVar(&o.summaryFile, "summary-file", "run_summary.json", "write the run summary, with time and API calls per phase, to this JSON file")
+	fs.StringVar(&o.requestLog, "request-log", "", "append every API request (URL, status, duration, rate limit remaining) to this NDJSON file")
+	fs.StringVar(&o.maxMemory, "max-memory", "", "keep at most this much repo data in memory, e.g. 512MB, spilling the rest to a temporary file")
+	fs.BoolVar(&o.graphQL, "graphql", false, "fetch user details through the GraphQL API, many users per call (needs a token)")
+	fs.IntVar(&o.graphQLBatch, "graphql-batch", graphQLBatch, "users per GraphQL query with --graphql")
+	fs.BoolVar(&o.progress, "progress", false, "show a progress line on stderr")
+	fs.StringVar(&o.console, "console", "pretty", "console output: pretty (phase banners, colors, and a digest of failed requests; plain when stdout is not a terminal), plain, or json (one object per line, for scripts)")
+	fs.BoolVar(&o.errorStream, "error-stream", false, "write every failed API request to stderr as a JSON line with its phase, login, status, and whether it is retryable")
+	fs.BoolVar(&o.tui, "tui", false, "show a live status screen on stderr; type p to pause, r to resume, f to flush outputs so far (redirect stdout to keep the run's messages)")
+	fs.StringVar(&o.apiURL, "api-url", defaultBaseURL, "GitHub API root, e.g. https://github.example.com/api/v3 for GitHub Enterprise")
+	fs.DurationVar(&o.timeout, "timeout", 10*time.Second, "timeout of each API request")
+	fs.DurationVar(&o.userTimeout, "user-timeout", 0, "give up on a user's details or repos after this long, e.g. 30s (0 = no limit)")
+	fs.DurationVar(&o.hedgeAfter, "hedge-after", 0, "send a second copy of a GET unanswered after this long and use the first answer, e.g. 2s (each copy is an API call)")
+	fs.Float64Var(&o.rps, "rps", 0, "send at most this many API requests a second, e.g. 0.5 for a gentle overnight run (0 = no limit)")
+	fs.IntVar(&o.retries, "retries", 0, "retry requests failing with a network error or 5xx status this many times, backing off exponentially")
+	fs.StringVar(&o.qualityReport, "quality-report", "data_quality.json", "check the exported CSVs for empty or duplicate keys, malformed timestamps, and bad counts, and write the findings to this JSON file (empty = skip)")
+	fs.BoolVar(&o.strict, "strict", false, "fail the run before any further export if --quality-report finds problems")
+	fs.BoolVar(&o.schemas, "schemas", false, "write CSVW metadata for the CSVs (csv-metadata.json) and JSON Schemas for users and repos")
+	fs.StringVar(&o.redact, "redact", "", "blank or hash these columns in users.csv, repositories.csv, partitions, templates, and exporters, e.g. email:hash,bio")
+	fs.BoolVar(&o.verifyFollowers, "verify-followers", false, "drop users whose profile shows fewer followers than the minimum, re-checking borderline cached profiles with the API")
+	fs.IntVar(&o.minFollowers, "min-followers", 0, "follower minimum for --verify-followers (default: taken from the search query)")
+	fs.IntVar(&o.followerMargin, "follower-margin", 10, "percent above the minimum within which --verify-followers re-checks cached profiles")
+	fs.BoolVar(&o.includeOrgs, "include-orgs", false, "keep organisation accounts in users.csv and fetch their repos; they are always listed in orgs.csv")
+	fs.StringVar(&o.statsJSON, "stats-json", "", "write headline counts, medians, and top languages to this JSON file, e.g. stats.json for badges")
+	fs.StringVar(&o.languageMap, "language-map", "", "YAML file renaming language labels in repositories.csv, e.g. \"Jupyter Notebook: Python (notebooks)\"")
+	fs.StringVar(&o.phaseBudget, "phase-budget", "", "cap each phase's share of --max-api-calls or the rate limit, e.g. search=20%,details=40%,repos=40%")
+	fs.BoolVar(&o.failOnErrors, "fail-on-errors", false, fmt.Sprintf("exit with status %d if the users can't be fetched or saved, or any user's details or repos or any export failed", exitPolicyFailed))
+	fs.IntVar(&o.failUnderUsers, "fail-under-users", 0, fmt.Sprintf("exit with status %d if fewer than this many users are exported (0 = never)", exitPolicyFailed))
+	fs.BoolVar(&o.warnOnly, "warn-only", false, "report --fail-on-errors, --fail-under-users, and --strict failures as warnings and exit 0")
+	fs.DurationVar(&o.deadline, "deadline", 0, "stop the run after this long, e.g. 2h")
+	fs.IntVar(&o.maxCalls, "max-api-calls", 0, "stop the run after this many API calls")
+	fs.StringVar(&o.checkpoint, "checkpoint", "checkpoint.json", "where to write the checkpoint when a limit stops the run")
+	fs.StringVar(&o.cacheDir, "cache-dir", "", "cache user and repo responses in this directory")
+	fs.DurationVar(&o.cacheTTL, "cache-ttl", 24*time.Hour, "how long cached responses stay fresh (0 = forever); stale ones are revalidated with If-Modified-Since")
+
+	fs.StringVar(&o.sheetsID, "sheets-id", "", "also export to this Google Sheets spreadsheet ID")
+	fs.StringVar(&o.sheetsCredentials, "sheets-credentials", "", "service-account key file for Google Sheets")
+	fs.StringVar(&o.sheetsUsersTab, "sheets-users-tab", "users", "Google Sheets tab for users")
+	fs.StringVar(&o.sheetsReposTab, "sheets-repos-tab", "repositories", "Google Sheets tab for repos")
+
+	fs.StringVar(&o.bigQueryDataset, "bigquery-dataset", "", "also load into this BigQuery project.dataset")
+	fs.StringVar(&o.bigQueryCredentials, "bigquery-credentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "service-account key file for BigQuery")
+	fs.StringVar(&o.bigQueryUsersTable, "bigquery-users-table", "users", "BigQuery table for users")
+	fs.StringVar(&o.bigQueryReposTable, "bigquery-repos-table", "repositories", "BigQuery table for repos")
+
+	fs.StringVar(&o.esURL, "es-url", "", "also index into Elasticsearch/OpenSearch at this URL (user:pass@ allowed)")
+	fs.StringVar(&o.esPrefix, "es-index-prefix", "tds", "prefix of the users and repositories indices")
+	fs.StringVar(&o.esAPIKey, "es-api-key", os.Getenv("ES_API_KEY"), "Elasticsearch API key")
+
+	fs.StringVar(&o.kafkaREST, "kafka-rest", "", "publish records as they are fetched via this Kafka REST Proxy URL")
+	fs.StringVar(&o.kafkaUsersTopic, "kafka-users-topic", "tds.users", "Kafka topic for users")
+	fs.StringVar(&o.kafkaReposTopic, "kafka-repos-topic", "tds.repositories", "Kafka topic for repos")
+
+	fs.StringVar(&o.mongoURI, "mongo-uri", "", "also upsert into MongoDB at this mongodb:// URI (database in the path)")
+	fs.StringVar(&o.mongoUsersColl, "mongo-users-collection", "users", "MongoDB collection for users")
+	fs.StringVar(&o.mongoReposColl, "mongo-repos-collection", "repositories", "MongoDB collection for repos")
+
+	fs.StringVar(&o.duckDB, "duckdb", "", "also build this DuckDB database file (with an init .sql next to it)")
+
+	fs.StringVar(&o.notifyWebhook, "notify-webhook", "", "post a completion summary to this Slack or Discord webhook")
+
+	fs.StringVar(&o.smtpHost, "smtp-host", "", "mail the completion summary through this SMTP server (host:port)")
+	fs.StringVar(&o.smtpUser, "smtp-user", "", "SMTP username")
+	fs.StringVar(&o.smtpPassword, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password")
+	fs.StringVar(&o.mailFrom, "mail-from", "", "sender address of the summary mail")
+	fs.StringVar(&o.mailTo, "mail-to", "", "comma-separated recipients of the summary mail")
+	fs.BoolVar(&o.mailAttach, "mail-attach", false, "attach the output files (zipped if needed) to the summary mail")
+	fs.IntVar(&o.mailAttachMax, "mail-attach-max-mb", 10, "largest attachment size in MB")
+
+	fs.StringVar(&o.gitRepo, "git-repo", "", "commit and push the outputs to this git repository (local path or URL)")
+	fs.StringVar(&o.gitBranch, "git-branch", "main", "branch to commit the outputs to")
+	fs.StringVar(&o.gitDir, "git-dir", ".", "directory inside the git repository for the outputs")
+
+	fs.StringVar(&o.publish, "publish", "", "publish the outputs to GitHub: gist or release:owner/repo")
+
+	fs.StringVar(&o.compress, "compress", "", "compress the outputs: gzip (one .gz per file) or zip (one bundle)")
+
+	fs.StringVar(&o.partitionBy, "partition-by", "", "also write one file per language (repos/), company or location (users/)")
+
+	fs.StringVar(&o.template, "template", "", "render each user and their repos through this Go text/template file")
+	fs.StringVar(&o.templateOut, "template-out", "template_output.txt", "output file for --template; a template such as profiles/{{.Login}}.md writes one file per user")
+
+	fs.StringVar(&o.upload, "upload", "", "upload output files to s3://bucket/prefix or gs://bucket/prefix")
+
+	fs.BoolVar(&o.events, "events", false, "add last public activity and a recency score per user (one API call per user)")
+
+	fs.BoolVar(&o.sponsors, "sponsors", false, "add GitHub Sponsors listing and sponsor count per user (GraphQL, one call per 50 users)")
+
+	fs.BoolVar(&o.commitEmails, "harvest-commit-emails", false, "fill missing profile emails from the user's own recent commits (noreply addresses are skipped)")
+	fs.StringVar(&o.commitEmailOptOut, "commit-email-optout", "", "file of logins, one per line, never to harvest commit emails for")
+
+	fs.StringVar(&o.cohorts, "cohorts", "", "tag each user with a follower band, e.g. \"200-500,500-2000,2000+\"")
+
+	fs.StringVar(&o.org, "org", "", "scrape this organisation's members instead of the location search")
+	fs.StringVar(&o.teamsOut, "teams", "", "with --org, also record team memberships (needs read:org) in a teams column and this CSV")
+
+	fs.StringVar(&o.techFilter, "tech-filter", "", "comma-separated technologies to check each user's code for, e.g. \"flink,spark\" (code search, 10 checks a minute)")
+
+	fs.StringVar(&o.query, "query", "", "run this user search instead of \"location:Shanghai followers:>200\", e.g. \"location:Beijing followers:>500\"; with --country only its followers qualifier is used")
+	fs.StringVar(&o.country, "country", "", "search a country's major cities and names instead of Shanghai, e.g. China, recording the matched searches per user")
+	fs.StringVar(&o.loginsFile, "logins-file", "", "fetch details and repos for the logins in this file, one per line, or - for stdin, instead of searching")
+	fs.StringVar(&o.excludeLogins, "exclude-logins", "", "skip users and orgs whose login matches a pattern in this file, one per line, e.g. known bots")
+	fs.StringVar(&o.onlyLogins, "only-logins", "", "keep only users and orgs whose login matches a pattern in this file, one per line")
+	fs.StringVar(&o.seedCodeQuery, "seed-code-query", "", "seed users from the owners of code search matches instead of the location search, e.g. \"import flink language:java\"")
+
+	fs.StringVar(&o.dependencies, "dependencies", "", "also export every non-fork repo's SBOM dependencies to this CSV (one API call per repo)")
+
+	fs.BoolVar(&o.traffic, "traffic", false, "add 14-day views and clones for repos the token can push to")
+
+	fs.BoolVar(&o.resolveForks, "resolve-forks", false, "look up the upstream of every fork for root_repo (one API call per fork)")
+	fs.BoolVar(&o.subscribers, "subscribers", false, "look up every repo for subscribers_count, its true watchers, and fork roots (one API call per repo)")
+
+	fs.StringVar(&o.seenStore, "seen-store", "", "skip users whose details and repos a run with the same store already fetched, recording each fetched login in this directory; users.csv and repositories.csv keep the rows of earlier runs, other outputs hold only the users new to the run")
+
+	fs.StringVar(&o.historyDir, "history-dir", "", "also keep a timestamped copy of users.csv and repositories.csv here, for trending")
+
+	fs.StringVar(&o.edges, "edges", "", "also collect follow edges among the scraped users into this CSV")
+
+	fs.BoolVar(&o.cleanText, "clean-text", false, "strip control characters from names, bios, companies, and locations, compose them to Unicode NFC, and collapse whitespace")
+	fs.StringVar(&o.pinyinTable, "pinyin-table", "", "add a name_pinyin column transliterating Chinese names, using the kMandarin readings in this Unihan_Readings.txt")
+
+	fs.BoolVar(&o.bioLanguage, "bio-language", false, "add a bio_language column with the detected language of each bio, e.g. zh or en")
+
+	fs.StringVar(&o.classifyURL, "classify-url", "", "add role and seniority_hint columns by sending bios and companies to this OpenAI-compatible chat completions endpoint, e.g. http://localhost:11434/v1/chat/completions")
+	fs.StringVar(&o.classifyModel, "classify-model", "", "model to ask with --classify-url, e.g. llama3.1")
+	fs.StringVar(&o.classifyKey, "classify-key", os.Getenv("LLM_API_KEY"), "API key for --classify-url")
+	fs.IntVar(&o.classifyBatch, "classify-batch", 20, "users per request to --classify-url")
+	fs.StringVar(&o.classifyCache, "classify-cache", "classifications.json", "cache of --classify-url answers, so reruns only send new or changed bios")
+
+	fs.BoolVar(&o.derivedColumns, "derived-columns", false, "add followers_following_ratio, repos_per_year, and stars_per_repo columns to users.csv")
+
+	fs.StringVar(&o.bioKeywords, "bio-keywords", "", "comma-separated keywords to tag users by bio, e.g. \"kubernetes,rust,ml\"")
+}
+
+// exporters builds the exporters enabled by the options.
+func (o *scrapeOptions) exporters() ([]exporter, error) {
+	var out []exporter
+	if o.sheetsID != "" {
+		if o.sheetsCredentials == "" {
+			return nil, errors.New("--sheets-id requires --sheets-credentials")
+		}
+		account, err := loadGoogleServiceAccount(o.sheetsCredentials)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &sheetsExporter{
+			account:       account,
+			spreadsheetID: o.sheetsID,
+			usersTab:      o.sheetsUsersTab,
+			reposTab:      o.sheetsReposTab,
+		})
+	}
+	if o.bigQueryDataset != "" {
+		if o.bigQueryCredentials == "" {
+			return nil, errors.New("--bigquery-dataset requires --bigquery-credentials")
+		}
+		account, err := loadGoogleServiceAccount(o.bigQueryCredentials)
+		if err != nil {
+			return nil, err
+		}
+		project, dataset, err := parseBigQueryDataset(o.bigQueryDataset, account)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &bigQueryExporter{
+			account:    account,
+			project:    project,
+			dataset:    dataset,
+			usersTable: o.bigQueryUsersTable,
+			reposTable: o.bigQueryReposTable,
+		})
+	}
+	if o.esURL != "" {
+		out = append(out, &esExporter{url: o.esURL, prefix: o.esPrefix, apiKey: o.esAPIKey})
+	}
+	if o.mongoURI != "" {
+		mongo, err := newMongoExporter(o.mongoURI, o.mongoUsersColl, o.mongoReposColl)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mongo)
+	}
+	if o.duckDB != "" {
+		out = append(out, &duckDBExporter{path: o.duckDB})
+	}
+	return out, nil
+}
+
+// checkpoint records how far a run got before it was stopped early.
+type checkpoint struct {
+	Reason    string   `json:"reason"`
+	Phase     string   `json:"phase"`
+	StoppedAt string   `json:"stopped_at"`
+	APICalls  int64    `json:"api_calls"`
+	Searched  []string `json:"searched"`
+	Detailed  []string `json:"detailed"`
+	WithRepos []string `json:"with_repos"`
+}
+
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func logins(users []User) []string {
+	out := make([]string, len(users))
+	for i, u := range users {
+		out[i] = u.Login
+	}
+	return out
+}
+
+// stopReason reports why the run must stop early, or "" if it may continue.
+func stopReason(ctx context.Context, c *apiClient) string {
+	switch {
+	case ctx.Err() != nil:
+		return "deadline"
+	case c.exhausted():
+		return "max-api-calls"
+	}
+	return ""
+}
+
+// artifactName is the name of an output file inside bundles and uploads:
+// its relative path, or its base name when it lies outside the working
+// directory.
+func artifactName(file string) string {
+	name := filepath.ToSlash(filepath.Clean(file))
+	if filepath.IsAbs(file) || strings.HasPrefix(name, "../") {
+		return filepath.Base(file)
+	}
+	return name
+}
+
+// runScrape runs the search, details, and repos pipeline.
+func runScrape(args []string) {
+	var opts scrapeOptions
+	fs := flag.NewFlagSet("tds", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	if !slices.Contains(consoleModes, opts.console) {
+		fmt.Println("Error: --console must be pretty, plain, or json")
+		return
+	}
+	display, err := newConsole(opts.console)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer display.close()
+	exporters, err := opts.exporters()
+	if err != nil {
+		fmt.Println("Error configuring exporters:", err)
+		return
+	}
+	if opts.upload != "" {
+		if _, _, err := parseUploadURL(opts.upload); err != nil {
+			fmt.Println("Error configuring upload:", err)
+			return
+		}
+	}
+	var publishTo publishTarget
+	if opts.publish != "" {
+		if publishTo, err = parsePublishTarget(opts.publish); err != nil {
+			fmt.Println("Error configuring publish:", err)
+			return
+		}
+	}
+	if opts.compress != "" && opts.compress != "none" && opts.compress != "gzip" && opts.compress != "zip" {
+		fmt.Println("Error: --compress must be gzip or zip")
+		return
+	}
+	if publishTo.kind == "gist" && opts.compress != "" && opts.compress != "none" {
+		fmt.Println("Error: gists hold text only, so --publish gist cannot take --compress outputs; use --publish release:owner/repo")
+		return
+	}
+	for flag, list := range map[string]string{"--bio-keywords": opts.bioKeywords, "--tech-filter": opts.techFilter} {
+		if _, err := tagSlugs(parseKeywords(list)); err != nil {
+			fmt.Printf("Error: %s: %v\n", flag, err)
+			return
+		}
+	}
+	switch opts.partitionBy {
+	case "", "language", "company", "location":
+	default:
+		fmt.Println("Error: --partition-by must be language, company, or location")
+		return
+	}
+	var userTmpl *template.Template
+	if opts.template != "" {
+		if userTmpl, err = parseUserTemplate(opts.template); err != nil {
+			fmt.Println("Error loading template:", err)
+			return
+		}
+	}
+	optOut, err := loadOptOuts(opts.commitEmailOptOut)
+	if err != nil {
+		fmt.Println("Error loading opt-out list:", err)
+		return
+	}
+	filter, err := loadLoginFilter(opts.excludeLogins, opts.onlyLogins)
+	if err != nil {
+		fmt.Println("Error loading login lists:", err)
+		return
+	}
+	cohorts, err := parseCohorts(opts.cohorts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	phaseShares, err := parsePhaseBudget(opts.phaseBudget)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	var classify *classifier
+	if opts.classifyURL != "" {
+		if opts.classifyModel == "" || opts.classifyBatch < 1 || opts.classifyBatch > 100 {
+			fmt.Println("Error: --classify-url needs --classify-model and a --classify-batch between 1 and 100")
+			return
+		}
+		if classify, err = newClassifier(opts.classifyURL, opts.classifyModel, opts.classifyKey, opts.classifyCache, opts.classifyBatch); err != nil {
+			fmt.Println("Error loading classifier cache:", err)
+			return
+		}
+	}
+	var pinyin pinyinTable
+	if opts.pinyinTable != "" {
+		if pinyin, err = loadPinyinTable(opts.pinyinTable); err != nil {
+			fmt.Println("Error loading pinyin table:", err)
+			return
+		}
+	}
+	languages, err := loadLanguageMap(opts.languageMap)
+	if err != nil {
+		fmt.Println("Error loading language map:", err)
+		return
+	}
+	if opts.tui && (opts.progress || opts.loginsFile == "-") {
+		fmt.Println("Error: --tui cannot be combined with --progress or --logins-file -")
+		return
+	}
+	if opts.errorStream && (opts.progress || opts.tui) {
+		fmt.Println("Error: --error-stream cannot be combined with --progress or --tui, which also draw on stderr")
+		return
+	}
+	if opts.query != "" && (opts.loginsFile != "" || opts.org != "" || opts.seedCodeQuery != "") {
+		fmt.Println("Error: --query cannot be combined with --logins-file, --org, or --seed-code-query")
+		return
+	}
+	if opts.country != "" {
+		if opts.loginsFile != "" || opts.org != "" || opts.seedCodeQuery != "" {
+			fmt.Println("Error: --country cannot be combined with --logins-file, --org, or --seed-code-query")
+			return
+		}
+		if _, err := countryQueries(opts.country); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+	if opts.loginsFile != "" && (opts.org != "" || opts.seedCodeQuery != "") {
+		fmt.Println("Error: --logins-file cannot be combined with --org or --seed-code-query")
+		return
+	}
+	if opts.teamsOut != "" && opts.org == "" {
+		fmt.Println("Error: --teams requires --org")
+		return
+	}
+	red, err := parseRedaction(opts.redact)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if opts.verifyFollowers && opts.minFollowers == 0 {
+		if opts.loginsFile != "" || opts.org != "" || opts.seedCodeQuery != "" {
+			fmt.Println("Error: --verify-followers needs --min-followers unless users come from the location search")
+			return
+		}
+		opts.minFollowers = queryMinFollowers(cmp.Or(opts.query, defaultUserQuery))
+	}
+	if opts.strict && opts.qualityReport == "" {
+		fmt.Println("Error: --strict requires --quality-report")
+		return
+	}
+	if opts.graphQL && (opts.graphQLBatch < 1 || opts.graphQLBatch > 100) {
+		fmt.Println("Error: --graphql-batch must be between 1 and 100")
+		return
+	}
+	var memLimit int64
+	if opts.maxMemory != "" {
+		if memLimit, err = parseByteSize(opts.maxMemory); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if opts.resolveForks || opts.subscribers || opts.traffic || opts.dependencies != "" || opts.partitionBy != "" || opts.template != "" || opts.statsJSON != "" || len(exporters) > 0 {
+			fmt.Println("Error: --max-memory cannot be combined with options that need every repo in memory: --resolve-forks, --subscribers, --traffic, --dependencies, --partition-by, --template, --stats-json, or remote exporters")
+			return
+		}
+	}
+	if opts.smtpHost != "" && (opts.mailFrom == "" || opts.mailTo == "") {
+		fmt.Println("Error configuring mail: --smtp-host requires --mail-from and --mail-to")
+		return
+	}
+
+	if opts.pprof != "" {
+		startPprof(opts.pprof)
+	}
+	ctx := context.Background()
+	if opts.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.deadline)
+		defer cancel()
+	}
+	client := newClient(
+		withToken(cmp.Or(opts.token, os.Getenv("GITHUB_TOKEN"), githubToken)),
+		withBaseURL(opts.apiURL),
+		withTimeout(opts.timeout),
+		withRetry(opts.retries),
+		withUserTimeout(opts.userTimeout),
+		withHedge(opts.hedgeAfter),
+		withRPS(opts.rps),
+		withMaxCalls(opts.maxCalls),
+	)
+	if !client.authenticated() {
+		fmt.Printf("Warning: no GitHub token configured; running unauthenticated at one request a minute, capped at %d API calls.\n", client.maxCalls)
+		fmt.Println("Set GITHUB_TOKEN or pass --token for a full run.")
+		if opts.graphQL {
+			fmt.Println("Error: --graphql needs a GitHub token")
+			return
+		}
+	}
+	client.breaker = newBreaker(opts.breakerThreshold, opts.breakerCooldown)
+	display.watch(client)
+	client.header.Set("User-Agent", opts.userAgent)
+	for _, h := range opts.headers {
+		name, value, err := parseHeader(h)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		client.header.Add(name, value)
+	}
+	if opts.proxy != "" {
+		if err := client.setProxy(opts.proxy); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+	if opts.caCert != "" || opts.insecureSkipVerify {
+		if err := client.setTLS(opts.caCert, opts.insecureSkipVerify); err != nil {
+			fmt.Println("Error configuring TLS:", err)
+			return
+		}
+	}
+	if opts.insecureSkipVerify {
+		fmt.Println("Warning: TLS certificate verification is disabled.")
+	}
+	if opts.requestLog != "" {
+		log, err := openRequestLog(opts.requestLog)
+		if err != nil {
+			fmt.Println("Error opening request log:", err)
+			return
+		}
+		defer log.close()
+		client.addResponseHook(log.record)
+	}
+	if opts.progress {
+		client.setProgress(printProgress)
+	}
+	// Exports to remote sinks go through the same proxy and TLS settings.
+	sinkClient.Transport = client.http.Transport
+	if opts.cacheDir != "" {
+		cache, err := newResponseCache(opts.cacheDir, opts.cacheTTL)
+		if err != nil {
+			fmt.Println("Error opening cache:", err)
+			return
+		}
+		client.cache = cache
+	}
+	var kafka *kafkaProducer
+	if opts.kafkaREST != "" {
+		kafka = newKafkaProducer(opts.kafkaREST, opts.kafkaUsersTopic, opts.kafkaReposTopic)
+		client.onUser = func(u User) { kafka.publishUser(redactItem(red, u)) }
+		client.onRepos = func(_ string, repos []Repo) { kafka.publishRepos(redactItems(red, repos)) }
+	}
+	var seen *seenStore
+	// prevUsers and prevRepos are the outputs of the earlier runs sharing
+	// the seen store, which this run's outputs are merged with.
+	var prevUsers, prevRepos csvTable
+	if opts.seenStore != "" {
+		if seen, err = openSeenStore(opts.seenStore); err != nil {
+			fmt.Println("Error opening seen store:", err)
+			return
+		}
+		if prevUsers, err = readCSVTable("users.csv"); err == nil {
+			prevRepos, err = readCSVTable("repositories.csv")
+		}
+		if err != nil {
+			fmt.Println("Error reading the outputs of earlier runs:", err)
+			return
+		}
+		// A login counts as seen once its repos are in, the last of what
+		// the run fetches per user.
+		onRepos, reported := client.onRepos, false
+		client.onRepos = func(login string, repos []Repo) {
+			if err := seen.add(login); err != nil && !reported {
+				fmt.Println("Error recording fetched logins in seen store:", err)
+				reported = true
+			}
+			if onRepos != nil {
+				onRepos(login, repos)
+			}
+		}
+	}
+	var screen *tui
+	if opts.tui {
+		screen = newTUI(client, red)
+		defer screen.close()
+	}
+	// exit ends the process once the status screen and console are done.
+	exit := func(status int) {
+		screen.close()
+		display.close()
+		os.Exit(status)
+	}
+	var trace *tracer
+	if opts.otlpEndpoint != "" {
+		trace = newTracer(opts.otlpEndpoint)
+	}
+	ctx, root := trace.start(ctx, "scrape")
+	cp := checkpoint{Phase: "search"}
+	started := time.Now()
+	clock := newPhaseClock(client)
+	clock.console = display
+	if opts.errorStream {
+		client.addResponseHook(newErrorStream(os.Stderr, clock.phase).record)
+	}
+	if len(phaseShares) > 0 {
+		clock.budget = client.setPhaseBudget(phaseShares)
+	}
+	clock.enter("search")
+	// artifacts lists the files written by this run, for uploading.
+	var artifacts []string
+
+	var users []User
+	var matchedQueries map[string][]string
+	searchCtx, sp := startSpan(ctx, "search")
+	switch {
+	case opts.country != "":
+		followers := followersQualifier.FindString(cmp.Or(opts.query, defaultUserQuery))
+		users, matchedQueries, err = client.searchCountry(searchCtx, opts.country, followers, seen)
+	case opts.loginsFile != "":
+		var list []string
+		list, err = readLoginList(opts.loginsFile)
+		users = usersFromLogins(list)
+	case opts.org != "":
+		users, err = client.fetchOrgMembers(searchCtx, opts.org)
+	case opts.seedCodeQuery != "":
+		users, err = client.fetchUsersFromCodeSearch(searchCtx, opts.seedCodeQuery)
+	default:
+		if opts.query != "" {
+			users, err = client.searchUsers(searchCtx, url.QueryEscape(opts.query))
+		} else {
+			users, err = client.fetchUsersInShanghai(searchCtx)
+		}
+	}
+	sp.finish(err)
+	if errors.Is(err, errPhaseBudgetExhausted) {
+		// Like the later phases, the search stops at its share and the
+		// run goes on with what it found.
+		fmt.Printf("Continuing with the %d users found within the search phase's budget\n", len(users))
+		err = nil
+	}
+	if err != nil && stopReason(ctx, client) == "" {
+		fmt.Println("Error fetching users:", err)
+		if opts.failOnErrors && opts.policyFailed("--fail-on-errors: the users could not be fetched") {
+			exit(exitPolicyFailed)
+		}
+		return
+	}
+	users, dropped := filter.apply(users)
+	if dropped > 0 {
+		fmt.Printf("Skipped %d of %d users by --exclude-logins and --only-logins\n", dropped, dropped+len(users))
+	}
+	// searchCountry dedupes through the store itself as its searches
+	// overlap.
+	if seen != nil && opts.country == "" {
+		var known int
+		if users, known, err = seen.filter(users); err != nil {
+			fmt.Println("Error reading seen store:", err)
+			return
+		}
+		fmt.Printf("Skipped %d of %d users fetched by earlier runs (--seen-store)\n", known, known+len(users))
+	}
+	cp.Searched = logins(users)
+
+	detailedUsers := users
+	// excluded counts users set aside after the details phase, by
+	// --verify-followers or as organisations, who are not detail failures.
+	excluded := 0
+	if stopReason(ctx, client) == "" {
+		cp.Phase = "details"
+		clock.enter("details")
+		detailsCtx, sp := startSpan(ctx, "details")
+		if opts.graphQL {
+			detailedUsers = client.fetchUserDetailsGraphQL(detailsCtx, users, opts.graphQLBatch)
+		} else {
+			detailedUsers = client.fetchUserDetailsConcurrently(detailsCtx, users)
+		}
+		sp.finish(nil)
+		if opts.verifyFollowers && stopReason(ctx, client) == "" {
+			var dropped int
+			detailedUsers, dropped = client.verifyFollowers(ctx, detailedUsers, opts.minFollowers, opts.followerMargin)
+			excluded += dropped
+			fmt.Printf("Dropped %d users with fewer than %d followers\n", dropped, opts.minFollowers)
+		}
+		people, orgs := splitOrgs(detailedUsers)
+		if len(orgs) > 0 {
+			if err := writeRecordsCSV("orgs.csv", orgColumns, client.fetchOrgAccounts(ctx, orgs), orgRecord); err != nil {
+				fmt.Println("Error saving orgs:", err)
+			} else {
+				artifacts = append(artifacts, "orgs.csv")
+			}
+			if !opts.includeOrgs {
+				detailedUsers = people
+				excluded += len(orgs)
+			}
+			fmt.Printf("Found %d organisation accounts\n", len(orgs))
+		}
+		cp.Detailed = logins(detailedUsers)
+		if seen != nil && stopReason(ctx, client) == "" {
+			// Users set aside here, or whose details could not be
+			// fetched, never reach the repos phase that records the rest.
+			kept := map[string]bool{}
+			for _, u := range detailedUsers {
+				kept[strings.ToLower(u.Login)] = true
+			}
+			for _, u := range users {
+				if !kept[strings.ToLower(u.Login)] {
+					if err := seen.add(u.Login); err != nil {
+						fmt.Println("Error recording fetched logins in seen store:", err)
+						break
+					}
+				}
+			}
+		}
+	}
+	clock.enter("enrichments")
+	if opts.cleanText {
+		if n := cleanUsers(detailedUsers); n > 0 {
+			fmt.Printf("Cleaned the text of %d users\n", n)
+		}
+	}
+	var extras []columnSet[User]
+	if pinyin != nil {
+		extras = append(extras, pinyinColumns(pinyin))
+	}
+	if opts.bioLanguage {
+		extras = append(extras, bioLanguageColumns())
+	}
+	if classify != nil && stopReason(ctx, client) == "" {
+		// Only what would be exported is sent, so --redact bio keeps bios
+		// from the endpoint.
+		labels, err := classify.classify(ctx, redactItems(red, detailedUsers))
+		if err != nil {
+			fmt.Println("Error classifying users:", err)
+		}
+		extras = append(extras, classificationColumns(labels))
+	}
+	if matchedQueries != nil {
+		extras = append(extras, matchedQueryColumns(matchedQueries))
+	}
+	if len(cohorts) > 0 {
+		extras = append(extras, cohortColumns(cohorts))
+	}
+	if opts.events && stopReason(ctx, client) == "" {
+		extras = append(extras, activityColumns(client.fetchLastActivity(ctx, detailedUsers), time.Now()))
+	}
+	if opts.commitEmails && stopReason(ctx, client) == "" {
+		extras = append(extras, emailSourceColumns(client.harvestCommitEmails(ctx, detailedUsers, optOut)))
+	}
+	if opts.teamsOut != "" && stopReason(ctx, client) == "" {
+		teams, err := client.fetchTeams(ctx, opts.org)
+		if err != nil {
+			fmt.Println("Error fetching teams:", err)
+		} else if err := writeRecordsCSV(opts.teamsOut, teamColumns, teams, teamRecord); err != nil {
+			fmt.Println("Error saving teams:", err)
+		} else {
+			artifacts = append(artifacts, opts.teamsOut)
+			extras = append(extras, teamsColumns(teams))
+		}
+	}
+	if techs := parseKeywords(opts.techFilter); len(techs) > 0 && stopReason(ctx, client) == "" {
+		extras = append(extras, techColumns(techs, client.fetchTechUsage(ctx, detailedUsers, techs)))
+	}
+	if opts.sponsors && stopReason(ctx, client) == "" {
+		info, err := client.fetchSponsors(ctx, detailedUsers)
+		if err != nil {
+			fmt.Println("Error fetching sponsors:", err)
+		}
+		extras = append(extras, sponsorColumns(info))
+	}
+	keywords := parseKeywords(opts.bioKeywords)
+	if len(keywords) > 0 {
+		extras = append(extras, bioTagColumns(keywords))
+	}
+	// tallies fills in as repos arrive; users.csv is saved again with them.
+	tallies := map[string]repoTally{}
+	if opts.derivedColumns {
+		extras = append(extras, derivedColumns(time.Now(), tallies))
+	}
+	clock.enter("export")
+	exportUsers := redactItems(red, detailedUsers)
+	if err := saveUsersToCSV(detailedUsers, red, redactColumns(red, extras)); err != nil {
+		fmt.Println("Error saving users to CSV:", err)
+		if opts.failOnErrors && opts.policyFailed("--fail-on-errors: the users could not be saved") {
+			exit(exitPolicyFailed)
+		}
+		return
+	}
+	if len(keywords) > 0 {
+		tagged := 0
+		for _, u := range detailedUsers {
+			if len(matchKeywords(u.Bio, keywords)) > 0 {
+				tagged++
+			}
+		}
+		fmt.Printf("Tagged %d of %d users by bio keywords\n", tagged, len(detailedUsers))
+	}
+	artifacts = append(artifacts, "users.csv")
+
+	var allRepos, exportRepos []Repo
+	var repoExtras []columnSet[Repo]
+	repoCount := 0
+	if stopReason(ctx, client) == "" && memLimit > 0 {
+		cp.Phase = "repos"
+		clock.enter("repos")
+		reposCtx, sp := startSpan(ctx, "repos")
+		repoSpill := newSpill(memLimit, repoSize)
+		var spillErr error
+		cp.WithRepos = client.streamUserRepos(reposCtx, detailedUsers, func(repos []Repo) {
+			languages.apply(repos)
+			tallyRepos(tallies, repos)
+			if err := repoSpill.add(repos...); err != nil && spillErr == nil {
+				spillErr = err
+			}
+		})
+		sp.finish(spillErr)
+		clock.enter("export")
+		err := spillErr
+		if err == nil {
+			err = saveSpilledRepos(repoSpill, red)
+		}
+		repoCount = repoSpill.len()
+		repoSpill.close()
+		if err != nil {
+			fmt.Println("Error saving repos to CSV:", err)
+		} else {
+			artifacts = append(artifacts, "repositories.csv")
+		}
+	} else if stopReason(ctx, client) == "" {
+		cp.Phase = "repos"
+		clock.enter("repos")
+		reposCtx, sp := startSpan(ctx, "repos")
+		allRepos, cp.WithRepos = client.fetchUserReposConcurrently(reposCtx, detailedUsers)
+		repoCount = len(allRepos)
+		tallyRepos(tallies, allRepos)
+		sp.finish(nil)
+		clock.enter("enrichments")
+		if n := languages.apply(allRepos); n > 0 {
+			fmt.Printf("Renamed the language of %d repos by --language-map\n", n)
+		}
+		switch {
+		case opts.subscribers:
+			client.fetchSubscribers(ctx, allRepos)
+		case opts.resolveForks:
+			client.resolveForkRoots(ctx, allRepos)
+		}
+		assignRootRepos(allRepos)
+		if opts.traffic {
+			repoExtras = append(repoExtras, trafficColumns(client.fetchTraffic(ctx, allRepos)))
+		}
+		clock.enter("export")
+		exportRepos = redactItems(red, allRepos)
+		if err := saveReposToCSV(slices.Values(allRepos), red, redactColumns(red, repoExtras)); err != nil {
+			fmt.Println("Error saving repos to CSV:", err)
+		} else {
+			artifacts = append(artifacts, "repositories.csv")
+		}
+	}
+
+	if opts.derivedColumns && len(tallies) > 0 {
+		if err := saveUsersToCSV(detailedUsers, red, redactColumns(red, extras)); err != nil {
+			fmt.Println("Error saving users to CSV:", err)
+		}
+	}
+
+	if seen != nil {
+		n, err := mergePrevious("users.csv", prevUsers, "login")
+		if err == nil && slices.Contains(artifacts, "repositories.csv") {
+			_, err = mergePrevious("repositories.csv", prevRepos, "full_name")
+		}
+		if err != nil {
+			fmt.Println("Error merging the outputs of earlier runs:", err)
+		} else if n > 0 {
+			fmt.Printf("Kept %d users from earlier runs in users.csv (--seen-store)\n", n)
+		}
+	}
+
+	if cols := red.unmatched(csvColumns(userColumns, extras), csvColumns(repoColumns, repoExtras)); len(cols) > 0 {
+		fmt.Println("Warning: --redact columns not in the export:", strings.Join(cols, ", "))
+	}
+
+	if opts.qualityReport != "" {
+		report, err := validateExports(opts.qualityReport, slices.Contains(artifacts, "repositories.csv"))
+		if err != nil {
+			fmt.Println("Error validating exports:", err)
+		} else {
+			artifacts = append(artifacts, opts.qualityReport)
+			if n := report.issues(); n > 0 {
+				fmt.Printf("Data validation found %d problems; see %s\n", n, opts.qualityReport)
+				if opts.strict && !opts.warnOnly {
+					exit(exitValidationFailed)
+				}
+			}
+		}
+	}
+
+	if opts.schemas {
+		var repoCols []string
+		if slices.Contains(artifacts, "repositories.csv") {
+			repoCols = csvColumns(repoColumns, repoExtras)
+		}
+		files, err := writeSchemas(csvColumns(userColumns, extras), repoCols)
+		if err != nil {
+			fmt.Println("Error writing schemas:", err)
+		}
+		artifacts = append(artifacts, files...)
+	}
+
+	// Only complete runs are snapshotted, so trends compare like with like.
+	if opts.historyDir != "" && cp.Phase == "repos" && stopReason(ctx, client) == "" {
+		if dir, err := saveSnapshot(opts.historyDir, started, []string{"users.csv", "repositories.csv"}); err != nil {
+			fmt.Println("Error saving history snapshot:", err)
+		} else {
+			fmt.Println("Saved history snapshot", dir)
+		}
+	}
+
+	if opts.dependencies != "" && stopReason(ctx, client) == "" {
+		cp.Phase = "dependencies"
+		clock.enter("dependencies")
+		deps := client.fetchDependencies(ctx, allRepos)
+		if err := writeRecordsCSV(opts.dependencies, dependencyColumns, deps, dependencyRecord); err != nil {
+			fmt.Println("Error saving dependencies:", err)
+		} else {
+			artifacts = append(artifacts, opts.dependencies)
+		}
+	}
+
+	if opts.edges != "" && stopReason(ctx, client) == "" {
+		cp.Phase = "edges"
+		clock.enter("edges")
+		edges := client.fetchEdgesConcurrently(ctx, detailedUsers)
+		if err := writeRecordsCSV(opts.edges, edgeColumns, edges, edgeRecord); err != nil {
+			fmt.Println("Error saving edges:", err)
+		} else {
+			artifacts = append(artifacts, opts.edges)
+		}
+	}
+
+	clock.enter("export")
+	if opts.partitionBy != "" {
+		files, err := writePartitions(opts.partitionBy, exportUsers, exportRepos)
+		if err != nil {
+			fmt.Println("Error writing partitions:", err)
+		}
+		artifacts = append(artifacts, files...)
+	}
+
+	if opts.statsJSON != "" {
+		if err := saveDashboardStats(opts.statsJSON, detailedUsers, allRepos); err != nil {
+			fmt.Println("Error saving stats:", err)
+		} else {
+			artifacts = append(artifacts, opts.statsJSON)
+		}
+	}
+
+	if userTmpl != nil {
+		files, err := renderProfiles(userTmpl, opts.templateOut, buildProfiles(exportUsers, exportRepos))
+		if err != nil {
+			fmt.Println("Error rendering template:", err)
+		}
+		artifacts = append(artifacts, files...)
+	}
+
+	if kafka != nil {
+		if err := kafka.close(); err != nil {
+			fmt.Println("Error publishing to Kafka:", err)
+		}
+	}
+
+	// Exporters get whatever the run collected, even when it stopped early,
+	// so they run on a context that is not bound by the deadline.
+	exportFailures := runExporters(context.WithoutCancel(ctx), exporters, exportUsers, exportRepos)
+
+	if cp.Reason = stopReason(ctx, client); cp.Reason != "" {
+		cp.StoppedAt = time.Now().UTC().Format(time.RFC3339)
+		cp.APICalls = client.callCount()
+		if err := saveCheckpoint(opts.checkpoint, cp); err != nil {
+			fmt.Println("Error saving checkpoint:", err)
+		} else {
+			artifacts = append(artifacts, opts.checkpoint)
+		}
+	}
+	if compressed, err := compressArtifacts(opts.compress, artifacts); err != nil {
+		fmt.Println("Error compressing outputs:", err)
+	} else {
+		artifacts = compressed
+	}
+	links := artifacts
+	if opts.upload != "" {
+		uploaded, err := uploadArtifacts(context.Background(), opts.upload, artifacts, started)
+		if err != nil {
+			fmt.Println("Error uploading outputs:", err)
+		}
+		links = uploaded
+	}
+
+	if opts.publish != "" {
+		published, err := client.publish(context.Background(), publishTo, artifacts, runSummary{
+			Started: started, Users: len(detailedUsers), Repos: repoCount, StopReason: cp.Reason,
+		})
+		if err != nil {
+			fmt.Println("Error publishing outputs:", err)
+		}
+		if published != "" {
+			fmt.Println("Published to", published)
+			links = append(links, published)
+		}
+	}
+
+	clock.stop()
+	summary := runSummary{
+		Started:        started,
+		Duration:       time.Since(started),
+		Searched:       len(users),
+		Users:          len(detailedUsers),
+		Repos:          repoCount,
+		APICalls:       client.callCount(),
+		ExportFailures: exportFailures,
+		StopReason:     cp.Reason,
+		Artifacts:      links,
+		Phases:         clock.stats,
+	}
+	if cp.Phase != "search" {
+		summary.DetailFailures = len(users) - len(detailedUsers) - excluded
+	}
+	if cp.Phase == "repos" || cp.Phase == "dependencies" || cp.Phase == "edges" {
+		summary.RepoFailures = len(detailedUsers) - len(cp.WithRepos)
+	}
+	if opts.summaryFile != "" {
+		if err := saveRunSummary(opts.summaryFile, summary); err != nil {
+			fmt.Println("Error saving run summary:", err)
+		}
+	}
+	fmt.Println("Time and API calls per phase:")
+	for _, p := range summary.Phases {
+		fmt.Printf("  %-12s %10s %8d API calls\n", p.Name, p.Duration.Round(time.Millisecond), p.APICalls)
+	}
+	if opts.gitRepo != "" {
+		publisher := &gitPublisher{repo: opts.gitRepo, branch: opts.gitBranch, dir: opts.gitDir}
+		if err := publisher.publish(context.Background(), artifacts, summary); err != nil {
+			fmt.Println("Error committing outputs:", err)
+		}
+	}
+	if opts.notifyWebhook != "" {
+		if err := postWebhook(context.Background(), opts.notifyWebhook, summary); err != nil {
+			fmt.Println("Error sending notification:", err)
+		}
+	}
+	if opts.smtpHost != "" {
+		mail := mailSettings{
+			host:          opts.smtpHost,
+			user:          opts.smtpUser,
+			password:      opts.smtpPassword,
+			from:          opts.mailFrom,
+			to:            strings.Split(opts.mailTo, ","),
+			attach:        opts.mailAttach,
+			maxAttachSize: int64(opts.mailAttachMax) << 20,
+		}
+		if err := sendSummaryMail(mail, summary, artifacts); err != nil {
+			fmt.Println("Error sending summary mail:", err)
+		}
+	}
+	root.finish(nil)
+	if err := trace.flush(context.Background()); err != nil {
+		fmt.Println("Error exporting traces:", err)
+	}
+	failed := opts.policyFailed(opts.policyViolations(summary)...)
+	if cp.Reason != "" {
+		fmt.Printf("Stopped early (%s) during %s phase after %d API calls\n", cp.Reason, cp.Phase, cp.APICalls)
+		exit(exitLimitReached)
+	}
+	if failed {
+		exit(exitPolicyFailed)
+	}
+	fmt.Println("Done")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A run stopped by --max-api-calls or --deadline writes a checkpoint and
+// exits with exitLimitReached. runScrape exits the process then, so each
+// run is a copy of the test binary that scrapes in place of the test.
+func TestScrapeStopsAtLimits(t *testing.T) {
+	if args := os.Getenv("TDS_TEST_SCRAPE_ARGS"); args != "" {
+		runScrape(strings.Split(args, "\n"))
+		os.Exit(0)
+	}
+	srv := fakeGitHub(300, 1, 20*time.Millisecond)
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		reason string
+		flags  []string
+	}{
+		{"max-api-calls", []string{"--max-api-calls", "5"}},
+		{"deadline", []string{"--deadline", "300ms"}},
+	} {
+		dir := t.TempDir()
+		args := append([]string{"--token", "x", "--api-url", srv.URL, "--console", "plain"}, tt.flags...)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestScrapeStopsAtLimits$")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "TDS_TEST_SCRAPE_ARGS="+strings.Join(args, "\n"))
+		out, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != exitLimitReached {
+			t.Errorf("%s: exit %v, want status %d\n%s", tt.reason, err, exitLimitReached, out)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "checkpoint.json"))
+		if err != nil {
+			t.Errorf("%s: no checkpoint: %v", tt.reason, err)
+			continue
+		}
+		var cp checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			t.Fatal(err)
+		}
+		if cp.Reason != tt.reason || cp.StoppedAt == "" || cp.APICalls == 0 {
+			t.Errorf("%s: checkpoint %+v", tt.reason, cp)
+		}
+		if tt.reason == "max-api-calls" && cp.APICalls != 5 {
+			t.Errorf("checkpoint records %d API calls, want the 5 allowed", cp.APICalls)
+		}
+		if !strings.Contains(string(out), "Stopped early ("+tt.reason+")") {
+			t.Errorf("%s: output does not report the stop:\n%s", tt.reason, out)
+		}
+	}
+}
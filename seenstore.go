@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// seenStore is a persistent set of the logins earlier runs fetched, and
+// the dedupe of the logins the current run's searches find, so that a
+// scrape spread over several invocations and days never fetches a login
+// twice. It keeps one small file per login, named by its hash and sharded
+// into subdirectories, so a lookup costs a file read rather than memory
+// however many logins it holds. A file holds the login, its state, and
+// for a login found but not yet fetched, the run that found it:
+//
+//	octocat fetched 2024-05-01T08:00:00Z
+//	torvalds found 4711-1714550400000000000 2024-05-01T08:00:00Z
+type seenStore struct {
+	dir string
+	// run identifies this run's claims; a login found by a run that ended
+	// before fetching it may be claimed again.
+	run string
+}
+
+func openSeenStore(dir string) (*seenStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &seenStore{dir: dir, run: fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())}, nil
+}
+
+func (s *seenStore) path(login string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(login)))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+// state returns login's state, "fetched" or "found", and for a found
+// login the run that found it. A login not in the store has state "".
+// Files written before runs claimed logins hold only the login and time,
+// and count as fetched.
+func (s *seenStore) state(login string) (state, run string, err error) {
+	data, err := os.ReadFile(s.path(login))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if f := strings.Fields(string(data)); len(f) >= 3 && f[1] == "found" {
+		return "found", f[2], nil
+	}
+	return "fetched", "", nil
+}
+
+func (s *seenStore) write(login string, fields ...string) error {
+	p := s.path(login)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	line := strings.Join(slices.Concat([]string{login}, fields, []string{time.Now().UTC().Format(time.RFC3339)}), " ")
+	return writeFileAtomic(p, []byte(line+"\n"))
+}
+
+// claim reports whether this run should fetch login, recording that it
+// found it: false if an earlier run fetched it or this run already found
+// it, as another query or page of the search may.
+func (s *seenStore) claim(login string) (bool, error) {
+	state, run, err := s.state(login)
+	if err != nil || state == "fetched" || run == s.run {
+		return false, err
+	}
+	return true, s.write(login, "found", s.run)
+}
+
+// add records that login was fetched, or that the run is done with it.
+func (s *seenStore) add(login string) error {
+	return s.write(login, "fetched")
+}
+
+// filter claims the users and keeps those this run should fetch. It
+// returns how many it dropped.
+func (s *seenStore) filter(users []User) ([]User, int, error) {
+	kept := users[:0:0]
+	for _, u := range users {
+		ok, err := s.claim(u.Login)
+		if err != nil {
+			return users, 0, err
+		}
+		if ok {
+			kept = append(kept, u)
+		}
+	}
+	return kept, len(users) - len(kept), nil
+}
+
+// mergePrevious adds to the CSV file at path the rows of prev, the file as
+// an earlier run left it, whose key column is not in it, so the outputs of
+// a scrape spread over several runs hold every user fetched so far. Columns
+// only one of them has are left blank in the other's rows.
+func mergePrevious(path string, prev csvTable, key string) (int, error) {
+	if len(prev.header) == 0 {
+		return 0, nil
+	}
+	cur, err := readCSVTable(path)
+	if err != nil {
+		return 0, err
+	}
+	header, record := prev.rewriter(cur.header, key)
+	rows := make([][]string, 0, len(cur.rows)+len(prev.rows))
+	for _, row := range cur.rows {
+		rows = append(rows, record(row))
+	}
+	have := cur.keyed(key)
+	keyIndex := slices.Index(prev.header, key)
+	added := 0
+	for _, row := range prev.rows {
+		if keyIndex < 0 || keyIndex >= len(row) {
+			continue
+		}
+		if _, ok := have[strings.ToLower(row[keyIndex])]; ok {
+			continue
+		}
+		rows = append(rows, append(slices.Clone(row), make([]string, max(0, len(header)-len(row)))...))
+		added++
+	}
+	return added, writeRecordsCSV(path, header, rows, func(r []string) []string { return r })
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSeenStoreClaim(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSeenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := func(s *seenStore, login string, want bool) {
+		t.Helper()
+		if got, err := s.claim(login); got != want || err != nil {
+			t.Fatalf("claim(%s) = %v, %v; want %v", login, got, err, want)
+		}
+	}
+	claim(s, "Alice", true)
+	// Another query or page finding the same login, in any case.
+	claim(s, "alice", false)
+
+	// A later run may claim a login an earlier run found but never fetched,
+	// but not one it fetched.
+	later, _ := openSeenStore(dir)
+	later.run = s.run + "-later"
+	claim(later, "alice", true)
+	if err := later.add("alice"); err != nil {
+		t.Fatal(err)
+	}
+	claim(s, "alice", false)
+
+	users, dropped, err := later.filter([]User{{Login: "alice"}, {Login: "bob"}, {Login: "BOB"}})
+	if err != nil || dropped != 2 || len(users) != 1 || users[0].Login != "bob" {
+		t.Fatalf("filter = %v, %d, %v; want bob only", users, dropped, err)
+	}
+}
+
+// Markers written before runs claimed logins hold only the login and time.
+func TestSeenStoreOldMarkers(t *testing.T) {
+	s, _ := openSeenStore(t.TempDir())
+	p := s.path("carol")
+	os.MkdirAll(filepath.Dir(p), 0o755)
+	os.WriteFile(p, []byte("carol 2024-05-01T08:00:00Z\n"), 0o644)
+	if state, _, err := s.state("carol"); state != "fetched" || err != nil {
+		t.Fatalf("state of an old marker = %q, %v; want fetched", state, err)
+	}
+}
+
+func TestMergePrevious(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	prev := csvTable{
+		header: []string{"login", "followers", "role"},
+		rows:   [][]string{{"alice", "10", "backend"}, {"bob", "20", "frontend"}},
+	}
+	if err := writeRecordsCSV(path, []string{"login", "followers", "bio_language"}, [][]string{{"Bob", "21", "en"}, {"carol", "5", "zh"}},
+		func(r []string) []string { return r }); err != nil {
+		t.Fatal(err)
+	}
+	n, err := mergePrevious(path, prev, "login")
+	if err != nil || n != 1 {
+		t.Fatalf("mergePrevious = %d, %v; want 1 row kept", n, err)
+	}
+	got, _ := readCSVTable(path)
+	want := csvTable{
+		header: []string{"login", "followers", "role", "bio_language"},
+		rows:   [][]string{{"Bob", "21", "frontend", "en"}, {"carol", "5", "", "zh"}, {"alice", "10", "backend", ""}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged = %v\nwant %v", got, want)
+	}
+}
+
+// A scrape spread over runs sharing a seen store ends with every user in
+// its outputs, fetching each only once.
+func TestSeenStoreRuns(t *testing.T) {
+	srv := fakeGitHub(10, 2, 0)
+	defer srv.Close()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	os.WriteFile("first.txt", []byte("user00000\nuser00001\nuser00002\nuser00003\n"), 0o644)
+	args := []string{"--token", "x", "--api-url", srv.URL, "--console", "plain", "--seen-store", "seen"}
+
+	rows := func(path string) int {
+		t.Helper()
+		table, err := readCSVTable(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(table.rows)
+	}
+	runScrape(append(args, "--logins-file", "first.txt"))
+	if n := rows("users.csv"); n != 4 {
+		t.Fatalf("first run: %d users, want 4", n)
+	}
+	for range 2 {
+		runScrape(args)
+		if n := rows("users.csv"); n != 10 {
+			t.Fatalf("users.csv has %d users, want all 10", n)
+		}
+		if n := rows("repositories.csv"); n != 20 {
+			t.Fatalf("repositories.csv has %d repos, want all 20", n)
+		}
+	}
+}
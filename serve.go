@@ -0,0 +1,256 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// datasetStore holds a scraped dataset in memory for serve mode and writes
+// it back to its CSV files after changes, keeping the columns it does not
+// model, such as enrichments, as the files had them.
+type datasetStore struct {
+	usersPath string
+	reposPath string
+	// usersFile and reposFile are the CSV files as loaded, for their
+	// other columns.
+	usersFile csvTable
+	reposFile csvTable
+
+	mu    sync.RWMutex
+	users []User
+	repos []Repo
+	dirty bool
+}
+
+func loadDatasetStore(usersPath, reposPath string) (*datasetStore, error) {
+	users, err := loadUsersCSV(usersPath)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := loadReposCSV(reposPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &datasetStore{usersPath: usersPath, reposPath: reposPath, users: users, repos: repos}
+	if s.usersFile, err = readCSVTable(usersPath); err != nil {
+		return nil, err
+	}
+	if s.reposFile, err = readCSVTable(reposPath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *datasetStore) user(login string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := slices.IndexFunc(s.users, func(u User) bool { return strings.EqualFold(u.Login, login) })
+	if i < 0 {
+		return User{}, false
+	}
+	return s.users[i], true
+}
+
+func (s *datasetStore) repo(fullName string) (Repo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := slices.IndexFunc(s.repos, func(r Repo) bool { return strings.EqualFold(r.FullName, fullName) })
+	if i < 0 {
+		return Repo{}, false
+	}
+	return s.repos[i], true
+}
+
+// contents returns copies of the users and repos.
+func (s *datasetStore) contents() ([]User, []Repo) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.users), slices.Clone(s.repos)
+}
+
+// putUser adds or replaces a user.
+func (s *datasetStore) putUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.users, func(x User) bool { return strings.EqualFold(x.Login, u.Login) }); i >= 0 {
+		s.users[i] = u
+	} else {
+		s.users = append(s.users, u)
+	}
+	s.dirty = true
+}
+
+// removeUser drops a user and their repos.
+func (s *datasetStore) removeUser(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = slices.DeleteFunc(s.users, func(u User) bool { return strings.EqualFold(u.Login, login) })
+	s.repos = slices.DeleteFunc(s.repos, func(r Repo) bool { return strings.EqualFold(r.Login, login) })
+	s.dirty = true
+}
+
+// putRepo adds or replaces a repo, keyed by its full name or, for renames
+// and transfers, by oldName. A fork keeps the root it was assigned.
+func (s *datasetStore) putRepo(r Repo, oldName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cmp.Or(oldName, r.FullName)
+	if !r.Fork {
+		r.RootRepo = r.FullName
+	}
+	if i := slices.IndexFunc(s.repos, func(x Repo) bool { return strings.EqualFold(x.FullName, key) }); i >= 0 {
+		r.RootRepo = cmp.Or(r.RootRepo, s.repos[i].RootRepo)
+		s.repos[i] = r
+	} else {
+		s.repos = append(s.repos, r)
+	}
+	s.dirty = true
+}
+
+func (s *datasetStore) removeRepo(fullName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos = slices.DeleteFunc(s.repos, func(r Repo) bool { return strings.EqualFold(r.FullName, fullName) })
+	s.dirty = true
+}
+
+// flush writes the dataset back to its CSV files if it changed. Columns
+// beyond the standard ones keep their loaded values; users and repos added
+// since have them blank.
+func (s *datasetStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	header, record := s.usersFile.rewriter(userColumns, "login")
+	if err := writeRecordsCSV(s.usersPath, header, s.users, func(u User) []string { return record(userRecord(u)) }); err != nil {
+		return err
+	}
+	header, record = s.reposFile.rewriter(repoColumns, "full_name")
+	if err := writeRecordsCSV(s.reposPath, header, s.repos, func(r Repo) []string { return record(repoRecord(r)) }); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	usersPath := fs.String("users", "users.csv", "users CSV to serve and keep up to date")
+	reposPath := fs.String("repos", "repositories.csv", "repositories CSV to serve and keep up to date")
+	secret := fs.String("webhook-secret", os.Getenv("GITHUB_WEBHOOK_SECRET"), "secret of the GitHub webhook posting to /webhook")
+	flushEvery := fs.Duration("flush-interval", 10*time.Second, "how often changes are written back to the CSV files")
+	token := fs.String("token", "", "GitHub token for looking up new members (default $GITHUB_TOKEN)")
+	historyDir := fs.String("history-dir", "history", "history store that /stats/trend compares the dataset against")
+	fs.Parse(args)
+
+	store, err := loadDatasetStore(*usersPath, *reposPath)
+	if err != nil {
+		fmt.Println("Error loading dataset:", err)
+		return 1
+	}
+	client := newClient(withToken(cmp.Or(*token, os.Getenv("GITHUB_TOKEN"), githubToken)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /users/{login}", func(w http.ResponseWriter, r *http.Request) {
+		u, ok := store.user(r.PathValue("login"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, u)
+	})
+	mux.HandleFunc("GET /repos/{owner}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		repo, ok := store.repo(r.PathValue("owner") + "/" + r.PathValue("name"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, repo)
+	})
+	mux.HandleFunc("GET /stats/trend", func(w http.ResponseWriter, r *http.Request) {
+		window := cmp.Or(r.URL.Query().Get("window"), "30d")
+		d, err := parseWindow(window)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		snaps, err := listSnapshots(*historyDir)
+		if err != nil || len(snaps) == 0 {
+			http.Error(w, "no snapshots in the history store", http.StatusNotFound)
+			return
+		}
+		now := time.Now().UTC()
+		base := baseSnapshot(snaps, now.Add(-d))
+		oldUsers, oldRepos, err := base.load()
+		if err != nil {
+			http.Error(w, "loading snapshot: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		users, repos := store.contents()
+		t := computeTrend(oldUsers, oldRepos, users, repos)
+		t.Window, t.From, t.To = window, base.taken, now
+		t.Days = now.Sub(base.taken).Hours() / 24
+		writeJSON(w, t)
+	})
+	var hook *webhookHandler
+	if *secret != "" {
+		hook = &webhookHandler{secret: []byte(*secret), store: store, client: client}
+		mux.Handle("POST /webhook", hook)
+	} else {
+		fmt.Println("Warning: no --webhook-secret set; the /webhook endpoint is disabled")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		ticker := time.NewTicker(*flushEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				srv.Shutdown(context.Background())
+				return
+			case <-ticker.C:
+				if err := store.flush(); err != nil {
+					fmt.Println("Error saving dataset:", err)
+				}
+			}
+		}
+	}()
+
+	fmt.Printf("Serving %d users and %d repos on %s\n", len(store.users), len(store.repos), *addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Println("Error serving:", err)
+		return 1
+	}
+	if hook != nil {
+		hook.pending.Wait()
+	}
+	if err := store.flush(); err != nil {
+		fmt.Println("Error saving dataset:", err)
+		return 1
+	}
+	return 0
+}
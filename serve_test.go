@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Flushing the served dataset keeps the columns a scrape's enrichments
+// added.
+func TestDatasetStoreFlushKeepsColumns(t *testing.T) {
+	dir := t.TempDir()
+	usersPath, reposPath := filepath.Join(dir, "users.csv"), filepath.Join(dir, "repositories.csv")
+	users := []User{{Login: "alice", Followers: 10}, {Login: "bob", Followers: 20}}
+	header := append(slices.Clone(userColumns), "role")
+	roles := map[string]string{"alice": "backend", "bob": "frontend"}
+	writeRecordsCSV(usersPath, header, users, func(u User) []string { return append(userRecord(u), roles[u.Login]) })
+	writeRecordsCSV(reposPath, repoColumns, []Repo{{Login: "alice", FullName: "alice/x"}}, repoRecord)
+
+	s, err := loadDatasetStore(usersPath, reposPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.removeUser("bob")
+	s.putUser(User{Login: "carol", Followers: 5})
+	if err := s.flush(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := readCSVTable(usersPath)
+	if !slices.Equal(got.header, header) {
+		t.Fatalf("header = %v, want %v", got.header, header)
+	}
+	role := slices.Index(header, "role")
+	want := map[string]string{"alice": "backend", "carol": ""}
+	if len(got.rows) != len(want) {
+		t.Fatalf("rows = %v", got.rows)
+	}
+	for _, row := range got.rows {
+		if r, ok := want[row[0]]; !ok || row[role] != r {
+			t.Errorf("row %v: role %q, want %q", row, row[role], r)
+		}
+	}
+}
+
+// A new member is looked up after the delivery is acknowledged, since the
+// lookup may outlast GitHub's delivery timeout.
+func TestWebhookMemberAddedAsync(t *testing.T) {
+	api := fakeGitHub(1, 0, 200*time.Millisecond)
+	defer api.Close()
+	s := &datasetStore{}
+	h := &webhookHandler{secret: []byte("s3cret"), store: s, client: newClient(withToken("x"), withBaseURL(api.URL))}
+
+	body := `{"action":"member_added","membership":{"user":{"login":"user00000"}}}`
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(body))
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "organization")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	start := time.Now()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", w.Code)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("delivery took %s; the lookup should not hold it up", d)
+	}
+	h.pending.Wait()
+	if u, ok := s.user("user00000"); !ok || u.Followers != 250 {
+		t.Fatalf("member = %+v, %v; want the fetched profile", u, ok)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetsExporter replaces the contents of two tabs of a Google Sheet with
+// the users and repos.
+type sheetsExporter struct {
+	account       *googleServiceAccount
+	spreadsheetID string
+	usersTab      string
+	reposTab      string
+}
+
+func (e *sheetsExporter) name() string { return "Google Sheets" }
+
+func (e *sheetsExporter) export(ctx context.Context, users []User, repos []Repo) error {
+	token, err := e.account.accessToken(ctx, sheetsScope)
+	if err != nil {
+		return err
+	}
+	userRows := [][]string{userColumns}
+	for _, u := range users {
+		userRows = append(userRows, userRecord(u))
+	}
+	if err := e.replaceTab(ctx, token, e.usersTab, userRows); err != nil {
+		return err
+	}
+	repoRows := [][]string{repoColumns}
+	for _, r := range repos {
+		repoRows = append(repoRows, repoRecord(r))
+	}
+	return e.replaceTab(ctx, token, e.reposTab, repoRows)
+}
+
+// replaceTab clears a tab and writes rows starting at A1. Values are sent
+// RAW so that bios starting with "=" are not evaluated as formulas.
+func (e *sheetsExporter) replaceTab(ctx context.Context, token, tab string, rows [][]string) error {
+	base := fmt.Sprintf("https://sheets.googleapis.com/v4/spreadsheets/%s/values/%s",
+		url.PathEscape(e.spreadsheetID), url.PathEscape("'"+tab+"'"))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", base+":clear", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if err := doJSON(req, nil); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]any{"values": rows})
+	if err != nil {
+		return err
+	}
+	req, err = http.NewRequestWithContext(ctx, "PUT", base+"!A1?valueInputOption=RAW", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(req, nil)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/gob"
+	"fmt"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// spill buffers records in memory up to a byte budget and appends the rest
+// to a temporary file, so a run's repos fit in bounded memory however many
+// there are. Records keep the order they were added in.
+type spill[T any] struct {
+	limit int64
+	size  func(T) int64
+
+	mem  []T
+	used int64
+
+	file    *os.File
+	buf     *bufio.Writer
+	enc     *gob.Encoder
+	spilled int
+	err     error
+}
+
+func newSpill[T any](limit int64, size func(T) int64) *spill[T] {
+	return &spill[T]{limit: limit, size: size}
+}
+
+// add buffers items. Once one record has gone to disk, every later one
+// does too.
+func (s *spill[T]) add(items ...T) error {
+	for _, item := range items {
+		if n := s.size(item); s.file == nil && s.used+n <= s.limit {
+			s.mem = append(s.mem, item)
+			s.used += n
+			continue
+		}
+		if s.file == nil {
+			f, err := os.CreateTemp("", "tds-spill-*")
+			if err != nil {
+				return err
+			}
+			s.file, s.buf = f, bufio.NewWriter(f)
+			s.enc = gob.NewEncoder(s.buf)
+			fmt.Printf("Spilling records to %s beyond %s in memory\n", f.Name(), formatByteSize(s.limit))
+		}
+		if err := s.enc.Encode(&item); err != nil {
+			return err
+		}
+		s.spilled++
+	}
+	return nil
+}
+
+func (s *spill[T]) len() int { return len(s.mem) + s.spilled }
+
+// all iterates over the records, reading spilled ones back from disk. A
+// read error ends the iteration early and is reported by err.
+func (s *spill[T]) all() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, item := range s.mem {
+			if !yield(item) {
+				return
+			}
+		}
+		if s.file == nil {
+			return
+		}
+		if s.err = s.buf.Flush(); s.err != nil {
+			return
+		}
+		f, err := os.Open(s.file.Name())
+		if err != nil {
+			s.err = err
+			return
+		}
+		defer f.Close()
+		dec := gob.NewDecoder(bufio.NewReader(f))
+		for range s.spilled {
+			var item T
+			if err := dec.Decode(&item); err != nil {
+				s.err = err
+				return
+			}
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// close removes the spill file.
+func (s *spill[T]) close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// saveSpilledRepos writes repositories.csv from a spill, assigning root
+// repos in a first pass over the records and writing them, redacted, in a
+// second.
+func saveSpilledRepos(s *spill[Repo], red redaction) error {
+	ix := newRootIndex()
+	for r := range s.all() {
+		ix.add(r)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	err := saveReposToCSV(func(yield func(Repo) bool) {
+		for r := range s.all() {
+			r.RootRepo = ix.root(r)
+			if !yield(r) {
+				return
+			}
+		}
+	}, red, nil)
+	return cmp.Or(err, s.err)
+}
+
+// repoSize estimates the memory a repo takes.
+func repoSize(r Repo) int64 {
+	return int64(160 + len(r.Login) + len(r.FullName) + len(r.CreatedAt) + len(r.Language) + len(r.LicenseName) + len(r.RootRepo) + len(r.Description) + len(r.Homepage))
+}
+
+// parseByteSize parses sizes such as "512MB", "2GB", or "1048576". Units
+// are binary: 1KB is 1024 bytes.
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		shift  int
+	}{{"GB", 30}, {"MB", 20}, {"KB", 10}, {"B", 0}}
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	shift := 0
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, shift = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), u.shift
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (want e.g. 512MB or 2GB)", s)
+	}
+	return n << shift, nil
+}
+
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sponsorInfo is a user's GitHub Sponsors status.
+type sponsorInfo struct {
+	HasListing bool
+	Sponsors   int
+}
+
+// graphQLBatch is how many users one GraphQL query asks about.
+const graphQLBatch = 50
+
+// fetchSponsors looks up the Sponsors listing and sponsor count of every
+// user through the GraphQL API, batching users into aliased queries. Users
+// in a failed batch are absent from the map.
+func (c *apiClient) fetchSponsors(ctx context.Context, users []User) (map[string]sponsorInfo, error) {
+	out := map[string]sponsorInfo{}
+	var firstErr error
+	for start := 0; start < len(users); start += graphQLBatch {
+		batch := users[start:min(start+graphQLBatch, len(users))]
+		var q strings.Builder
+		q.WriteString("query {")
+		for i, u := range batch {
+			fmt.Fprintf(&q, " u%d: user(login: %s) { hasSponsorsListing sponsors { totalCount } }", i, strconv.Quote(u.Login))
+		}
+		q.WriteString(" }")
+
+		var resp struct {
+			Data map[string]*struct {
+				HasSponsorsListing bool `json:"hasSponsorsListing"`
+				Sponsors           struct {
+					TotalCount int `json:"totalCount"`
+				} `json:"sponsors"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := c.sendJSON(ctx, "POST", c.graphQLURL(), map[string]string{"query": q.String()}, &resp); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if stopReason(ctx, c) != "" {
+				break
+			}
+			continue
+		}
+		if len(resp.Errors) > 0 && resp.Data == nil && firstErr == nil {
+			firstErr = fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+		}
+		for i, u := range batch {
+			if d := resp.Data["u"+strconv.Itoa(i)]; d != nil {
+				out[u.Login] = sponsorInfo{d.HasSponsorsListing, d.Sponsors.TotalCount}
+			}
+		}
+	}
+	return out, firstErr
+}
+
+// sponsorColumns returns the users.csv columns for the Sponsors enrichment.
+// Users that were not looked up are left blank.
+func sponsorColumns(info map[string]sponsorInfo) columnSet[User] {
+	return columnSet[User]{
+		columns: []string{"has_sponsors", "sponsor_count"},
+		record: func(u User) []string {
+			s, ok := info[u.Login]
+			if !ok {
+				return []string{"", ""}
+			}
+			return []string{strconv.FormatBool(s.HasListing), strconv.Itoa(s.Sponsors)}
+		},
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// distribution summarises a set of non-negative counts.
+type distribution struct {
+	Metric string
+	N      int
+	Mean   float64
+	Min    int
+	P50    float64
+	P90    float64
+	P99    float64
+	Max    int
+	Gini   float64
+}
+
+// describe computes the distribution of values.
+func describe(metric string, values []int) distribution {
+	d := distribution{Metric: metric, N: len(values)}
+	if len(values) == 0 {
+		return d
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sum := 0
+	for _, v := range sorted {
+		sum += v
+	}
+	d.Mean = float64(sum) / float64(len(sorted))
+	d.Min, d.Max = sorted[0], sorted[len(sorted)-1]
+	d.P50 = percentile(sorted, 50)
+	d.P90 = percentile(sorted, 90)
+	d.P99 = percentile(sorted, 99)
+	d.Gini = gini(sorted)
+	return d
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// linearly between the closest ranks.
+func percentile(sorted []int, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := min(lo+1, len(sorted)-1)
+	return float64(sorted[lo]) + (rank-float64(lo))*float64(sorted[hi]-sorted[lo])
+}
+
+// gini returns the Gini coefficient of sorted non-negative values: 0 when
+// everyone has the same, approaching 1 when one holds everything.
+func gini(sorted []int) float64 {
+	n := float64(len(sorted))
+	var sum, weighted float64
+	for i, v := range sorted {
+		sum += float64(v)
+		weighted += float64(i+1) * float64(v)
+	}
+	if sum == 0 {
+		return 0
+	}
+	return (2*weighted)/(n*sum) - (n+1)/n
+}
+
+var distributionColumns = []string{"metric", "n", "mean", "min", "p50", "p90", "p99", "max", "gini"}
+
+func distributionRecord(d distribution) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return []string{
+		d.Metric, strconv.Itoa(d.N), f(d.Mean), strconv.Itoa(d.Min),
+		f(d.P50), f(d.P90), f(d.P99), strconv.Itoa(d.Max), strconv.FormatFloat(d.Gini, 'f', 4, 64),
+	}
+}
+
+// distributions describes followers per user, stars per repo, and total
+// stars per user.
+func distributions(users []User, repos []Repo) []distribution {
+	followers := make([]int, len(users))
+	for i, u := range users {
+		followers[i] = u.Followers
+	}
+	repoStars := make([]int, len(repos))
+	byUser := map[string]int{}
+	for i, r := range repos {
+		repoStars[i] = r.StargazersCount
+		byUser[r.Login] += r.StargazersCount
+	}
+	userStars := make([]int, len(users))
+	for i, u := range users {
+		userStars[i] = byUser[u.Login]
+	}
+	return []distribution{
+		describe("followers", followers),
+		describe("repo_stars", repoStars),
+		describe("user_stars", userStars),
+	}
+}
+
+// loadTimezone resolves an IANA zone name or a fixed offset such as +08:00.
+func loadTimezone(name string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, nil
+	}
+	t, err := time.Parse("-07:00", name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q (want a name like Asia/Shanghai or an offset like +08:00)", name)
+	}
+	_, offset := t.Zone()
+	return time.FixedZone(name, offset), nil
+}
+
+// creationRhythm breaks repo creation times down by local weekday (Monday
+// first), hour of day, and weekday versus weekend.
+func creationRhythm(repos []Repo, loc *time.Location) (weekdays, hours, weekend []share) {
+	weekdays = make([]share, 7)
+	for i := range weekdays {
+		weekdays[i].Name = time.Weekday((i + 1) % 7).String()
+	}
+	hours = make([]share, 24)
+	for h := range hours {
+		hours[h].Name = fmt.Sprintf("%02d", h)
+	}
+	weekend = []share{{Name: "weekday"}, {Name: "weekend"}}
+	total := 0
+	for _, r := range repos {
+		t, err := time.Parse(time.RFC3339, r.CreatedAt)
+		if err != nil {
+			continue
+		}
+		t = t.In(loc)
+		total++
+		weekdays[(int(t.Weekday())+6)%7].Count++
+		hours[t.Hour()].Count++
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			weekend[1].Count++
+		} else {
+			weekend[0].Count++
+		}
+	}
+	for _, table := range [][]share{weekdays, hours, weekend} {
+		for i := range table {
+			if total > 0 {
+				table[i].Percent = 100 * float64(table[i].Count) / float64(total)
+			}
+		}
+	}
+	return weekdays, hours, weekend
+}
+
+// correlation relates two per-user variables, with the least-squares line
+// y = intercept + slope*x.
+type correlation struct {
+	X, Y      string
+	N         int
+	Pearson   float64
+	Spearman  float64
+	Slope     float64
+	Intercept float64
+	R2        float64
+}
+
+func correlate(xName, yName string, x, y []float64) correlation {
+	c := correlation{X: xName, Y: yName, N: len(x)}
+	if len(x) < 2 {
+		return c
+	}
+	c.Pearson = pearson(x, y)
+	c.Spearman = pearson(ranks(x), ranks(y))
+	mx, my := mean(x), mean(y)
+	var sxy, sxx float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+	}
+	if sxx > 0 {
+		c.Slope = sxy / sxx
+	}
+	c.Intercept = my - c.Slope*mx
+	c.R2 = c.Pearson * c.Pearson
+	return c
+}
+
+func mean(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+func pearson(x, y []float64) float64 {
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// ranks returns the rank of each value, averaging ties, for Spearman's rho.
+func ranks(v []float64) []float64 {
+	order := make([]int, len(v))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(v[a], v[b]) })
+	out := make([]float64, len(v))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && v[order[j+1]] == v[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			out[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return out
+}
+
+// correlations relates follower counts to other user attributes.
+func correlations(users []User, repos []Repo) []correlation {
+	stars := map[string]int{}
+	for _, r := range repos {
+		stars[r.Login] += r.StargazersCount
+	}
+	n := len(users)
+	followers, publicRepos, following := make([]float64, n), make([]float64, n), make([]float64, n)
+	bioLength, hireable, totalStars := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, u := range users {
+		followers[i] = float64(u.Followers)
+		publicRepos[i] = float64(u.PublicRepos)
+		following[i] = float64(u.Following)
+		bioLength[i] = float64(utf8.RuneCountInString(u.Bio))
+		if u.Hireable {
+			hireable[i] = 1
+		}
+		totalStars[i] = float64(stars[u.Login])
+	}
+	return []correlation{
+		correlate("public_repos", "followers", publicRepos, followers),
+		correlate("bio_length", "followers", bioLength, followers),
+		correlate("hireable", "followers", hireable, followers),
+		correlate("following", "followers", following, followers),
+		correlate("total_stars", "followers", totalStars, followers),
+	}
+}
+
+var correlationColumns = []string{"x", "y", "n", "pearson", "spearman", "slope", "intercept", "r2"}
+
+func correlationRecord(c correlation) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	return []string{c.X, c.Y, strconv.Itoa(c.N), f(c.Pearson), f(c.Spearman), f(c.Slope), f(c.Intercept), f(c.R2)}
+}
+
+// licenseRank is one row of the license ranking: a license's place among
+// the non-fork repos of a language, or of all languages.
+type licenseRank struct {
+	Language string
+	Rank     int
+	License  string
+	Count    int
+	Percent  float64 // of the group's non-fork repos, licensed or not
+}
+
+// licenseRanking ranks licenses overall (language "(all)") and within each
+// language, ignoring forks so that copied repos do not count twice.
+// Unlicensed repos are left out of the ranking but not out of the totals.
+func licenseRanking(repos []Repo) []licenseRank {
+	groups := map[string][]string{}
+	for _, r := range repos {
+		if r.Fork {
+			continue
+		}
+		groups["(all)"] = append(groups["(all)"], r.LicenseName)
+		if r.Language != "" {
+			groups[r.Language] = append(groups[r.Language], r.LicenseName)
+		}
+	}
+	languages := make([]string, 0, len(groups))
+	for l := range groups {
+		languages = append(languages, l)
+	}
+	slices.SortFunc(languages, func(a, b string) int {
+		switch {
+		case a == "(all)":
+			return -1
+		case b == "(all)":
+			return 1
+		}
+		if c := cmp.Compare(len(groups[b]), len(groups[a])); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	var out []licenseRank
+	for _, l := range languages {
+		rank := 0
+		for _, s := range shareOf(groups[l]) {
+			if s.Name == "(none)" {
+				continue
+			}
+			rank++
+			out = append(out, licenseRank{l, rank, s.Name, s.Count, s.Percent})
+		}
+	}
+	return out
+}
+
+var licenseRankColumns = []string{"language", "rank", "license", "count", "percent"}
+
+func licenseRankRecord(l licenseRank) []string {
+	return []string{l.Language, strconv.Itoa(l.Rank), l.License, strconv.Itoa(l.Count), strconv.FormatFloat(l.Percent, 'f', 2, 64)}
+}
+
+// topRepoRank is one row of top_repos_by_language.csv.
+type topRepoRank struct {
+	Language string
+	Rank     int
+	Repo     Repo
+}
+
+// topReposByLanguage lists the n most-starred non-fork repos of each
+// language, languages with the most stars first.
+func topReposByLanguage(repos []Repo, n int) []topRepoRank {
+	groups := map[string][]Repo{}
+	stars := map[string]int{}
+	for _, r := range repos {
+		if r.Fork || r.Language == "" {
+			continue
+		}
+		groups[r.Language] = append(groups[r.Language], r)
+		stars[r.Language] += r.StargazersCount
+	}
+	languages := make([]string, 0, len(groups))
+	for l := range groups {
+		languages = append(languages, l)
+	}
+	slices.SortFunc(languages, func(a, b string) int {
+		if c := cmp.Compare(stars[b], stars[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	var out []topRepoRank
+	for _, l := range languages {
+		group := groups[l]
+		slices.SortStableFunc(group, func(a, b Repo) int {
+			if c := cmp.Compare(b.StargazersCount, a.StargazersCount); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.FullName, b.FullName)
+		})
+		for i, r := range group[:min(n, len(group))] {
+			out = append(out, topRepoRank{l, i + 1, r})
+		}
+	}
+	return out
+}
+
+var topRepoColumns = []string{"language", "rank", "full_name", "owner", "stargazers_count", "description"}
+
+func topRepoRecord(t topRepoRank) []string {
+	return []string{t.Language, strconv.Itoa(t.Rank), t.Repo.FullName, t.Repo.Login, strconv.Itoa(t.Repo.StargazersCount), t.Repo.Description}
+}
+
+// companySuffixes are legal-form suffixes dropped when grouping companies.
+var companySuffixes = []string{"CO., LTD", "CO.,LTD", "CO. LTD", "CO LTD", "INC", "LTD", "LLC", "CORP", "CORPORATION", "LIMITED", "GMBH"}
+
+// normalizeCompany reduces a company name to a grouping key: the cleaned
+// upper-case name with whitespace collapsed and legal-form suffixes and
+// trailing punctuation removed.
+func normalizeCompany(company string) string {
+	name := strings.Join(strings.Fields(cleanCompanyName(company)), " ")
+	for {
+		trimmed := strings.TrimRight(name, " .,")
+		for _, suffix := range companySuffixes {
+			if strings.HasSuffix(trimmed, " "+suffix) || strings.HasSuffix(trimmed, ","+suffix) {
+				trimmed = strings.TrimRight(strings.TrimSuffix(trimmed, suffix), " .,")
+				break
+			}
+		}
+		if trimmed == name {
+			return name
+		}
+		name = trimmed
+	}
+}
+
+// companyStats is one row of the company leaderboard.
+type companyStats struct {
+	Company      string
+	Users        int
+	AvgFollowers float64
+	TotalStars   int
+	Repos        int
+}
+
+// companyLeaderboard groups users by normalised company and ranks the
+// companies by weight: their users, total stars, or repos.
+func companyLeaderboard(users []User, repos []Repo, weight string) []companyStats {
+	stars := map[string]int{}
+	owned := map[string]int{}
+	for _, r := range repos {
+		stars[r.Login] += r.StargazersCount
+		owned[r.Login]++
+	}
+	byCompany := map[string]*companyStats{}
+	followers := map[string]int{}
+	for _, u := range users {
+		name := normalizeCompany(u.Company)
+		if name == "" {
+			continue
+		}
+		c := byCompany[name]
+		if c == nil {
+			c = &companyStats{Company: name}
+			byCompany[name] = c
+		}
+		c.Users++
+		c.TotalStars += stars[u.Login]
+		c.Repos += owned[u.Login]
+		followers[name] += u.Followers
+	}
+	out := make([]companyStats, 0, len(byCompany))
+	for name, c := range byCompany {
+		c.AvgFollowers = float64(followers[name]) / float64(c.Users)
+		out = append(out, *c)
+	}
+	key := func(c companyStats) int {
+		switch weight {
+		case "stars":
+			return c.TotalStars
+		case "repos":
+			return c.Repos
+		}
+		return c.Users
+	}
+	slices.SortFunc(out, func(a, b companyStats) int {
+		if c := cmp.Compare(key(b), key(a)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Users, a.Users); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.TotalStars, a.TotalStars); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Company, b.Company)
+	})
+	return out
+}
+
+var companyColumns = []string{"rank", "company", "users", "avg_followers", "total_stars", "repos"}
+
+// weightTitles names the weightings of the language and company
+// aggregates. Each answers a different question: repos measures output,
+// stars measures attention, and users measures adoption.
+var weightTitles = map[string]string{
+	"repos": "repositories",
+	"stars": "stars",
+	"users": "users",
+}
+
+// parseWeights parses the comma-separated --weights list.
+func parseWeights(s string) ([]string, error) {
+	var weights []string
+	for _, w := range strings.Split(s, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" || slices.Contains(weights, w) {
+			continue
+		}
+		if _, ok := weightTitles[w]; !ok {
+			return nil, fmt.Errorf("unknown weight %q (want repos, stars, or users)", w)
+		}
+		weights = append(weights, w)
+	}
+	return weights, nil
+}
+
+// languageShares ranks languages by weight. Repos counts every repo, as
+// languages.csv does; stars sums the stars of unique, the repos with forks
+// folded into their upstream; users counts the users with at least one
+// repo in the language, as a percentage of all users.
+func languageShares(users []User, repos, unique []Repo, weight string) []share {
+	counts := map[string]int{}
+	total := 0
+	switch weight {
+	case "stars":
+		for _, r := range unique {
+			counts[cmp.Or(r.Language, "(none)")] += r.StargazersCount
+			total += r.StargazersCount
+		}
+	case "users":
+		seen := map[[2]string]bool{}
+		for _, r := range repos {
+			key := [2]string{r.Login, cmp.Or(r.Language, "(none)")}
+			if !seen[key] {
+				seen[key] = true
+				counts[key[1]]++
+			}
+		}
+		total = len(users)
+	default:
+		for _, r := range repos {
+			counts[cmp.Or(r.Language, "(none)")]++
+		}
+		total = len(repos)
+	}
+	return rankShares(counts, total)
+}
+
+// dashboardStats is the compact stats.json written with --stats-json, for
+// shields.io dynamic JSON badges (e.g. query=$.users) and lightweight
+// dashboards that track the dataset across runs.
+type dashboardStats struct {
+	GeneratedAt     time.Time       `json:"generated_at"`
+	Users           int             `json:"users"`
+	Repos           int             `json:"repos"`
+	Hireable        int             `json:"hireable"`
+	MedianFollowers float64         `json:"median_followers"`
+	MedianRepoStars float64         `json:"median_repo_stars"`
+	MedianUserStars float64         `json:"median_user_stars"`
+	TopLanguages    []languageShare `json:"top_languages"`
+}
+
+type languageShare struct {
+	Language string  `json:"language"`
+	Repos    int     `json:"repos"`
+	Percent  float64 `json:"percent"`
+}
+
+// dashboardTopLanguages is how many languages stats.json lists.
+const dashboardTopLanguages = 5
+
+func computeDashboardStats(users []User, repos []Repo) dashboardStats {
+	s := summarize(users, repos, 0)
+	d := dashboardStats{
+		GeneratedAt:  time.Now().UTC(),
+		Users:        s.Users,
+		Repos:        s.Repos,
+		Hireable:     s.Hireable,
+		TopLanguages: []languageShare{},
+	}
+	for _, dist := range distributions(users, repos) {
+		switch dist.Metric {
+		case "followers":
+			d.MedianFollowers = dist.P50
+		case "repo_stars":
+			d.MedianRepoStars = dist.P50
+		case "user_stars":
+			d.MedianUserStars = dist.P50
+		}
+	}
+	for _, l := range s.Languages {
+		if l.Name == "(none)" {
+			continue
+		}
+		if len(d.TopLanguages) == dashboardTopLanguages {
+			break
+		}
+		d.TopLanguages = append(d.TopLanguages, languageShare{l.Name, l.Count, math.Round(l.Percent*10) / 10})
+	}
+	return d
+}
+
+func saveDashboardStats(path string, users []User, repos []Repo) error {
+	data, err := json.MarshalIndent(computeDashboardStats(users, repos), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
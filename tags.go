@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// foldText normalises text for keyword matching: full-width forms become
+// ASCII, combining marks are dropped, and letters are case-folded.
+func foldText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '！' && r <= '～':
+			r -= '！' - '!'
+		case r == '　':
+			r = ' '
+		case unicode.Is(unicode.Mn, r):
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// matchKeywords returns the keywords that occur in text as whole words,
+// ignoring case and width differences. Keywords in scripts written without
+// spaces (such as Chinese) match anywhere.
+func matchKeywords(text string, keywords []string) []string {
+	text = foldText(text)
+	var matched []string
+	for _, k := range keywords {
+		if f := foldText(k); f != "" && containsWord(text, f) {
+			matched = append(matched, k)
+		}
+	}
+	return matched
+}
+
+func containsWord(text, word string) bool {
+	first, _ := utf8.DecodeRuneInString(word)
+	last, _ := utf8.DecodeLastRuneInString(word)
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !joinsWord(before, first)) && (end == len(text) || !joinsWord(last, after)) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// joinsWord reports whether adjacent runes a and b belong to the same word.
+func joinsWord(a, b rune) bool {
+	isWord := func(r rune) bool {
+		return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+	}
+	return isWord(a) && isWord(b)
+}
+
+// parseKeywords splits a comma-separated keyword list, dropping blanks.
+func parseKeywords(list string) []string {
+	var out []string
+	for _, k := range strings.Split(list, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// bioTagColumns returns the users.csv columns for keyword tagging: the
+// matched keywords, then one true/false column per keyword. The keywords
+// must have passed tagSlugs.
+func bioTagColumns(keywords []string) columnSet[User] {
+	columns := []string{"bio_tags"}
+	slugs, _ := tagSlugs(keywords)
+	for _, slug := range slugs {
+		columns = append(columns, "tag_"+slug)
+	}
+	return columnSet[User]{columns, func(u User) []string {
+		matched := matchKeywords(u.Bio, keywords)
+		record := []string{strings.Join(matched, ";")}
+		for _, k := range keywords {
+			record = append(record, strconv.FormatBool(slices.Contains(matched, k)))
+		}
+		return record
+	}}
+}
+
+// slugSymbols are spelt out in slugs, so that C, C++, and C# get distinct
+// columns.
+var slugSymbols = map[rune]string{'+': "plus", '#': "sharp"}
+
+// tagSlug turns a keyword into a column-name suffix: lower case, with + and
+// # spelt out and runs of anything else but letters and digits replaced by
+// one underscore, e.g. "C++" becomes c_plus_plus.
+func tagSlug(k string) string {
+	var words []string
+	word := ""
+	for _, r := range foldText(k) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word += string(r)
+			continue
+		}
+		if word != "" {
+			words = append(words, word)
+			word = ""
+		}
+		if name := slugSymbols[r]; name != "" && len(words) > 0 {
+			words = append(words, name)
+		}
+	}
+	if word != "" {
+		words = append(words, word)
+	}
+	return strings.Join(words, "_")
+}
+
+// tagSlugs returns the column-name suffix of each keyword. Keywords whose
+// slugs collide are numbered in order, e.g. go and Go become go and go_2;
+// a keyword without letters or digits is an error.
+func tagSlugs(keywords []string) ([]string, error) {
+	slugs := make([]string, len(keywords))
+	used := map[string]bool{}
+	for i, k := range keywords {
+		slug := tagSlug(k)
+		if slug == "" {
+			return nil, fmt.Errorf("keyword %q has no letters or digits to name its column", k)
+		}
+		for n := 2; used[slug]; n++ {
+			slug = tagSlug(k) + "_" + strconv.Itoa(n)
+		}
+		used[slug] = true
+		slugs[i] = slug
+	}
+	return slugs, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTagSlugs(t *testing.T) {
+	tests := []struct {
+		keywords, want []string
+	}{
+		{[]string{"Kubernetes", "machine learning", "Node.js"}, []string{"kubernetes", "machine_learning", "node_js"}},
+		{[]string{"C", "C++", "C#", "F#", ".NET"}, []string{"c", "c_plus_plus", "c_sharp", "f_sharp", "net"}},
+		{[]string{"go", "Go", "GO!", "ｇｏ"}, []string{"go", "go_2", "go_3", "go_4"}},
+		{[]string{"c plus plus", "C++"}, []string{"c_plus_plus", "c_plus_plus_2"}},
+		{[]string{"#rust", "大数据"}, []string{"rust", "大数据"}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		if got, err := tagSlugs(tt.keywords); err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("tagSlugs(%q) = %q, %v; want %q", tt.keywords, got, err, tt.want)
+		}
+	}
+	if _, err := tagSlugs([]string{"rust", "+++"}); err == nil {
+		t.Error("a keyword without letters or digits got a column")
+	}
+}
+
+func TestBioTagColumns(t *testing.T) {
+	x := bioTagColumns([]string{"C", "C++", "Rust"})
+	if want := []string{"bio_tags", "tag_c", "tag_c_plus_plus", "tag_rust"}; !slices.Equal(x.columns, want) {
+		t.Errorf("columns %q, want %q", x.columns, want)
+	}
+	if got, want := x.record(User{Bio: "Rust developer"}), []string{"Rust", "false", "false", "true"}; !slices.Equal(got, want) {
+		t.Errorf("record %q, want %q", got, want)
+	}
+	if got := techColumns([]string{"C", "C#"}, nil).columns; !slices.Equal(got, []string{"tech_c", "tech_c_sharp"}) {
+		t.Errorf("tech columns %q", got)
+	}
+}
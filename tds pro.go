@@ -1,21 +1,26 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
-const (
-	githubToken = "_"
-	baseURL     = "https://api.github.com"
-)
+const githubToken = "_"
+
+// defaultBaseURL is the GitHub API root; clients for GitHub Enterprise or
+// a fake server are given another with withBaseURL.
+const defaultBaseURL = "https://api.github.com"
 
 type User struct {
 	Login       string `json:"login"`
@@ -29,6 +34,8 @@ type User struct {
 	Followers   int    `json:"followers"`
 	Following   int    `json:"following"`
 	CreatedAt   string `json:"created_at"`
+	// Type is "User" or, for organisation accounts, "Organization".
+	Type string `json:"type"`
 }
 
 type Repo struct {
@@ -40,58 +47,136 @@ type Repo struct {
 	Language        string `json:"language"`
 	HasProjects     bool   `json:"has_projects"`
 	HasWiki         bool   `json:"has_wiki"`
+	HasIssues       bool   `json:"has_issues"`
+	HasDiscussions  bool   `json:"has_discussions"`
+	HasPages        bool   `json:"has_pages"`
 	LicenseName     string `json:"license_name"`
+	Fork            bool   `json:"fork"`
+	RootRepo        string `json:"root_repo"`
+	Description     string `json:"description"`
+	Homepage        string `json:"homepage"`
+	// SubscribersCount is the number of users watching the repo. Unlike
+	// WatchersCount, which the REST API sets to the stars, it is only
+	// filled in by --subscribers.
+	SubscribersCount int `json:"subscribers_count"`
+
+	// canPush records whether the token may push to the repo, which
+	// gates endpoints such as traffic. It is not exported.
+	canPush bool
 }
 
-func fetchUsersInShanghai() ([]User, error) {
-	var users []User
-	query := "location:Shanghai+followers:>200"
-	page := 1
-	perPage := 100
+// UnmarshalJSON reads a repo from either the GitHub API, which nests the
+// license as {"license": {"key": ...}} and reports the token's permissions,
+// or this tool's own JSON exports, which carry license_name directly.
+func (r *Repo) UnmarshalJSON(data []byte) error {
+	type plain Repo
+	aux := struct {
+		*plain
+		License *struct {
+			Key string `json:"key"`
+		} `json:"license"`
+		Permissions struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if r.LicenseName == "" && aux.License != nil {
+		r.LicenseName = aux.License.Key
+	}
+	r.canPush = aux.Permissions.Push
+	return nil
+}
 
-	for {
-		url := fmt.Sprintf("%s/search/users?q=%s&per_page=%d&page=%d", baseURL, query, perPage, page)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Authorization", "token "+githubToken)
+// defaultUserQuery is the user search the scrape runs, URL-encoded.
+const defaultUserQuery = "location:Shanghai+followers:>200"
 
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Do(req)
+// fetchUsersInShanghai runs the default user search.
+func (c *apiClient) fetchUsersInShanghai(ctx context.Context) ([]User, error) {
+	return c.searchUsers(ctx, defaultUserQuery)
+}
+
+// searchUsers pages through the user search for the URL-encoded query. On
+// error it returns the users collected so far alongside the error.
+func (c *apiClient) searchUsers(ctx context.Context, query string) ([]User, error) {
+	var users []User
+	for u, err := range c.userSearch(ctx, query) {
 		if err != nil {
-			return nil, err
+			return users, err
 		}
-		defer resp.Body.Close()
+		users = append(users, u)
+		c.progress.report("search", len(users), 0)
+	}
+	return users, nil
+}
 
+// userSearch iterates over the users matching the URL-encoded query as
+// search pages arrive, most followed first.
+func (c *apiClient) userSearch(ctx context.Context, query string) iter.Seq2[User, error] {
+	return pageItems(ctx, c, fmt.Sprintf("%s/search/users?q=%s&sort=followers&order=desc", c.baseURL, query), func(body []byte) ([]User, error) {
 		var result struct {
 			Items []User `json:"items"`
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return nil, err
-		}
+		err := json.Unmarshal(body, &result)
+		return result.Items, err
+	})
+}
 
-		users = append(users, result.Items...)
-		if len(result.Items) < perPage {
-			break
+// userRepos iterates over a user's repos as pages arrive.
+func (c *apiClient) userRepos(ctx context.Context, username string) iter.Seq2[Repo, error] {
+	return func(yield func(Repo, error) bool) {
+		for r, err := range pageItems[Repo](ctx, c, fmt.Sprintf("%s/users/%s/repos", c.baseURL, username), nil) {
+			if err == nil {
+				r.Login = username
+			}
+			if !yield(r, err) || err != nil {
+				return
+			}
 		}
-		page++
 	}
-	return users, nil
 }
 
-func fetchUserDetailsConcurrently(users []User) []User {
+// fetchConcurrency bounds the users whose details or repos are fetched at
+// once. Users are started in priority order, so a run cut short by its
+// budget or deadline has the most followed users.
+const fetchConcurrency = 32
+
+// byFollowers returns users sorted by followers, most first, keeping the
+// order of ties, such as search results, which carry no follower counts
+// and come back most followed first.
+func byFollowers(users []User) []User {
+	sorted := slices.Clone(users)
+	slices.SortStableFunc(sorted, func(a, b User) int { return cmp.Compare(b.Followers, a.Followers) })
+	return sorted
+}
+
+// fetchUserDetailsConcurrently fetches the details of every user, most
+// followed first. Users whose details are unchanged since the cached copy
+// are not passed to onUser again, so streaming sinks only see updates on
+// incremental runs.
+func (c *apiClient) fetchUserDetailsConcurrently(ctx context.Context, users []User) []User {
+	type detail struct {
+		user    User
+		changed bool
+		err     error
+	}
 	var wg sync.WaitGroup
-	ch := make(chan User, len(users))
+	ch := make(chan detail, len(users))
 
-	for _, user := range users {
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, user := range byFollowers(users) {
 		wg.Add(1)
+		sem <- struct{}{}
 		go func(login string) {
 			defer wg.Done()
-			userDetail, err := fetchUserDetails(login) // Fixed variable name
-			if err == nil {
-				ch <- userDetail
-			}
+			defer func() { <-sem }()
+			ctx, cancel := c.userContext(ctx)
+			defer cancel()
+			ctx, sp := startSpan(ctx, "user fetch", "login", login)
+			userDetail, changed, err := c.fetchUserDetails(ctx, login) // Fixed variable name
+			sp.finish(err)
+			ch <- detail{userDetail, changed, err}
 		}(user.Login)
 	}
 
@@ -101,33 +186,50 @@ func fetchUserDetailsConcurrently(users []User) []User {
 	}()
 
 	var detailedUsers []User
-	for user := range ch {
-		detailedUsers = append(detailedUsers, user)
+	unchanged, finished := 0, 0
+	for d := range ch {
+		finished++
+		c.progress.report("details", finished, len(users))
+		if d.err != nil {
+			continue
+		}
+		detailedUsers = append(detailedUsers, d.user)
+		if !d.changed {
+			unchanged++
+		} else if c.onUser != nil {
+			c.onUser(d.user)
+		}
+	}
+	if unchanged > 0 {
+		fmt.Printf("%d of %d users unchanged since they were cached\n", unchanged, len(detailedUsers))
 	}
 	return detailedUsers
 }
 
-func fetchUserDetails(username string) (User, error) {
-	url := fmt.Sprintf("%s/users/%s", baseURL, username)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return User{}, err
-	}
-	req.Header.Set("Authorization", "token "+githubToken)
+// fetchUserDetails fetches a user's profile and reports whether it changed
+// since the cached copy.
+func (c *apiClient) fetchUserDetails(ctx context.Context, username string) (User, bool, error) {
+	return c.userDetails(ctx, username, false)
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+// userDetails is fetchUserDetails that, with revalidate, checks a cached
+// profile with the server even while it is fresh.
+func (c *apiClient) userDetails(ctx context.Context, username string, revalidate bool) (User, bool, error) {
+	url := fmt.Sprintf("%s/users/%s", c.baseURL, username)
+	page, changed, err := c.fetchPage(ctx, url, revalidate)
 	if err != nil {
-		return User{}, err
+		return User{}, false, err
+	}
+	if page.status != http.StatusOK {
+		return User{}, false, &statusError{url, page.status, strings.TrimSpace(string(page.body))}
 	}
-	defer resp.Body.Close()
 
 	var user User
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return User{}, err
+	if err := json.Unmarshal(page.body, &user); err != nil {
+		return User{}, false, err
 	}
 	user.Company = cleanCompanyName(user.Company)
-	return user, nil
+	return user, changed, nil
 }
 
 func cleanCompanyName(company string) string {
@@ -138,19 +240,43 @@ func cleanCompanyName(company string) string {
 	return company
 }
 
-func fetchUserReposConcurrently(users []User) []Repo {
+// fetchUserReposConcurrently fetches the repos of every user and also
+// returns the logins whose repos were fetched successfully.
+func (c *apiClient) fetchUserReposConcurrently(ctx context.Context, users []User) ([]Repo, []string) {
+	var allRepos []Repo
+	done := c.streamUserRepos(ctx, users, func(repos []Repo) {
+		allRepos = append(allRepos, repos...)
+	})
+	return allRepos, done
+}
+
+// streamUserRepos fetches the repos of every user, most followed first,
+// handing each user's repos to fn as they arrive instead of collecting
+// them, and returns the logins whose repos were fetched successfully. fn
+// is never called concurrently.
+func (c *apiClient) streamUserRepos(ctx context.Context, users []User, fn func([]Repo)) []string {
+	type userRepos struct {
+		login string
+		repos []Repo
+		err   error
+	}
 	var wg sync.WaitGroup
-	repoCh := make(chan []Repo, len(users))
+	repoCh := make(chan userRepos, len(users))
 
-	for _, user := range users {
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, user := range byFollowers(users) {
 		wg.Add(1)
-		go func(login string) {
+		sem <- struct{}{}
+		go func(login string, count int) {
 			defer wg.Done()
-			repos, err := fetchUserRepos(login)
-			if err == nil {
-				repoCh <- repos
-			}
-		}(user.Login)
+			defer func() { <-sem }()
+			ctx, cancel := c.userContext(ctx)
+			defer cancel()
+			ctx, sp := startSpan(ctx, "repo fetch", "login", login)
+			repos, err := c.fetchUserRepos(ctx, login, count)
+			sp.finish(err)
+			repoCh <- userRepos{login, repos, err}
+		}(user.Login, user.PublicRepos)
 	}
 
 	go func() {
@@ -158,98 +284,207 @@ func fetchUserReposConcurrently(users []User) []Repo {
 		close(repoCh)
 	}()
 
-	var allRepos []Repo
-	for repos := range repoCh {
-		allRepos = append(allRepos, repos...)
+	var done []string
+	finished := 0
+	for r := range repoCh {
+		finished++
+		c.progress.report("repos", finished, len(users))
+		if r.err != nil {
+			continue
+		}
+		fn(r.repos)
+		done = append(done, r.login)
+		if c.onRepos != nil {
+			c.onRepos(r.login, r.repos)
+		}
 	}
-	return allRepos
+	return done
 }
 
-func fetchUserRepos(username string) ([]Repo, error) {
-	var repos []Repo
-	url := fmt.Sprintf("%s/users/%s/repos?per_page=500", baseURL, username)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// repoPageConcurrency bounds the repo pages fetched at once for one user.
+const repoPageConcurrency = 4
+
+// fetchUserRepos fetches all of a user's repos. count, the user's
+// public_repos, sizes the page range so the pages of prolific users are
+// fetched concurrently; pages past it are followed serially in case the
+// count is stale.
+func (c *apiClient) fetchUserRepos(ctx context.Context, username string, count int) ([]Repo, error) {
+	const perPage = listPageSize
+	pageURL := func(page int) string {
+		return fmt.Sprintf("%s/users/%s/repos?per_page=%d&page=%d", c.baseURL, username, perPage, page)
 	}
-	req.Header.Set("Authorization", "token "+githubToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	// The pages the profile's repo count calls for are fetched
+	// concurrently; any beyond them, for repos created since, are followed
+	// from the last one.
+	pages := make([][]Repo, max(1, (count+perPage-1)/perPage))
+	next := make([]string, len(pages))
+	errs := make([]error, len(pages))
+	sem := make(chan struct{}, repoPageConcurrency)
+	var wg sync.WaitGroup
+	for i := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			resp, err := c.getListPage(ctx, pageURL(i+1))
+			if err == nil {
+				err = json.Unmarshal(resp.body, &pages[i])
+				next[i] = nextPageURL(pageURL(i+1), resp.header, len(pages[i]))
+			}
+			errs[i] = err
+		}()
 	}
-	defer resp.Body.Close()
-
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
 		return nil, err
 	}
+	if tail := next[len(next)-1]; tail != "" {
+		more, err := paginate[Repo](ctx, c, tail, nil)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, more)
+	}
 
+	repos := slices.Concat(pages...)
 	for i := range repos {
 		repos[i].Login = username
 	}
 	return repos, nil
 }
 
-func saveUsersToCSV(users []User) error {
-	file, err := os.Create("users.csv")
-	if err != nil {
-		return err
+var userColumns = []string{"login", "name", "company", "location", "email", "hireable", "bio", "public_repos", "followers", "following", "created_at"}
+
+// userRecord returns the export row for a user, in userColumns order.
+func userRecord(user User) []string {
+	return []string{
+		user.Login, user.Name, user.Company, user.Location, user.Email,
+		strconv.FormatBool(user.Hireable), user.Bio, strconv.Itoa(user.PublicRepos),
+		strconv.Itoa(user.Followers), strconv.Itoa(user.Following), user.CreatedAt,
 	}
-	defer file.Close()
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+var repoColumns = []string{"login", "full_name", "created_at", "stargazers_count", "watchers_count", "language", "has_projects", "has_wiki", "license_name", "fork", "root_repo", "description", "homepage", "subscribers_count", "has_issues", "has_discussions", "has_pages"}
+
+// repoRecord returns the export row for a repo, in repoColumns order.
+func repoRecord(repo Repo) []string {
+	return []string{
+		repo.Login, repo.FullName, repo.CreatedAt,
+		strconv.Itoa(repo.StargazersCount), strconv.Itoa(repo.WatchersCount),
+		repo.Language, strconv.FormatBool(repo.HasProjects),
+		strconv.FormatBool(repo.HasWiki), repo.LicenseName,
+		strconv.FormatBool(repo.Fork), repo.RootRepo, repo.Description, repo.Homepage,
+		strconv.Itoa(repo.SubscribersCount),
+		strconv.FormatBool(repo.HasIssues), strconv.FormatBool(repo.HasDiscussions), strconv.FormatBool(repo.HasPages),
+	}
+}
 
-	writer.Write([]string{"login", "name", "company", "location", "email", "hireable", "bio", "public_repos", "followers", "following", "created_at"})
-	for _, user := range users {
-		writer.Write([]string{
-			user.Login, user.Name, user.Company, user.Location, user.Email,
-			strconv.FormatBool(user.Hireable), user.Bio, strconv.Itoa(user.PublicRepos),
-			strconv.Itoa(user.Followers), strconv.Itoa(user.Following), user.CreatedAt,
-		})
+// columnSet is a group of extra CSV columns produced by an optional
+// enrichment.
+type columnSet[T any] struct {
+	columns []string
+	record  func(T) []string
+}
+
+// csvColumns returns the header of a CSV with base columns and extras.
+func csvColumns[T any](base []string, extras []columnSet[T]) []string {
+	columns := slices.Clone(base)
+	for _, x := range extras {
+		columns = append(columns, x.columns...)
 	}
-	return nil
+	return columns
 }
 
-func saveReposToCSV(repos []Repo) error {
-	file, err := os.Create("repositories.csv")
+// saveUsersToCSV writes users.csv, redacted by red, appending the columns
+// of each extra column set to every row. The extra columns are computed
+// from the unredacted user.
+func saveUsersToCSV(users []User, red redaction, extras []columnSet[User]) error {
+	file, err := createAtomic("users.csv")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer file.discard()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
-	writer.Write([]string{"login", "full_name", "created_at", "stargazers_count", "watchers_count", "language", "has_projects", "has_wiki", "license_name"})
-	for _, repo := range repos {
-		writer.Write([]string{
-			repo.Login, repo.FullName, repo.CreatedAt,
-			strconv.Itoa(repo.StargazersCount), strconv.Itoa(repo.WatchersCount),
-			repo.Language, strconv.FormatBool(repo.HasProjects),
-			strconv.FormatBool(repo.HasWiki), repo.LicenseName,
-		})
+	writer.Write(csvColumns(userColumns, extras))
+	for _, user := range users {
+		record := userRecord(redactItem(red, user))
+		for _, x := range extras {
+			record = append(record, x.record(user)...)
+		}
+		writer.Write(record)
 	}
-	return nil
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.commit()
 }
 
-func main() {
-	users, err := fetchUsersInShanghai()
+// saveReposToCSV writes repositories.csv, redacted by red, appending the
+// columns of each extra column set to every row. The extra columns are
+// computed from the unredacted repo.
+func saveReposToCSV(repos iter.Seq[Repo], red redaction, extras []columnSet[Repo]) error {
+	file, err := createAtomic("repositories.csv")
 	if err != nil {
-		fmt.Println("Error fetching users:", err)
-		return
+		return err
 	}
+	defer file.discard()
 
-	detailedUsers := fetchUserDetailsConcurrently(users)
-	if err := saveUsersToCSV(detailedUsers); err != nil {
-		fmt.Println("Error saving users to CSV:", err)
-		return
+	writer := csv.NewWriter(file)
+
+	writer.Write(csvColumns(repoColumns, extras))
+	for repo := range repos {
+		record := repoRecord(redactItem(red, repo))
+		for _, x := range extras {
+			record = append(record, x.record(repo)...)
+		}
+		writer.Write(record)
 	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.commit()
+}
 
-	allRepos := fetchUserReposConcurrently(detailedUsers)
-	if err := saveReposToCSV(allRepos); err != nil {
-		fmt.Println("Error saving repos to CSV:", err)
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "analyze":
+			os.Exit(runAnalyze(os.Args[2:]))
+		case "profiles":
+			os.Exit(runProfiles(os.Args[2:]))
+		case "score":
+			os.Exit(runScore(os.Args[2:]))
+		case "trending":
+			os.Exit(runTrending(os.Args[2:]))
+		case "prune":
+			os.Exit(runPrune(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "track":
+			os.Exit(runTrack(os.Args[2:]))
+		case "watch":
+			os.Exit(runWatch(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "runs":
+			os.Exit(runRuns(os.Args[2:]))
+		case "init":
+			os.Exit(runInit(os.Args[2:]))
+		case "completion":
+			os.Exit(runCompletion(os.Args[2:]))
+		case "fake":
+			os.Exit(runFake(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		}
 	}
-	fmt.Println("Done")
+	runScrape(os.Args[1:])
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// hasTechnology reports whether any of login's repos contain code matching
+// tech, using a code search. Searches that go to the network are paced;
+// fresh cached answers are not.
+func (c *apiClient) hasTechnology(ctx context.Context, login, tech string) (bool, error) {
+	q := url.QueryEscape(fmt.Sprintf("%q user:%s", tech, login))
+	u := fmt.Sprintf("%s/search/code?q=%s&per_page=1", c.baseURL, q)
+	if _, fresh := c.cache.lookup(u); !fresh {
+		if err := codeSearchPacer.wait(ctx); err != nil {
+			return false, err
+		}
+	}
+	body, err := c.getCached(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	var result struct {
+		TotalCount *int   `json:"total_count"`
+		Message    string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, err
+	}
+	if result.TotalCount == nil {
+		return false, fmt.Errorf("code search for %s: %s", login, result.Message)
+	}
+	return *result.TotalCount > 0, nil
+}
+
+// fetchTechUsage checks every user for every technology. Code search is
+// limited to 10 requests a minute, so this takes about users*techs/10
+// minutes; cached answers are free. Failed checks are absent from the map.
+func (c *apiClient) fetchTechUsage(ctx context.Context, users []User, techs []string) map[string]map[string]bool {
+	usage := map[string]map[string]bool{}
+	for _, u := range users {
+		for _, tech := range techs {
+			found, err := c.hasTechnology(ctx, u.Login, tech)
+			if err != nil {
+				if stopReason(ctx, c) != "" {
+					return usage
+				}
+				continue
+			}
+			if usage[u.Login] == nil {
+				usage[u.Login] = map[string]bool{}
+			}
+			usage[u.Login][tech] = found
+		}
+	}
+	return usage
+}
+
+// techColumns returns one users.csv column per technology, true when the
+// user's code mentions it and blank when it was not checked.
+// The technologies must have passed tagSlugs.
+func techColumns(techs []string, usage map[string]map[string]bool) columnSet[User] {
+	columns, _ := tagSlugs(techs)
+	for i, slug := range columns {
+		columns[i] = "tech_" + slug
+	}
+	return columnSet[User]{columns, func(u User) []string {
+		record := make([]string, len(techs))
+		for i, t := range techs {
+			if found, ok := usage[u.Login][t]; ok {
+				record[i] = strconv.FormatBool(found)
+			}
+		}
+		return record
+	}}
+}
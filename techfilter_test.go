@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Cached code searches are answered without waiting for the code search
+// pacer.
+func TestFetchTechUsageCached(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	c.cache = &responseCache{dir: t.TempDir(), ttl: time.Hour}
+	answers := map[string]int{"alice/rust": 2, "alice/kafka": 0, "bob/rust": 0, "bob/kafka": 1}
+	users := []User{{Login: "alice"}, {Login: "bob"}}
+	techs := []string{"rust", "kafka"}
+	for _, u := range users {
+		for _, tech := range techs {
+			q := url.QueryEscape(fmt.Sprintf("%q user:%s", tech, u.Login))
+			body := fmt.Sprintf(`{"total_count":%d}`, answers[u.Login+"/"+tech])
+			c.cache.store(fmt.Sprintf("%s/search/code?q=%s&per_page=1", srv.URL, q), []byte(body), http.Header{})
+		}
+	}
+
+	start := time.Now()
+	usage := c.fetchTechUsage(context.Background(), users, techs)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cached checks took %v", elapsed)
+	}
+	want := map[string]map[string]bool{"alice": {"rust": true, "kafka": false}, "bob": {"rust": false, "kafka": true}}
+	if !reflect.DeepEqual(usage, want) || c.callCount() != 0 {
+		t.Errorf("usage %v after %d API calls, want %v", usage, c.callCount(), want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// userProfile is the data a user template is executed with: the user's
+// fields plus their repos.
+type userProfile struct {
+	User
+	Repos []Repo
+}
+
+// buildProfiles pairs every user with their repos, keeping user order.
+func buildProfiles(users []User, repos []Repo) []userProfile {
+	byLogin := map[string][]Repo{}
+	for _, r := range repos {
+		byLogin[r.Login] = append(byLogin[r.Login], r)
+	}
+	profiles := make([]userProfile, len(users))
+	for i, u := range users {
+		profiles[i] = userProfile{User: u, Repos: byLogin[u.Login]}
+	}
+	return profiles
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// topRepos returns the n most-starred repos.
+	"topRepos": func(n int, repos []Repo) []Repo {
+		sorted := slices.Clone(repos)
+		slices.SortStableFunc(sorted, func(a, b Repo) int { return cmp.Compare(b.StargazersCount, a.StargazersCount) })
+		return sorted[:min(n, len(sorted))]
+	},
+	// languages returns the repos' languages, most used first.
+	"languages": func(repos []Repo) []string {
+		var langs []string
+		for _, r := range repos {
+			if r.Language != "" {
+				langs = append(langs, r.Language)
+			}
+		}
+		var names []string
+		for _, s := range shareOf(langs) {
+			names = append(names, s.Name)
+		}
+		return names
+	},
+	// lastCreated returns the creation timestamp of the newest repo.
+	"lastCreated": func(repos []Repo) string {
+		latest := ""
+		for _, r := range repos {
+			latest = max(latest, r.CreatedAt)
+		}
+		return latest
+	},
+	// date trims an RFC 3339 timestamp to its date.
+	"date": func(ts string) string {
+		if len(ts) >= 10 {
+			return ts[:10]
+		}
+		return ts
+	},
+	"md": mdCell,
+	"stars": func(repos []Repo) int {
+		total := 0
+		for _, r := range repos {
+			total += r.StargazersCount
+		}
+		return total
+	},
+}
+
+// renderProfiles executes tmpl for every profile. If outPattern is itself a
+// template (contains "{{"), it is executed per profile to name one file per
+// user; otherwise all output goes to the single file outPattern. It returns
+// the files written.
+func renderProfiles(tmpl *template.Template, outPattern string, profiles []userProfile) ([]string, error) {
+	if !strings.Contains(outPattern, "{{") {
+		var b bytes.Buffer
+		for _, p := range profiles {
+			if err := tmpl.Execute(&b, p); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeFileAtomic(outPattern, b.Bytes()); err != nil {
+			return nil, err
+		}
+		return []string{outPattern}, nil
+	}
+
+	pathTmpl, err := template.New("path").Funcs(templateFuncs).Parse(outPattern)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, p := range profiles {
+		var path, body bytes.Buffer
+		if err := pathTmpl.Execute(&path, p); err != nil {
+			return files, err
+		}
+		if err := tmpl.Execute(&body, p); err != nil {
+			return files, err
+		}
+		name := filepath.Clean(path.String())
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			return files, err
+		}
+		if err := writeFileAtomic(name, body.Bytes()); err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func parseUserTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// throttle is a token bucket per host: each host allows rate requests a
+// second on average, in bursts of up to a second's worth. Unlike the
+// concurrency limits, it bounds the request rate however many workers are
+// running, e.g. for a gentle overnight run at 0.5 requests a second.
+type throttle struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newThrottle(rps float64) *throttle {
+	return &throttle{rate: rps, burst: math.Max(1, math.Ceil(rps)), buckets: map[string]*tokenBucket{}}
+}
+
+// wait takes a token from host's bucket, blocking until one is available
+// or ctx is done. A nil throttle never waits.
+func (t *throttle) wait(ctx context.Context, host string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	b := t.buckets[host]
+	if b == nil {
+		b = &tokenBucket{tokens: t.burst, last: now}
+		t.buckets[host] = b
+	}
+	b.tokens = math.Min(t.burst, b.tokens+now.Sub(b.last).Seconds()*t.rate)
+	b.last = now
+	// Taking the token now, even into debt, reserves this request's turn.
+	b.tokens--
+	delay := time.Duration(-b.tokens / t.rate * float64(time.Second))
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	th := newThrottle(50)
+	ctx := context.Background()
+
+	// A burst of 50 passes at once; the 10 after it wait 20ms each.
+	start := time.Now()
+	for range 60 {
+		if err := th.wait(ctx, "api.github.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("60 requests at 50 a second took %v, want about 200ms", elapsed)
+	}
+	// Each host has its own bucket.
+	start = time.Now()
+	if err := th.wait(ctx, "uploads.github.com"); err != nil || time.Since(start) > 10*time.Millisecond {
+		t.Errorf("another host waited %v, err %v", time.Since(start), err)
+	}
+
+	slow := newThrottle(0.1)
+	slow.wait(ctx, "h")
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := slow.wait(short, "h"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled wait err = %v", err)
+	}
+	var none *throttle
+	if err := none.wait(ctx, "h"); err != nil {
+		t.Errorf("nil throttle err = %v", err)
+	}
+}
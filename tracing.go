@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otlpBatchSize is the most spans sent in one OTLP export request.
+const otlpBatchSize = 1000
+
+// tracer records the spans of one run as a single trace and exports them to
+// an OpenTelemetry collector over OTLP/HTTP with JSON encoding, so slow runs
+// can be inspected in Jaeger or Tempo.
+type tracer struct {
+	endpoint string
+	traceID  string
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+// span is one timed operation. A nil span is valid and records nothing, so
+// callers need not check whether tracing is enabled.
+type span struct {
+	t      *tracer
+	id     string
+	parent string
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  [][2]string
+	err    error
+}
+
+type spanKey struct{}
+
+func newTracer(endpoint string) *tracer {
+	return &tracer{endpoint: strings.TrimSuffix(endpoint, "/"), traceID: randomHex(16)}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// start begins the root span of the trace. A nil tracer returns ctx and a
+// nil span.
+func (t *tracer) start(ctx context.Context, name string, attrs ...string) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+	return t.begin(ctx, name, "", attrs)
+}
+
+func (t *tracer) begin(ctx context.Context, name, parent string, attrs []string) (context.Context, *span) {
+	s := &span{t: t, id: randomHex(8), parent: parent, name: name, start: time.Now()}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs = append(s.attrs, [2]string{attrs[i], attrs[i+1]})
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// startSpan begins a child of the span in ctx, with attributes given as
+// key, value pairs. Without a span in ctx, tracing is off and it returns
+// ctx and a nil span.
+func startSpan(ctx context.Context, name string, attrs ...string) (context.Context, *span) {
+	parent, _ := ctx.Value(spanKey{}).(*span)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.t.begin(ctx, name, parent.id, attrs)
+}
+
+// finish ends the span, marking it failed when err is not nil.
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	s.end, s.err = time.Now(), err
+	s.t.mu.Lock()
+	s.t.spans = append(s.t.spans, s)
+	s.t.mu.Unlock()
+}
+
+// flush exports the finished spans to the collector's /v1/traces endpoint.
+func (t *tracer) flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+
+	for len(spans) > 0 {
+		n := min(otlpBatchSize, len(spans))
+		body, err := otlpPayload(t.traceID, spans[:n])
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", t.endpoint+"/v1/traces", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := doJSON(req, nil); err != nil {
+			return err
+		}
+		spans = spans[n:]
+	}
+	return nil
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttributes(pairs [][2]string) []otlpAttribute {
+	out := make([]otlpAttribute, len(pairs))
+	for i, p := range pairs {
+		out[i].Key = p[0]
+		out[i].Value.StringValue = p[1]
+	}
+	return out
+}
+
+// otlpPayload encodes spans as an OTLP ExportTraceServiceRequest in the
+// protobuf JSON mapping.
+func otlpPayload(traceID string, spans []*span) ([]byte, error) {
+	type status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       *status         `json:"status,omitempty"`
+	}
+	out := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		out[i] = otlpSpan{
+			TraceID:      traceID,
+			SpanID:       s.id,
+			ParentSpanID: s.parent,
+			Name:         s.name,
+			Kind:         1, // SPAN_KIND_INTERNAL
+			Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+			End:          strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:   otlpAttributes(s.attrs),
+		}
+		if s.err != nil {
+			out[i].Status = &status{Code: 2, Message: s.err.Error()} // STATUS_CODE_ERROR
+		}
+	}
+	return json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttributes([][2]string{{"service.name", "tds"}})},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "tds"},
+				"spans": out,
+			}},
+		}},
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trackedProfile is the last seen state of a tracked user.
+type trackedProfile struct {
+	Company   string `json:"company"`
+	Location  string `json:"location"`
+	Hireable  bool   `json:"hireable"`
+	Followers int    `json:"followers"`
+	// AlertedFollowers is the follower count follower gains are measured
+	// from; it moves up each time a gain is alerted.
+	AlertedFollowers int `json:"alerted_followers"`
+}
+
+func trackedProfileOf(u User) trackedProfile {
+	return trackedProfile{u.Company, u.Location, u.Hireable, u.Followers, u.Followers}
+}
+
+// profileChange is one alertable change to a tracked user.
+type profileChange struct {
+	Login  string
+	Field  string
+	Before string
+	After  string
+}
+
+func (c profileChange) String() string {
+	return fmt.Sprintf("%s: %s changed from %q to %q", c.Login, c.Field, c.Before, c.After)
+}
+
+// profileChanges compares a tracked user's stored profile with their
+// current one. Followers are reported once they have grown by at least
+// gain since the last follower alert. It returns the changes and the
+// profile to store.
+func profileChanges(before trackedProfile, u User, gain int) ([]profileChange, trackedProfile) {
+	after := trackedProfileOf(u)
+	after.AlertedFollowers = before.AlertedFollowers
+	var out []profileChange
+	add := func(field, b, a string) {
+		if b != a {
+			out = append(out, profileChange{u.Login, field, b, a})
+		}
+	}
+	add("company", before.Company, after.Company)
+	add("location", before.Location, after.Location)
+	add("hireable", strconv.FormatBool(before.Hireable), strconv.FormatBool(after.Hireable))
+	if gain > 0 && u.Followers-before.AlertedFollowers >= gain {
+		add("followers", strconv.Itoa(before.AlertedFollowers), strconv.Itoa(u.Followers))
+		after.AlertedFollowers = u.Followers
+	}
+	return out, after
+}
+
+func loadTrackState(path string) (map[string]trackedProfile, error) {
+	state := map[string]trackedProfile{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+func saveTrackState(path string, state map[string]trackedProfile) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// checkTracked fetches every tracked user and returns the changes since
+// the stored state, updating it. Users seen for the first time are only
+// recorded.
+func (c *apiClient) checkTracked(ctx context.Context, tracked []string, state map[string]trackedProfile, gain int) []profileChange {
+	var changes []profileChange
+	for _, u := range c.fetchUserDetailsConcurrently(ctx, usersFromLogins(tracked)) {
+		before, ok := state[strings.ToLower(u.Login)]
+		if !ok {
+			state[strings.ToLower(u.Login)] = trackedProfileOf(u)
+			continue
+		}
+		var ch []profileChange
+		ch, state[strings.ToLower(u.Login)] = profileChanges(before, u, gain)
+		changes = append(changes, ch...)
+	}
+	return changes
+}
+
+func runTrack(args []string) int {
+	fs := flag.NewFlagSet("track", flag.ExitOnError)
+	usersPath := fs.String("users", "tracked_users.txt", "logins to track, one per line")
+	statePath := fs.String("state", "tracked_state.json", "last seen profile of each tracked user")
+	gain := fs.Int("follower-gain", 100, "alert when a user gains this many followers (0 = never)")
+	webhook := fs.String("notify-webhook", "", "post alerts to this Slack or Discord webhook")
+	interval := fs.Duration("interval", time.Hour, "time between checks")
+	once := fs.Bool("once", false, "check once and exit, e.g. from cron")
+	token := fs.String("token", "", "GitHub token (default $GITHUB_TOKEN)")
+	fs.Parse(args)
+
+	tracked, err := readLoginList(*usersPath)
+	if err != nil {
+		fmt.Println("Error loading tracked users:", err)
+		return 1
+	}
+	state, err := loadTrackState(*statePath)
+	if err != nil {
+		fmt.Println("Error loading track state:", err)
+		return 1
+	}
+	client := newClient(withToken(cmp.Or(*token, os.Getenv("GITHUB_TOKEN"), githubToken)))
+
+	return repeatPolls(client, *interval, *once, func(ctx context.Context) error {
+		changes := client.checkTracked(ctx, tracked, state, *gain)
+		var text strings.Builder
+		for _, ch := range changes {
+			fmt.Println(ch)
+			text.WriteString(ch.String() + "\n")
+		}
+		if *webhook != "" && len(changes) > 0 {
+			if err := postWebhookText(ctx, *webhook, "Tracked user changes:\n"+text.String()); err != nil {
+				fmt.Fprintln(os.Stderr, "Error sending notification:", err)
+			}
+		}
+		return saveTrackState(*statePath, state)
+	})
+}
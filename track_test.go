@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProfileChanges(t *testing.T) {
+	before := trackedProfile{Company: "Acme", Location: "Shanghai", Followers: 120, AlertedFollowers: 100}
+	tests := []struct {
+		name      string
+		user      User
+		gain      int
+		changes   []profileChange
+		alertedAt int
+	}{
+		{"unchanged", User{Login: "a", Company: "Acme", Location: "Shanghai", Followers: 120}, 50, nil, 100},
+		{"moved and hireable", User{Login: "a", Company: "Acme", Location: "Beijing", Hireable: true, Followers: 120}, 50, []profileChange{
+			{"a", "location", "Shanghai", "Beijing"},
+			{"a", "hireable", "false", "true"},
+		}, 100},
+		// Gains add up from the last alert, not from the last check.
+		{"followers", User{Login: "a", Company: "Acme", Location: "Shanghai", Followers: 150}, 50, []profileChange{
+			{"a", "followers", "100", "150"},
+		}, 150},
+		{"followers below the gain", User{Login: "a", Company: "Acme", Location: "Shanghai", Followers: 149}, 50, nil, 100},
+		{"follower alerts off", User{Login: "a", Company: "", Location: "Shanghai", Followers: 900}, 0, []profileChange{
+			{"a", "company", "Acme", ""},
+		}, 100},
+	}
+	for _, tt := range tests {
+		changes, after := profileChanges(before, tt.user, tt.gain)
+		if !reflect.DeepEqual(changes, tt.changes) {
+			t.Errorf("%s: changes %v, want %v", tt.name, changes, tt.changes)
+		}
+		if after.AlertedFollowers != tt.alertedAt || after.Followers != tt.user.Followers || after.Location != tt.user.Location {
+			t.Errorf("%s: stored %+v", tt.name, after)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// repoTraffic is a repo's views and clones over GitHub's 14-day window.
+type repoTraffic struct {
+	Views, UniqueViews   int
+	Clones, UniqueClones int
+}
+
+// fetchTraffic fetches views and clones for the repos the token can push
+// to; GitHub refuses traffic for any other repo. Repos whose lookup failed
+// are absent from the map.
+func (c *apiClient) fetchTraffic(ctx context.Context, repos []Repo) map[string]repoTraffic {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	out := map[string]repoTraffic{}
+	sem := make(chan struct{}, fetchConcurrency)
+	for _, r := range repos {
+		if !r.canPush {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(fullName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var t repoTraffic
+			for _, kind := range []string{"views", "clones"} {
+				body, err := c.getCached(ctx, fmt.Sprintf("%s/repos/%s/traffic/%s", c.baseURL, fullName, kind))
+				if err != nil {
+					return
+				}
+				var resp struct {
+					Count   *int `json:"count"`
+					Uniques int  `json:"uniques"`
+				}
+				if json.Unmarshal(body, &resp) != nil || resp.Count == nil {
+					return
+				}
+				if kind == "views" {
+					t.Views, t.UniqueViews = *resp.Count, resp.Uniques
+				} else {
+					t.Clones, t.UniqueClones = *resp.Count, resp.Uniques
+				}
+			}
+			mu.Lock()
+			out[fullName] = t
+			mu.Unlock()
+		}(r.FullName)
+	}
+	wg.Wait()
+	return out
+}
+
+// trafficColumns returns the repositories.csv columns for the traffic
+// enrichment. Repos without traffic access are left blank.
+func trafficColumns(traffic map[string]repoTraffic) columnSet[Repo] {
+	return columnSet[Repo]{
+		columns: []string{"views_14d", "unique_views_14d", "clones_14d", "unique_clones_14d"},
+		record: func(r Repo) []string {
+			t, ok := traffic[r.FullName]
+			if !ok {
+				return []string{"", "", "", ""}
+			}
+			return []string{strconv.Itoa(t.Views), strconv.Itoa(t.UniqueViews), strconv.Itoa(t.Clones), strconv.Itoa(t.UniqueClones)}
+		},
+	}
+}
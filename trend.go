@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseWindow parses a trend window: a number of days or weeks, e.g. "30d"
+// or "2w", or a Go duration such as "36h".
+func parseWindow(s string) (time.Duration, error) {
+	unit := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, d := range unit {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid window %q", s)
+			}
+			return time.Duration(v) * d, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q (want e.g. 30d, 2w, or 36h)", s)
+	}
+	return d, nil
+}
+
+// countDelta compares a count at the start and end of a trend window.
+type countDelta struct {
+	Before  int `json:"before"`
+	After   int `json:"after"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// trend is the /stats/trend response: how the dataset changed since the
+// newest snapshot in the history store taken at least the window ago.
+type trend struct {
+	Window string     `json:"window"`
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Days   float64    `json:"days"`
+	Users  countDelta `json:"users"`
+	Repos  countDelta `json:"repos"`
+	// FollowerGrowth sums the follower change of the users in both
+	// datasets, so that users joining or leaving do not count as growth.
+	FollowerGrowth int `json:"follower_growth"`
+	// StarGrowth does the same for the stars of repos in both.
+	StarGrowth int `json:"star_growth"`
+}
+
+// baseSnapshot picks the newest snapshot taken at or before cutoff, or the
+// oldest one when none is that old. snaps must be sorted oldest first and
+// not be empty.
+func baseSnapshot(snaps []snapshot, cutoff time.Time) snapshot {
+	base := snaps[0]
+	for _, s := range snaps {
+		if s.taken.After(cutoff) {
+			break
+		}
+		base = s
+	}
+	return base
+}
+
+// computeTrend compares the dataset at the start of a window with the
+// current one.
+func computeTrend(oldUsers []User, oldRepos []Repo, users []User, repos []Repo) trend {
+	var t trend
+	t.Users, t.FollowerGrowth = delta(oldUsers, users, func(u User) (string, int) { return strings.ToLower(u.Login), u.Followers })
+	t.Repos, t.StarGrowth = delta(oldRepos, repos, func(r Repo) (string, int) { return strings.ToLower(r.FullName), r.StargazersCount })
+	return t
+}
+
+// delta counts the items added and removed between before and after, keyed
+// by key, and sums the change in the value of the items in both.
+func delta[T any](before, after []T, key func(T) (string, int)) (countDelta, int) {
+	d := countDelta{Before: len(before), After: len(after)}
+	old := make(map[string]int, len(before))
+	for _, item := range before {
+		k, v := key(item)
+		old[k] = v
+	}
+	growth := 0
+	seen := make(map[string]bool, len(after))
+	for _, item := range after {
+		k, v := key(item)
+		seen[k] = true
+		if was, ok := old[k]; ok {
+			growth += v - was
+		} else {
+			d.Added++
+		}
+	}
+	for k := range old {
+		if !seen[k] {
+			d.Removed++
+		}
+	}
+	return d, growth
+}
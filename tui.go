@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tuiErrors is how many recent errors the terminal UI lists.
+const tuiErrors = 5
+
+// tui is the --tui status screen: live progress, rate-limit and API call
+// gauges, and recent errors, redrawn on stderr. Keys typed on stdin pause
+// and resume requests or flush what has arrived so far to
+// users.partial.csv and repositories.partial.csv.
+type tui struct {
+	client *apiClient
+	red    redaction
+	start  time.Time
+
+	mu        sync.Mutex
+	last      progress
+	rateLimit int
+	errors    []string
+	notice    string
+	// resume is non-nil while paused; closing it lets requests go on.
+	resume chan struct{}
+	// users and repos are what has arrived so far, for flushing.
+	users []User
+	repos []Repo
+
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+	sttyMode  string
+}
+
+// newTUI attaches a status screen to c. It must be set up before the run
+// starts and closed when it ends.
+func newTUI(c *apiClient, red redaction) *tui {
+	t := &tui{client: c, red: red, start: time.Now(), stop: make(chan struct{})}
+	c.setProgress(func(p progress) {
+		t.mu.Lock()
+		t.last = p
+		t.mu.Unlock()
+	})
+	c.addRequestHook(t.gate)
+	c.addResponseHook(t.observe)
+	// Keep any sink already receiving users and repos as they arrive.
+	onUser, onRepos := c.onUser, c.onRepos
+	c.onUser = func(u User) {
+		t.mu.Lock()
+		t.users = append(t.users, u)
+		t.mu.Unlock()
+		if onUser != nil {
+			onUser(u)
+		}
+	}
+	c.onRepos = func(login string, repos []Repo) {
+		t.mu.Lock()
+		t.repos = append(t.repos, repos...)
+		t.mu.Unlock()
+		if onRepos != nil {
+			onRepos(login, repos)
+		}
+	}
+	// Read single keys where the terminal allows; otherwise each key needs
+	// Enter.
+	if out, err := sttyCommand("-g").Output(); err == nil {
+		t.sttyMode = strings.TrimSpace(string(out))
+		sttyCommand("-icanon", "-echo", "min", "1").Run()
+	}
+	t.done.Add(1)
+	go t.loop()
+	go t.readKeys()
+	return t
+}
+
+func sttyCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd
+}
+
+// close stops redrawing, resumes a paused run, and restores the terminal.
+// It may be called more than once, and on a nil tui.
+func (t *tui) close() {
+	if t == nil {
+		return
+	}
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		t.done.Wait()
+		t.mu.Lock()
+		if t.resume != nil {
+			close(t.resume)
+			t.resume = nil
+		}
+		t.mu.Unlock()
+		if t.sttyMode != "" {
+			sttyCommand(t.sttyMode).Run()
+		}
+	})
+}
+
+// gate holds requests while the run is paused.
+func (t *tui) gate(req *http.Request) error {
+	t.mu.Lock()
+	resume := t.resume
+	t.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// observe keeps the rate limit and any failed request.
+func (t *tui) observe(req *http.Request, start time.Time, resp *http.Response, err error) {
+	msg := ""
+	switch {
+	case err != nil:
+		msg = err.Error()
+	case resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound:
+		msg = fmt.Sprintf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if resp != nil {
+		if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+			t.rateLimit = limit
+		}
+	}
+	if msg != "" {
+		t.errors = append(t.errors, time.Now().Format(time.TimeOnly)+" "+msg)
+		if len(t.errors) > tuiErrors {
+			t.errors = t.errors[1:]
+		}
+	}
+}
+
+func (t *tui) loop() {
+	defer t.done.Done()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		t.draw()
+		select {
+		case <-t.stop:
+			t.draw()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readKeys handles p (pause), r (resume), and f (flush). It runs until the
+// process exits, since a read from stdin cannot be interrupted.
+func (t *tui) readKeys() {
+	in := bufio.NewReader(os.Stdin)
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return
+		}
+		switch b {
+		case 'p':
+			t.mu.Lock()
+			if t.resume == nil {
+				t.resume = make(chan struct{})
+				t.notice = "paused; requests in flight finish"
+			}
+			t.mu.Unlock()
+		case 'r':
+			t.mu.Lock()
+			if t.resume != nil {
+				close(t.resume)
+				t.resume = nil
+				t.notice = "resumed"
+			}
+			t.mu.Unlock()
+		case 'f':
+			t.flush()
+		}
+	}
+}
+
+// flush writes the users and repos that have arrived so far. Like the
+// streaming sinks, it sees only users changed since the response cache.
+func (t *tui) flush() {
+	t.mu.Lock()
+	users, repos := redactItems(t.red, t.users), redactItems(t.red, t.repos)
+	t.mu.Unlock()
+	err := writeRecordsCSV("users.partial.csv", userColumns, users, userRecord)
+	if err == nil {
+		err = writeRecordsCSV("repositories.partial.csv", repoColumns, repos, repoRecord)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.notice = "flush failed: " + err.Error()
+		return
+	}
+	t.notice = fmt.Sprintf("flushed %d users and %d repos to users.partial.csv and repositories.partial.csv", len(users), len(repos))
+}
+
+// gauge renders done of total as a bar width characters wide.
+func gauge(done, total, width int) string {
+	if total <= 0 {
+		return strings.Repeat("·", width)
+	}
+	filled := min(width, width*done/total)
+	return strings.Repeat("█", filled) + strings.Repeat("·", width-filled)
+}
+
+func (t *tui) draw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	state := "running"
+	if t.resume != nil {
+		state = "PAUSED"
+	}
+	fmt.Fprintf(&b, "tds scrape  %s  %s\n\n", time.Since(t.start).Round(time.Second), state)
+	p := t.last
+	if p.Total > 0 {
+		fmt.Fprintf(&b, "  %-12s %s %d/%d\n", p.Phase, gauge(p.Done, p.Total, 30), p.Done, p.Total)
+	} else {
+		fmt.Fprintf(&b, "  %-12s %d\n", cmp.Or(p.Phase, "starting"), p.Done)
+	}
+	fmt.Fprintf(&b, "  %-12s %d", "API calls", t.client.callCount())
+	if t.client.maxCalls > 0 {
+		fmt.Fprintf(&b, " of %d %s", t.client.maxCalls, gauge(int(t.client.callCount()), int(t.client.maxCalls), 20))
+	}
+	b.WriteString("\n")
+	switch {
+	case p.RateLimited:
+		fmt.Fprintf(&b, "  %-12s exhausted, waiting until %s\n", "rate limit", p.RateLimitReset.Format(time.TimeOnly))
+	case p.RateLimitRemaining >= 0:
+		fmt.Fprintf(&b, "  %-12s %s %d left", "rate limit", gauge(p.RateLimitRemaining, t.rateLimit, 20), p.RateLimitRemaining)
+		if !p.RateLimitReset.IsZero() {
+			fmt.Fprintf(&b, ", resets %s", p.RateLimitReset.Format(time.TimeOnly))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nRecent errors:\n")
+	if len(t.errors) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, e := range t.errors {
+		fmt.Fprintf(&b, "  %s\n", e)
+	}
+	b.WriteString("\n[p] pause  [r] resume  [f] flush outputs so far\n")
+	if t.notice != "" {
+		fmt.Fprintf(&b, "%s\n", t.notice)
+	}
+	fmt.Fprint(os.Stderr, b.String())
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// objectStore is a bucket that artifacts can be uploaded to.
+type objectStore interface {
+	put(ctx context.Context, key string, body []byte) error
+}
+
+// parseUploadURL resolves an s3:// or gs:// URL into a store and key prefix.
+func parseUploadURL(raw string) (objectStore, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("upload URL %q has no bucket", raw)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		store, err := newS3Store(u.Host)
+		return store, prefix, err
+	case "gs":
+		store, err := newGCSStore(u.Host)
+		return store, prefix, err
+	}
+	return nil, "", fmt.Errorf("unsupported upload scheme %q (want s3:// or gs://)", u.Scheme)
+}
+
+// uploadArtifacts uploads files under prefix/<UTC timestamp>/, so that
+// scheduled runs never overwrite each other. It returns the URLs of the
+// uploaded objects.
+func uploadArtifacts(ctx context.Context, dest string, files []string, stamp time.Time) ([]string, error) {
+	store, prefix, err := parseUploadURL(dest)
+	if err != nil {
+		return nil, err
+	}
+	var uploaded []string
+	dir := path.Join(prefix, stamp.UTC().Format("20060102T150405Z"))
+	for _, f := range files {
+		body, err := os.ReadFile(f)
+		if err != nil {
+			return uploaded, err
+		}
+		key := path.Join(dir, artifactName(f))
+		if err := store.put(ctx, key, body); err != nil {
+			return uploaded, fmt.Errorf("uploading %s: %w", f, err)
+		}
+		location := strings.TrimSuffix(dest, "/") + "/" + strings.TrimPrefix(key, prefix+"/")
+		fmt.Printf("Uploaded %s to %s\n", f, location)
+		uploaded = append(uploaded, location)
+	}
+	return uploaded, nil
+}
+
+// s3Store uploads to Amazon S3 or an S3-compatible service using the
+// standard AWS_* environment variables. AWS_ENDPOINT_URL selects a
+// path-style endpoint for S3-compatible services.
+type s3Store struct {
+	bucket       string
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newS3Store(bucket string) (*s3Store, error) {
+	s := &s3Store{
+		bucket:       bucket,
+		region:       os.Getenv("AWS_REGION"),
+		endpoint:     os.Getenv("AWS_ENDPOINT_URL"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, errors.New("s3 upload needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return s, nil
+}
+
+func (s *s3Store) put(ctx context.Context, key string, body []byte) error {
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, awsURIEncode(key))
+	if s.endpoint != "" {
+		endpoint = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.endpoint, "/"), s.bucket, awsURIEncode(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	s.sign(req, body, time.Now().UTC())
+	return doJSON(req, nil)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req.
+func (s *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payloadHash, amzDate}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, s.sessionToken)
+	}
+
+	var canonicalHeaders strings.Builder
+	for i, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[i] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	canonical := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode percent-encodes a key the way SigV4 expects: everything but
+// unreserved characters and '/'.
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsStore uploads to Google Cloud Storage with the service account named
+// by GOOGLE_APPLICATION_CREDENTIALS.
+type gcsStore struct {
+	bucket  string
+	account *googleServiceAccount
+	token   string
+}
+
+func newGCSStore(bucket string) (*gcsStore, error) {
+	keyFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if keyFile == "" {
+		return nil, errors.New("gs upload needs GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	account, err := loadGoogleServiceAccount(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{bucket: bucket, account: account}, nil
+}
+
+func (s *gcsStore) put(ctx context.Context, key string, body []byte) error {
+	if s.token == "" {
+		token, err := s.account.accessToken(ctx, gcsScope)
+		if err != nil {
+			return err
+		}
+		s.token = token
+	}
+	endpoint := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(s.bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return doJSON(req, nil)
+}
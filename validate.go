@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qualityIssue is one problem found in an exported row. Row counts data
+// rows from 1, after the header.
+type qualityIssue struct {
+	File    string `json:"file"`
+	Row     int    `json:"row"`
+	Column  string `json:"column"`
+	Value   string `json:"value"`
+	Problem string `json:"problem"`
+}
+
+// fileQuality summarises the checks of one exported file.
+type fileQuality struct {
+	File     string         `json:"file"`
+	Rows     int            `json:"rows"`
+	Issues   int            `json:"issues"`
+	Problems map[string]int `json:"problems"`
+}
+
+// qualityReport is written to data_quality.json after a run's CSVs are
+// exported.
+type qualityReport struct {
+	CheckedAt time.Time      `json:"checked_at"`
+	Files     []fileQuality  `json:"files"`
+	Issues    []qualityIssue `json:"issues"`
+	// Truncated is set when more issues were found than are listed.
+	Truncated bool `json:"truncated"`
+}
+
+// maxQualityIssues caps the issues listed in the report; the per-file
+// counts still cover all of them.
+const maxQualityIssues = 1000
+
+// countColumns are the columns that hold non-negative counts.
+var countColumns = []string{"public_repos", "followers", "following", "stargazers_count", "watchers_count", "subscribers_count"}
+
+// validateCSV checks an exported file: key must be set and unique (case
+// insensitively, as GitHub treats logins and repo names), timestamps must
+// be RFC 3339, and counts must be non-negative integers.
+func (q *qualityReport) validateCSV(path, key string) error {
+	fq := fileQuality{File: path, Problems: map[string]int{}}
+	seen := map[string]int{}
+	add := func(row int, column, value, problem string) {
+		fq.Issues++
+		fq.Problems[problem]++
+		if len(q.Issues) < maxQualityIssues {
+			q.Issues = append(q.Issues, qualityIssue{path, row, column, value, problem})
+		} else {
+			q.Truncated = true
+		}
+	}
+	err := readCSV(path, func(r csvRecord) {
+		fq.Rows++
+		row := fq.Rows
+		switch k := strings.ToLower(r.str(key)); {
+		case k == "":
+			add(row, key, "", "empty "+key)
+		case seen[k] > 0:
+			add(row, key, r.str(key), "duplicate "+key)
+		default:
+			seen[k] = row
+		}
+		if v := r.str("created_at"); v != "" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				add(row, "created_at", v, "malformed timestamp")
+			}
+		}
+		for _, col := range countColumns {
+			if _, ok := r.cols[col]; !ok {
+				continue
+			}
+			v := r.str(col)
+			if n, err := strconv.Atoi(v); err != nil {
+				add(row, col, v, "not an integer")
+			} else if n < 0 {
+				add(row, col, v, "negative count")
+			}
+		}
+	})
+	q.Files = append(q.Files, fq)
+	return err
+}
+
+// issues returns the total number of problems found.
+func (q *qualityReport) issues() int {
+	n := 0
+	for _, f := range q.Files {
+		n += f.Issues
+	}
+	return n
+}
+
+func (q *qualityReport) save(path string) error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// validateExports checks the run's users.csv and, if it was written,
+// repositories.csv, and saves the report to path.
+func validateExports(path string, withRepos bool) (*qualityReport, error) {
+	q := &qualityReport{CheckedAt: time.Now().UTC(), Issues: []qualityIssue{}}
+	if err := q.validateCSV("users.csv", "login"); err != nil {
+		return nil, err
+	}
+	if withRepos {
+		if err := q.validateCSV("repositories.csv", "full_name"); err != nil {
+			return nil, err
+		}
+	}
+	return q, q.save(path)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateCSV(t *testing.T) {
+	tests := []struct {
+		name, csv string
+		want      []qualityIssue
+	}{
+		{"clean", "login,followers,created_at\nalice,3,2020-01-01T00:00:00Z\nbob,0,\n", nil},
+		{"keys", "login,followers\nalice,1\nAlice,2\n,3\n", []qualityIssue{
+			{Row: 2, Column: "login", Value: "Alice", Problem: "duplicate login"},
+			{Row: 3, Column: "login", Problem: "empty login"},
+		}},
+		{"values", "login,followers,public_repos,created_at\nalice,-1,many,2020-01-01\n", []qualityIssue{
+			{Row: 1, Column: "created_at", Value: "2020-01-01", Problem: "malformed timestamp"},
+			{Row: 1, Column: "public_repos", Value: "many", Problem: "not an integer"},
+			{Row: 1, Column: "followers", Value: "-1", Problem: "negative count"},
+		}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "users.csv")
+		os.WriteFile(path, []byte(tt.csv), 0o644)
+		q := &qualityReport{}
+		if err := q.validateCSV(path, "login"); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i := range tt.want {
+			tt.want[i].File = path
+		}
+		if !reflect.DeepEqual(q.Issues, tt.want) {
+			t.Errorf("%s: issues %+v\nwant %+v", tt.name, q.Issues, tt.want)
+		}
+		if q.issues() != len(tt.want) || q.Files[0].Issues != len(tt.want) {
+			t.Errorf("%s: counted %d issues, want %d", tt.name, q.issues(), len(tt.want))
+		}
+	}
+}
+
+func TestValidateCSVTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	data := []byte("login\n")
+	for range maxQualityIssues + 5 {
+		data = append(data, ",\n"...)
+	}
+	os.WriteFile(path, data, 0o644)
+	q := &qualityReport{}
+	q.validateCSV(path, "login")
+	if len(q.Issues) != maxQualityIssues || !q.Truncated || q.issues() != maxQualityIssues+5 {
+		t.Errorf("listed %d of %d issues, truncated %v", len(q.Issues), q.issues(), q.Truncated)
+	}
+}
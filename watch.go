@@ -0,0 +1,170 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchState is what the watcher remembers between polls and restarts:
+// the logins that matched the query at the last poll.
+type watchState struct {
+	Query    string    `json:"query"`
+	LastPoll time.Time `json:"last_poll"`
+	Matching []string  `json:"matching"`
+}
+
+func loadWatchState(path string) (watchState, error) {
+	var st watchState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	return st, json.Unmarshal(data, &st)
+}
+
+func saveWatchState(path string, st watchState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// watchEvent is one newly matching user, emitted as a line of NDJSON.
+type watchEvent struct {
+	DetectedAt time.Time `json:"detected_at"`
+	// Reason is "new_account" for accounts created since the previous
+	// poll and "now_matching" for existing accounts that entered the
+	// results, for example by crossing the follower threshold.
+	Reason string `json:"reason"`
+	User   User   `json:"user"`
+}
+
+// newMatches returns the logins in current that were not in previous.
+func newMatches(previous []string, current []User) []string {
+	seen := map[string]bool{}
+	for _, l := range previous {
+		seen[l] = true
+	}
+	var out []string
+	for _, u := range current {
+		if !seen[u.Login] {
+			out = append(out, u.Login)
+		}
+	}
+	return out
+}
+
+// poll runs the search once and emits an event for every newly matching
+// user. The first poll of a fresh state only records the baseline.
+func (c *apiClient) poll(ctx context.Context, query string, st *watchState, w io.Writer, webhook string) error {
+	users, err := c.searchUsers(ctx, url.QueryEscape(query))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if st.LastPoll.IsZero() || st.Query != query {
+		fmt.Fprintf(os.Stderr, "Watching %d users matching %q\n", len(users), query)
+	} else {
+		enc := json.NewEncoder(w)
+		for _, login := range newMatches(st.Matching, users) {
+			u, _, err := c.fetchUserDetails(ctx, login)
+			if err != nil {
+				u = User{Login: login}
+			}
+			ev := watchEvent{DetectedAt: now, Reason: "now_matching", User: u}
+			if created, err := time.Parse(time.RFC3339, u.CreatedAt); err == nil && created.After(st.LastPoll) {
+				ev.Reason = "new_account"
+			}
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+			if webhook != "" {
+				text := fmt.Sprintf("New match for %s: %s (%s, %d followers) https://github.com/%s", query, u.Login, ev.Reason, u.Followers, u.Login)
+				if err := postWebhookText(ctx, webhook, text); err != nil {
+					fmt.Fprintln(os.Stderr, "Error sending notification:", err)
+				}
+			}
+		}
+	}
+	st.Query, st.LastPoll, st.Matching = query, now, logins(users)
+	return nil
+}
+
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	query := fs.String("query", "location:Shanghai followers:>200", "user search to watch")
+	interval := fs.Duration("interval", 15*time.Minute, "time between polls")
+	statePath := fs.String("state", "watch_state.json", "remembers the matching users across restarts")
+	out := fs.String("out", "", "append new matches as NDJSON to this file instead of stdout")
+	webhook := fs.String("notify-webhook", "", "also post each new match to this Slack or Discord webhook")
+	once := fs.Bool("once", false, "poll once and exit, e.g. from cron")
+	token := fs.String("token", "", "GitHub token (default $GITHUB_TOKEN)")
+	fs.Parse(args)
+
+	st, err := loadWatchState(*statePath)
+	if err != nil {
+		fmt.Println("Error loading watch state:", err)
+		return 1
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			fmt.Println("Error opening output:", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	client := newClient(withToken(cmp.Or(*token, os.Getenv("GITHUB_TOKEN"), githubToken)))
+	if !client.authenticated() {
+		fmt.Fprintln(os.Stderr, "Warning: no GitHub token configured; polling is paced and stops after", client.maxCalls, "API calls")
+	}
+
+	return repeatPolls(client, *interval, *once, func(ctx context.Context) error {
+		if err := client.poll(ctx, *query, &st, w, *webhook); err != nil {
+			return err
+		}
+		return saveWatchState(*statePath, st)
+	})
+}
+
+// repeatPolls calls poll every interval until interrupted, or just once. A
+// failed poll is reported and retried at the next interval unless the API
+// call budget is spent. It returns the process exit status.
+func repeatPolls(c *apiClient, interval time.Duration, once bool, poll func(context.Context) error) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		if err := poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return 0
+			}
+			fmt.Fprintln(os.Stderr, "Error polling:", err)
+			if c.exhausted() {
+				return exitLimitReached
+			}
+		}
+		if once {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(interval):
+		}
+	}
+}
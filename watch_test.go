@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewMatches(t *testing.T) {
+	current := []User{{Login: "a"}, {Login: "b"}, {Login: "c"}}
+	tests := []struct {
+		previous, want []string
+	}{
+		{nil, []string{"a", "b", "c"}},
+		{[]string{"a", "c"}, []string{"b"}},
+		{[]string{"a", "b", "c", "gone"}, nil},
+	}
+	for _, tt := range tests {
+		if got := newMatches(tt.previous, current); !slices.Equal(got, tt.want) {
+			t.Errorf("newMatches(%v) = %v, want %v", tt.previous, got, tt.want)
+		}
+	}
+}
+
+// The first poll records a baseline; later ones emit the users that
+// entered the results.
+func TestPoll(t *testing.T) {
+	srv := fakeGitHub(3, 0, 0)
+	defer srv.Close()
+	c := newClient(withToken("x"), withBaseURL(srv.URL))
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	var st watchState
+	if err := c.poll(ctx, "location:Shanghai", &st, &out, ""); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 || len(st.Matching) != 3 || st.LastPoll.IsZero() {
+		t.Fatalf("baseline poll emitted %q, state %+v", out.String(), st)
+	}
+
+	st.Matching = st.Matching[:1]
+	st.LastPoll = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := c.poll(ctx, "location:Shanghai", &st, &out, ""); err != nil {
+		t.Fatal(err)
+	}
+	var events []watchEvent
+	for dec := json.NewDecoder(&out); dec.More(); {
+		var ev watchEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 2 || events[0].User.Login != "user00001" || events[0].User.Followers == 0 {
+		t.Fatalf("events = %+v", events)
+	}
+	// The accounts were created after the previous poll.
+	if events[0].Reason != "new_account" || len(st.Matching) != 3 {
+		t.Errorf("reason %s, state %+v", events[0].Reason, st)
+	}
+
+	// Older accounts are existing users that now match.
+	st.Matching = st.Matching[1:]
+	st.LastPoll = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	out.Reset()
+	if err := c.poll(ctx, "location:Shanghai", &st, &out, ""); err != nil {
+		t.Fatal(err)
+	}
+	var ev watchEvent
+	if err := json.Unmarshal(out.Bytes(), &ev); err != nil || ev.User.Login != "user00000" || ev.Reason != "now_matching" {
+		t.Errorf("event %s: %+v, %v", out.String(), ev, err)
+	}
+}
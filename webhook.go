@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webhookHandler receives GitHub webhook deliveries for users and orgs and
+// applies them to the served dataset, keeping it fresh between scrapes.
+type webhookHandler struct {
+	secret []byte
+	store  *datasetStore
+	client *apiClient
+	// pending tracks the lookups of new members still running after their
+	// delivery was acknowledged.
+	pending sync.WaitGroup
+}
+
+// webhookRepo is a repository as it appears in webhook payloads, which
+// also say who owns it and whether it is private.
+type webhookRepo struct {
+	Repo
+	Private bool
+	Owner   string
+}
+
+func (r *webhookRepo) UnmarshalJSON(data []byte) error {
+	// Push payloads give created_at as a Unix timestamp.
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var sec int64
+	if json.Unmarshal(raw["created_at"], &sec) == nil {
+		raw["created_at"], _ = json.Marshal(time.Unix(sec, 0).UTC().Format(time.RFC3339))
+		data, _ = json.Marshal(raw)
+	}
+	if err := json.Unmarshal(data, &r.Repo); err != nil {
+		return err
+	}
+	var extra struct {
+		Private bool `json:"private"`
+		Owner   struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	r.Private, r.Owner = extra.Private, extra.Owner.Login
+	r.Login = r.Owner
+	return nil
+}
+
+type webhookPayload struct {
+	Action     string       `json:"action"`
+	Repository *webhookRepo `json:"repository"`
+	Forkee     *webhookRepo `json:"forkee"`
+	Membership *struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"membership"`
+	Changes struct {
+		Repository struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"repository"`
+		Owner struct {
+			From struct {
+				User struct {
+					Login string `json:"login"`
+				} `json:"user"`
+			} `json:"from"`
+		} `json:"owner"`
+	} `json:"changes"`
+}
+
+// validSignature checks the X-Hub-Signature-256 header against body.
+func validSignature(secret, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 25<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !validSignature(h.secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	event := r.Header.Get("X-GitHub-Event")
+	msg, later := h.apply(event, p)
+	if msg != "" {
+		fmt.Println("Webhook:", msg)
+	}
+	if later == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// GitHub gives up on a delivery after 10 seconds, and an API lookup
+	// can take longer, so it runs once the delivery is acknowledged.
+	w.WriteHeader(http.StatusAccepted)
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		if msg := later(); msg != "" {
+			fmt.Println("Webhook:", msg)
+		}
+	}()
+}
+
+// tracks reports whether repo belongs in the dataset: it is public and its
+// owner is one of the dataset's users, or it is already in the dataset.
+func (h *webhookHandler) tracks(repo *webhookRepo) bool {
+	if _, ok := h.store.repo(repo.FullName); ok {
+		return true
+	}
+	_, ok := h.store.user(repo.Owner)
+	return ok && !repo.Private
+}
+
+// apply updates the store for one delivery and describes what changed, or
+// returns "" if the event did not concern the dataset. Work that needs the
+// API is returned as later, to run after the delivery is acknowledged.
+func (h *webhookHandler) apply(event string, p webhookPayload) (msg string, later func() string) {
+	switch {
+	case event == "repository" && p.Repository != nil:
+		repo := p.Repository
+		switch p.Action {
+		case "deleted", "privatized":
+			if _, ok := h.store.repo(repo.FullName); ok {
+				h.store.removeRepo(repo.FullName)
+				return fmt.Sprintf("removed %s (%s)", repo.FullName, p.Action), nil
+			}
+			return "", nil
+		}
+		// Renames and transfers are keyed by the repo's previous name.
+		oldName := ""
+		owner, name, _ := strings.Cut(repo.FullName, "/")
+		if from := p.Changes.Repository.Name.From; from != "" {
+			oldName = owner + "/" + from
+		}
+		if from := p.Changes.Owner.From.User.Login; from != "" {
+			oldName = from + "/" + name
+		}
+		if _, known := h.store.repo(oldName); !known && !h.tracks(repo) {
+			return "", nil
+		}
+		h.store.putRepo(repo.Repo, oldName)
+		return fmt.Sprintf("updated %s (%s)", repo.FullName, p.Action), nil
+
+	case event == "fork" && p.Forkee != nil:
+		if !h.tracks(p.Forkee) {
+			return "", nil
+		}
+		p.Forkee.Fork = true
+		h.store.putRepo(p.Forkee.Repo, "")
+		return "added fork " + p.Forkee.FullName, nil
+
+	case (event == "star" || event == "push" || event == "public") && p.Repository != nil:
+		if !h.tracks(p.Repository) {
+			return "", nil
+		}
+		h.store.putRepo(p.Repository.Repo, "")
+		return fmt.Sprintf("updated %s (%s)", p.Repository.FullName, event), nil
+
+	case event == "organization" && p.Membership != nil:
+		login := p.Membership.User.Login
+		switch p.Action {
+		case "member_added":
+			return "", func() string {
+				u, _, err := h.client.fetchUserDetails(context.Background(), login)
+				if err != nil {
+					u = User{Login: login}
+				}
+				h.store.putUser(u)
+				return "added member " + login
+			}
+		case "member_removed":
+			h.store.removeUser(login)
+			return "removed member " + login, nil
+		}
+	}
+	return "", nil
+}
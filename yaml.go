@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by the config files:
+//
+//   - block mappings and sequences, nested by indenting with spaces,
+//     including "- key: value" items;
+//   - flow sequences of scalars on one line, such as [a, "b c", 3];
+//   - plain, 'single-quoted', and "double-quoted" scalars on one line;
+//   - # comments and a leading --- document marker.
+//
+// Mappings decode to map[string]any, sequences to []any, and scalars to
+// string, float64, bool (true/false, yes/no, on/off), or nil (null, ~).
+// Anything else, such as block scalars (| and >), flow mappings, anchors,
+// aliases, tags, multi-line scalars, duplicate keys, or several documents,
+// is an error rather than being read differently than YAML would.
+func parseYAML(data string) (any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		text := stripYAMLComment(raw)
+		switch strings.TrimSpace(text) {
+		case "":
+			continue
+		case "---":
+			if len(lines) > 0 {
+				return nil, fmt.Errorf("line %d: only one YAML document is supported", i+1)
+			}
+			continue
+		case "...":
+			return nil, fmt.Errorf("line %d: document end markers are not supported", i+1)
+		}
+		if strings.Contains(text, "\t") && strings.TrimLeft(text, " \t") != strings.TrimLeft(text, " ") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		lines = append(lines, yamlLine{num: i + 1, indent: indent, text: strings.TrimSpace(text)})
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation (multi-line scalars are not supported)", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+// loadYAMLFile reads and parses a YAML file whose top level is a mapping.
+func loadYAMLFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parseYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: top level must be a mapping", path)
+	}
+	return m, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence whose lines start at indent.
+func (p *yamlParser) block(indent int) (any, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	var out []any
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if !strings.HasPrefix(line.text, "-") {
+			return nil, fmt.Errorf("line %d: expected a sequence item", line.num)
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if rest == "" {
+			p.pos++
+			item, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+			continue
+		}
+		if _, _, isKey := splitYAMLKey(rest); isKey {
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + 2, text: rest}
+			item, err := p.mapping(indent + 2)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+			continue
+		}
+		p.pos++
+		v, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	out := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", line.num)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		if rest == "" {
+			v, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+			continue
+		}
+		v, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// nested parses the block under a key or dash, if it is indented further
+// (or is a sequence at the same indent, as YAML allows under a key).
+func (p *yamlParser) nested(indent int) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (next.indent == indent && strings.HasPrefix(next.text, "- ")) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+// splitYAMLKey splits "key: value", honouring quoted keys.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, `'`) {
+		end := strings.Index(text[1:], text[:1])
+		if end < 0 {
+			return "", "", false
+		}
+		key = text[1 : end+1]
+		after := text[end+2:]
+		if !strings.HasPrefix(after, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(after[1:]), true
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if strings.HasSuffix(text, ":") {
+			return strings.TrimSpace(text[:len(text)-1]), "", true
+		}
+		return "", "", false
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+}
+
+func parseYAMLScalar(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %q", s)
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		out := []any{}
+		if inner == "" {
+			return out, nil
+		}
+		items := splitYAMLFlow(inner)
+		// A trailing comma, as in [rust, ], ends the sequence.
+		if strings.TrimSpace(items[len(items)-1]) == "" {
+			items = items[:len(items)-1]
+		}
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, fmt.Errorf("empty item in flow sequence %q", s)
+			}
+			v, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, `'`):
+		inner := s[1:]
+		if !strings.HasSuffix(inner, `'`) || strings.Contains(strings.ReplaceAll(inner[:len(inner)-1], "''", ""), "'") {
+			return nil, fmt.Errorf("invalid single-quoted string %s", s)
+		}
+		return strings.ReplaceAll(inner[:len(inner)-1], "''", "'"), nil
+	case strings.ContainsAny(s[:1], "|>"):
+		return nil, fmt.Errorf("block scalars (%s) are not supported; quote the value on one line", s)
+	case strings.HasPrefix(s, "- "):
+		return nil, fmt.Errorf("a sequence must start on its own line: %q", s)
+	case strings.ContainsAny(s[:1], "{}]&*!@`"):
+		return nil, fmt.Errorf("unsupported YAML %q: flow mappings, anchors, aliases, and tags are not supported; quote the value if it is a string", s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return s, nil
+}
+
+// splitYAMLFlow splits the items of a flow sequence on commas outside quotes.
+func splitYAMLFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// stripYAMLComment removes a # comment that is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return strings.TrimRight(line[:i], " ")
+		}
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// yamlFloat, yamlString, and yamlStrings read typed values from a decoded
+// mapping, returning def when the key is missing or has another type.
+func yamlFloat(m map[string]any, key string, def float64) float64 {
+	if v, ok := m[key].(float64); ok {
+		return v
+	}
+	return def
+}
+
+func yamlString(m map[string]any, key, def string) string {
+	switch v := m[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return def
+}
+
+func yamlStrings(m map[string]any, key string) []string {
+	items, _ := m[key].([]any)
+	var out []string
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			out = append(out, v)
+		case float64:
+			out = append(out, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	return out
+}
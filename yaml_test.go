@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{"empty", "# nothing\n", map[string]any{}},
+		{"scalars", `
+a: plain text
+b: 'it''s'
+c: "tab\tand \"quotes\""
+d: 42
+e: -1.5
+f: yes
+g: Off
+h: ~
+i: null
+query: location:Beijing followers:>500  # comment
+j: "# not a comment"
+`, map[string]any{
+			"a": "plain text", "b": "it's", "c": "tab\tand \"quotes\"", "d": 42.0, "e": -1.5,
+			"f": true, "g": false, "h": nil, "i": nil, "query": "location:Beijing followers:>500", "j": "# not a comment",
+		}},
+		{"nested", `---
+weights:
+  followers: 3
+  stars: 2
+bio_keywords: [kubernetes, "machine learning", 'go']
+empty: []
+`, map[string]any{
+			"weights":      map[string]any{"followers": 3.0, "stars": 2.0},
+			"bio_keywords": []any{"kubernetes", "machine learning", "go"},
+			"empty":        []any{},
+		}},
+		{"sequences", `
+runs:
+  - name: beijing
+    flags:
+      query: "location:Beijing"
+  - name: china
+    dir: out/china
+list:
+- a
+- 2
+-
+  - nested
+`, map[string]any{
+			"runs": []any{
+				map[string]any{"name": "beijing", "flags": map[string]any{"query": "location:Beijing"}},
+				map[string]any{"name": "china", "dir": "out/china"},
+			},
+			"list": []any{"a", 2.0, []any{"nested"}},
+		}},
+		{"quoted keys", `"C++": "C/C++"` + "\n" + `'Jupyter Notebook': Python`, map[string]any{"C++": "C/C++", "Jupyter Notebook": "Python"}},
+		{"missing value", "a:\nb: 1\n", map[string]any{"a": nil, "b": 1.0}},
+		{"trailing comma", "bio_keywords: [rust, ]\ntags: ['a',]\n", map[string]any{"bio_keywords": []any{"rust"}, "tags": []any{"a"}}},
+		{"crlf", "a: 1\r\nb: 2\r\n", map[string]any{"a": 1.0, "b": 2.0}},
+	}
+	for _, tt := range tests {
+		got, err := parseYAML(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v\nwant %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// YAML outside the supported subset is rejected rather than misread.
+func TestParseYAMLUnsupported(t *testing.T) {
+	tests := map[string]string{
+		"literal block scalar": "bio: |\n  line one\n  line two\n",
+		"folded block scalar":  "bio: >\n  folded\n",
+		"flow mapping":         "weights: {followers: 3}\n",
+		"anchor":               "base: &base x\n",
+		"alias":                "copy: *base\n",
+		"tag":                  "n: !!str 3\n",
+		"multi-line scalar":    "a: first\n  continued\n",
+		"bad dedent":           "a:\n    b: 1\n  c: 2\n",
+		"duplicate key":        "a: 1\na: 2\n",
+		"two documents":        "a: 1\n---\nb: 2\n",
+		"document end":         "a: 1\n...\n",
+		"tab indent":           "a:\n\tb: 1\n",
+		"inline sequence":      "a: - b\n",
+		"empty flow item":      "a: [1, , 2]\n",
+		"only a comma":         "a: [,]\n",
+		"unterminated flow":    "a: [1, 2\n",
+		"unterminated quote":   "a: \"open\n",
+		"stray single quote":   "a: 'x' 'y'\n",
+		"not a mapping":        "just text\n",
+		"reserved character":   "a: @home\n",
+	}
+	for name, in := range tests {
+		if v, err := parseYAML(in); err == nil {
+			t.Errorf("%s: parsed as %#v, want an error", name, v)
+		}
+	}
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	dir := t.TempDir()
+	top := filepath.Join(dir, "list.yaml")
+	os.WriteFile(top, []byte("- a\n- b\n"), 0o644)
+	if _, err := loadYAMLFile(top); err == nil || !strings.Contains(err.Error(), "mapping") {
+		t.Errorf("a top-level sequence: err = %v", err)
+	}
+	if _, err := loadYAMLFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("a missing file loaded")
+	}
+}
+
+// The runs config tds init writes reads back as the flags it was given.
+func TestRenderRunsConfigRoundTrip(t *testing.T) {
+	a := initAnswers{name: "shanghai", flags: map[string]string{
+		"query": `location:"Shanghai" followers:>200`, "token": `t'ok"en\`, "events": "true", "duckdb": "tds.duckdb",
+	}}
+	path := filepath.Join(t.TempDir(), "runs.yaml")
+	os.WriteFile(path, []byte(renderRunsConfig(a)), 0o644)
+	cfg, err := loadRunsConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.runs) != 1 || cfg.runs[0].name != "shanghai" || !reflect.DeepEqual(cfg.runs[0].flags, a.flags) {
+		t.Fatalf("runs = %+v, want the flags %v", cfg.runs, a.flags)
+	}
+}